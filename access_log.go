@@ -0,0 +1,176 @@
+package nginxparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type AccessLog struct {
+	Directive *Directive `json:"-"`
+	Server    *Directive `json:"-"`
+	Location  *Directive `json:"-"`
+	Path      string     `json:"path"`
+	Format    string     `json:"format,omitempty"`
+	Off       bool       `json:"off,omitempty"`
+	Condition string     `json:"condition,omitempty"`
+	// Rate is the fraction of requests logged, -1 when it depends on
+	// request data, such as a map over $status.
+	Rate float64 `json:"rate"`
+	// Sampling describes where the condition comes from.
+	Sampling string `json:"sampling,omitempty"`
+}
+
+type logCondition struct {
+	rate     float64
+	sampling string
+}
+
+// conditionRates evaluates the variables usable as an access_log if=
+// condition: split_clients gives the share of requests mapped to a value
+// other than "" or "0", maps and sets with constant values give 0 or 1.
+func conditionRates(directives []*Directive) map[string]*logCondition {
+	result := make(map[string]*logCondition)
+	truthy := func(value string) bool {
+		return value != "" && value != "0"
+	}
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		switch d.Directive {
+		case "split_clients":
+			if len(d.Args) != 2 {
+				return false
+			}
+			c := &logCondition{sampling: fmt.Sprintf("split_clients %s", d.Args[0])}
+			rest := 100.0
+			for _, entry := range children(d.Block) {
+				if len(entry.Args) == 0 {
+					continue
+				}
+				share := rest
+				if entry.Directive != "*" {
+					n, err := strconv.ParseFloat(strings.TrimSuffix(entry.Directive, "%"), 64)
+					if err != nil {
+						c.rate = -1
+						break
+					}
+					share = n
+				}
+				rest -= share
+				if truthy(entry.Args[0]) {
+					c.rate += share / 100
+				}
+			}
+			result[strings.TrimPrefix(d.Args[1], "$")] = c
+			return false
+		case "map":
+			if len(d.Args) != 2 {
+				return false
+			}
+			c := &logCondition{sampling: fmt.Sprintf("map %s", d.Args[0])}
+			values := make(map[bool]bool)
+			hasDefault := false
+			for _, entry := range children(d.Block) {
+				switch entry.Directive {
+				case "hostnames", "volatile", "include":
+					continue
+				case "default":
+					hasDefault = true
+				}
+				if len(entry.Args) == 0 || len(Variables(entry.Args[0])) > 0 {
+					values[true], values[false] = true, true
+					continue
+				}
+				values[truthy(entry.Args[0])] = true
+			}
+			if !hasDefault {
+				values[false] = true
+			}
+			switch {
+			case values[true] && values[false]:
+				c.rate = -1
+			case values[true]:
+				c.rate = 1
+			}
+			result[strings.TrimPrefix(d.Args[1], "$")] = c
+			return false
+		case "set":
+			if len(d.Args) == 2 && strings.HasPrefix(d.Args[0], "$") {
+				name := strings.TrimPrefix(d.Args[0], "$")
+				c := &logCondition{sampling: "set", rate: -1}
+				if len(Variables(d.Args[1])) == 0 {
+					c.rate = 0
+					if truthy(d.Args[1]) {
+						c.rate = 1
+					}
+				}
+				if previous, ok := result[name]; ok && previous.rate != c.rate {
+					c.rate = -1
+				}
+				result[name] = c
+			}
+		}
+		return true
+	})
+	return result
+}
+
+// AccessLogs reports the access logs in effect for every location, or for
+// a server without locations, with the share of requests each one
+// records. access_log is an array directive, so a level that defines any
+// replaces all of the inherited ones.
+func AccessLogs(directives []*Directive) []*AccessLog {
+	conditions := conditionRates(directives)
+	result := make([]*AccessLog, 0)
+	add := func(server, location *Directive, block, parents []*Directive) {
+		logs := lookupInheritedAll("access_log", block, parents)
+		if len(logs) == 0 {
+			result = append(result, &AccessLog{Server: server, Location: location, Path: "logs/access.log", Format: "combined", Rate: 1})
+			return
+		}
+		for _, d := range logs {
+			entry := &AccessLog{Directive: d, Server: server, Location: location, Rate: 1}
+			if len(d.Args) > 0 {
+				entry.Path = d.Args[0]
+			}
+			if entry.Path == "off" {
+				entry.Off, entry.Rate = true, 0
+				result = append(result, entry)
+				continue
+			}
+			entry.Format = "combined"
+			for i, arg := range d.Args[1:] {
+				if i == 0 && !strings.Contains(arg, "=") {
+					entry.Format = arg
+				}
+				if strings.HasPrefix(arg, "if=") {
+					entry.Condition = strings.TrimPrefix(arg, "if=")
+				}
+			}
+			if entry.Condition != "" {
+				entry.Rate = -1
+				if vars := Variables(entry.Condition); len(vars) == 1 && "$"+vars[0] == entry.Condition {
+					if c, ok := conditions[vars[0]]; ok {
+						entry.Rate, entry.Sampling = c.rate, c.sampling
+					}
+				}
+			}
+			result = append(result, entry)
+		}
+	}
+
+	for _, server := range servers(directives) {
+		outer := parentsOf(directives, server)
+		found := false
+		Walk(server.Block, func(d *Directive, parents []*Directive) bool {
+			if d.Directive == "location" {
+				found = true
+				add(server, d, d.Block, append(append(outer[:len(outer):len(outer)], server), parents...))
+			}
+			return true
+		})
+		if !found {
+			add(server, nil, server.Block, outer)
+		}
+	}
+	return result
+}
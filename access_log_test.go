@@ -0,0 +1,60 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestAccessLogs(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    split_clients $request_id $sampled {
+        10% 1;
+        *   0;
+    }
+    map $status $loggable {
+        ~^[23] 0;
+        default 1;
+    }
+    map $uri $never {
+        default 0;
+    }
+    access_log /var/log/nginx/sampled.log main if=$sampled;
+    server {
+        location / { }
+        location /errors { access_log /var/log/nginx/errors.log if=$loggable; }
+        location /health { access_log off; }
+        location /quiet { access_log /var/log/nginx/quiet.log combined if=$never; }
+    }
+    server {
+        access_log /var/log/nginx/all.log;
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := AccessLogs(directives)
+	if len(logs) != 5 {
+		t.Fatalf("unexpected logs %+v", logs)
+	}
+	expected := []struct {
+		path     string
+		format   string
+		rate     float64
+		sampling string
+	}{
+		{"/var/log/nginx/sampled.log", "main", 0.1, "split_clients $request_id"},
+		{"/var/log/nginx/errors.log", "combined", -1, "map $status"},
+		{"off", "", 0, ""},
+		{"/var/log/nginx/quiet.log", "combined", 0, "map $uri"},
+		{"/var/log/nginx/all.log", "combined", 1, ""},
+	}
+	for i, e := range expected {
+		log := logs[i]
+		if log.Path != e.path || log.Format != e.format || log.Rate != e.rate || log.Sampling != e.sampling {
+			t.Fatalf("expected %+v but got %+v", e, log)
+		}
+	}
+	if logs[4].Location != nil || logs[0].Location == nil {
+		t.Fatalf("unexpected locations %+v", logs)
+	}
+}
@@ -0,0 +1,137 @@
+package nginxparser
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// PathPreview is the file nginx serves for a request URI. Source is the
+// root or alias directive in effect, nil when the default root html
+// applies.
+type PathPreview struct {
+	Location *Directive `json:"-"`
+	Source   *Directive `json:"-"`
+	URI      string     `json:"uri"`
+	Path     string     `json:"path"`
+}
+
+// PreviewPath computes the filesystem path nginx maps a request URI to in
+// a server, following root and alias semantics: root appends the whole
+// URI, alias replaces the part matched by a prefix location, or stands
+// for the whole path, with captures substituted, in a regex location.
+func PreviewPath(server *Directive, uri string) *PathPreview {
+	preview := &PathPreview{URI: uri, Path: "html" + uri}
+	location := FindLocation(server, uri)
+	preview.Location = location
+	parents := []*Directive{server}
+	block := server.Block
+	if location != nil {
+		parents = parentsOf([]*Directive{server}, location)
+		block = location.Block
+		if alias := findFirst(location.Block, "alias"); alias != nil && len(alias.Args) > 0 {
+			preview.Source, preview.Path = alias, aliasPath(location, alias.Args[0], uri)
+			return preview
+		}
+	}
+	if root := lookupInherited("root", block, parents); root != nil && len(root.Args) > 0 {
+		preview.Source, preview.Path = root, strings.TrimSuffix(root.Args[0], "/")+uri
+	}
+	return preview
+}
+
+func parentsOf(directives []*Directive, target *Directive) []*Directive {
+	var result []*Directive
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d == target {
+			result = parents
+		}
+		return result == nil
+	})
+	return result
+}
+
+func aliasPath(location *Directive, alias, uri string) string {
+	modifier, pattern := locationPattern(location)
+	if modifier != locationRegex && modifier != locationRegexNoCase {
+		return alias + strings.TrimPrefix(uri, pattern)
+	}
+	re, err := locationRegexp(location)
+	if err != nil {
+		return alias
+	}
+	match := re.FindStringSubmatch(uri)
+	if match == nil {
+		return alias
+	}
+	return expandCaptures(alias, re, match)
+}
+
+// expandCaptures substitutes $1..$9 and named captures in s. Other
+// variables are left as they are.
+func expandCaptures(s string, re *regexp.Regexp, match []string) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			buf.WriteByte(s[i])
+			continue
+		}
+		if c := s[i+1]; c >= '0' && c <= '9' {
+			if n := int(c - '0'); n < len(match) {
+				buf.WriteString(match[n])
+			}
+			i++
+			continue
+		}
+		name, end := "", i+1
+		if s[end] == '{' {
+			if j := strings.IndexByte(s[end:], '}'); j >= 0 {
+				name, end = s[end+1:end+j], end+j+1
+			}
+		} else {
+			for end < len(s) && isVariableByte(s[end]) {
+				end++
+			}
+			name = s[i+1 : end]
+		}
+		if n := re.SubexpIndex(name); name != "" && n >= 0 {
+			buf.WriteString(match[n])
+			i = end - 1
+			continue
+		}
+		buf.WriteByte(s[i])
+	}
+	return buf.String()
+}
+
+// CheckAlias flags the alias traversal bug, a prefix location without a
+// trailing slash whose alias has one, so that location /img { alias
+// /data/img/; } serves /data/ for /img../, and aliases that only repeat
+// the location and are better written as root.
+func CheckAlias(directives []*Directive) []*Issue {
+	issues := make([]*Issue, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive != "alias" || len(d.Args) == 0 {
+			return true
+		}
+		location := enclosing("location", parents)
+		if location == nil {
+			return true
+		}
+		modifier, pattern := locationPattern(location)
+		if modifier != locationPrefix && modifier != locationPreferPrefix {
+			return true
+		}
+		alias := d.Args[0]
+		if !strings.HasSuffix(pattern, "/") && strings.HasSuffix(alias, "/") {
+			uri := pattern + "../"
+			issues = append(issues, newIssue("alias-traversal", d, "location %s does not end with / but its alias does, a request for %s is served from %s, outside %s", pattern, uri, path.Clean(alias+"../"), alias))
+			return true
+		}
+		if strings.HasSuffix(pattern, "/") && strings.HasSuffix(alias, pattern) && len(Variables(alias)) == 0 {
+			issues = append(issues, newIssue("alias-traversal", d, "alias repeats the location %s, use root %s instead", pattern, strings.TrimSuffix(alias, pattern)))
+		}
+		return true
+	})
+	return issues
+}
@@ -0,0 +1,43 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestPreviewPath(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        root /var/www;
+        location / { }
+        location /img { alias /data/images/; }
+        location /static/ { alias /srv/static/; }
+        location ~ ^/users/([^/]+)/avatar$ { alias /data/avatars/$1.png; }
+        location /docs/ { root /srv; }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := servers(directives)[0]
+	for uri, expected := range map[string]string{
+		"/index.html":        "/var/www/index.html",
+		"/img/a.png":         "/data/images//a.png",
+		"/img../etc/passwd":  "/data/images/../etc/passwd",
+		"/static/app.js":     "/srv/static/app.js",
+		"/users/bob/avatar":  "/data/avatars/bob.png",
+		"/docs/guide/a.html": "/srv/docs/guide/a.html",
+	} {
+		if preview := PreviewPath(server, uri); preview.Path != expected {
+			t.Fatalf("expected %s for %s but got %s", expected, uri, preview.Path)
+		}
+	}
+
+	issues := CheckAlias(directives)
+	if len(issues) != 2 || issues[0].Line != 6 || issues[1].Line != 7 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	if expected := "location /img does not end with / but its alias does, a request for /img../ is served from /data, outside /data/images/"; issues[0].Message != expected {
+		t.Fatalf("unexpected message %s", issues[0].Message)
+	}
+}
@@ -0,0 +1,75 @@
+package nginxparser
+
+type AuthRequest struct {
+	Directive *Directive `json:"-"`
+	Server    *Directive `json:"-"`
+	Location  *Directive `json:"-"`
+	Target    *Directive `json:"-"`
+	URI       string     `json:"uri"`
+	Internal  bool       `json:"internal"`
+}
+
+// AuthRequests traces every auth_request directive to the location its
+// subrequest is routed to. A directive set at http level yields one entry
+// per server since the target is resolved against each server's locations.
+func AuthRequests(directives []*Directive) []*AuthRequest {
+	result := make([]*AuthRequest, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive != "auth_request" || len(d.Args) != 1 || d.Args[0] == "off" {
+			return true
+		}
+		location := enclosing("location", parents)
+		scope := []*Directive{enclosing("server", parents)}
+		if scope[0] == nil {
+			scope = servers(parents)
+		}
+		for _, server := range scope {
+			auth := &AuthRequest{
+				Directive: d,
+				Server:    server,
+				Location:  location,
+				URI:       d.Args[0],
+			}
+			auth.Target = FindLocation(server, auth.URI)
+			if auth.Target != nil {
+				auth.Internal = findFirst(auth.Target.Block, "internal") != nil
+			}
+			result = append(result, auth)
+		}
+		return true
+	})
+	return result
+}
+
+// AuthDependencies groups the protected locations by the auth location
+// they depend on. Directives set above location level are keyed by the
+// enclosing server instead.
+func AuthDependencies(directives []*Directive) map[*Directive][]*Directive {
+	graph := make(map[*Directive][]*Directive)
+	for _, auth := range AuthRequests(directives) {
+		if auth.Target == nil {
+			continue
+		}
+		dependent := auth.Location
+		if dependent == nil {
+			dependent = auth.Server
+		}
+		graph[auth.Target] = append(graph[auth.Target], dependent)
+	}
+	return graph
+}
+
+func checkAuthRequests(directives []*Directive) []*Issue {
+	issues := make([]*Issue, 0)
+	for _, auth := range AuthRequests(directives) {
+		switch {
+		case auth.Target == nil:
+			issues = append(issues, newIssue("auth-request", auth.Directive, "auth_request %s does not match any location", auth.URI))
+		case auth.Target == auth.Location:
+			issues = append(issues, newIssue("auth-request", auth.Directive, "auth_request %s is routed back to the protected location", auth.URI))
+		case !auth.Internal:
+			issues = append(issues, newIssue("auth-request", auth.Directive, "auth_request %s is routed to location %s line %d which is not internal", auth.URI, auth.Target.FileName, auth.Target.Line))
+		}
+	}
+	return issues
+}
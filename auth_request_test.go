@@ -0,0 +1,50 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestAuthRequests(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        location /private/ {
+            auth_request /auth;
+        }
+        location /admin/ {
+            auth_request /missing;
+        }
+        location /public/ {
+            auth_request /public/check;
+        }
+        location = /auth {
+            internal;
+            proxy_pass http://auth;
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auths := AuthRequests(directives)
+	if len(auths) != 3 {
+		t.Fatalf("expected 3 auth requests but got %d", len(auths))
+	}
+	if auths[0].Target == nil || !auths[0].Internal {
+		t.Fatalf("expected /auth to resolve to an internal location")
+	}
+
+	graph := AuthDependencies(directives)
+	if len(graph[auths[0].Target]) != 1 || graph[auths[0].Target][0] != auths[0].Location {
+		t.Fatalf("unexpected dependency graph %v", graph)
+	}
+
+	issues, err := Lint(directives, "auth-request")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 || issues[0].Line != 8 || issues[1].Line != 11 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+}
@@ -0,0 +1,131 @@
+package nginxparser
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// defaultPorts are the ports nginx assumes when a *_pass URL leaves it out.
+var defaultPorts = map[string]string{
+	"http":   "80",
+	"https":  "443",
+	"grpc":   "80",
+	"grpcs":  "443",
+	"uwsgi":  "80",
+	"suwsgi": "443",
+}
+
+type Backend struct {
+	Directive *Directive `json:"-"`
+	Upstream  string     `json:"upstream,omitempty"`
+	Host      string     `json:"host"`
+	Port      string     `json:"port,omitempty"`
+	Unix      bool       `json:"unix,omitempty"`
+	Variables bool       `json:"variables,omitempty"`
+	Addresses []string   `json:"addresses,omitempty"`
+}
+
+// Endpoints returns the host:port pairs the backend is reached at, using
+// the resolved addresses when available.
+func (b *Backend) Endpoints() []string {
+	if b.Unix {
+		return []string{b.Host}
+	}
+	hosts := b.Addresses
+	if len(hosts) == 0 {
+		hosts = []string{b.Host}
+	}
+	result := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if b.Port == "" {
+			result = append(result, host)
+			continue
+		}
+		result = append(result, net.JoinHostPort(host, b.Port))
+	}
+	return result
+}
+
+type BackendOptions struct {
+	// Resolve looks up the addresses of backends named by host name.
+	Resolve bool
+	// Resolver defaults to net.LookupHost.
+	Resolver func(host string) ([]string, error)
+}
+
+// Backends lists every address the configuration proxies to: the servers
+// of upstream groups and the hosts of *_pass directives that do not name
+// an upstream. Implicit ports are filled in, and host names are resolved
+// when asked to. Targets built from variables are reported with Variables
+// set and are never resolved.
+func Backends(directives []*Directive, options *BackendOptions) ([]*Backend, error) {
+	if options == nil {
+		options = &BackendOptions{}
+	}
+	if options.Resolver == nil {
+		options.Resolver = net.LookupHost
+	}
+
+	result := make([]*Backend, 0)
+	for _, upstream := range upstreams(directives) {
+		for _, server := range findAll(upstream.Block, "server") {
+			if len(server.Args) == 0 {
+				continue
+			}
+			backend := &Backend{Directive: server, Upstream: upstreamName(upstream), Port: "80"}
+			address := server.Args[0]
+			switch {
+			case strings.HasPrefix(address, "unix:"):
+				backend.Host, backend.Port, backend.Unix = address, "", true
+			case strings.HasPrefix(address, "["):
+				_, backend.Host, backend.Port = splitPassTarget(address)
+			default:
+				if i := strings.LastIndexByte(address, ':'); i >= 0 {
+					backend.Host, backend.Port = address[:i], address[i+1:]
+				} else {
+					backend.Host = address
+				}
+			}
+			if backend.Port == "" && !backend.Unix {
+				backend.Port = "80"
+			}
+			result = append(result, backend)
+		}
+	}
+
+	for _, pass := range PassTargets(directives) {
+		if pass.Resolution == ResolveUpstream {
+			continue
+		}
+		backend := &Backend{
+			Directive: pass.Directive,
+			Host:      pass.Host,
+			Port:      pass.Port,
+			Unix:      pass.Resolution == ResolveUnix,
+			Variables: pass.Variables,
+		}
+		if backend.Port == "" && !backend.Unix {
+			backend.Port = defaultPorts[pass.Scheme]
+			if pass.Scheme == "" && pass.Directive.Directive == "grpc_pass" {
+				backend.Port = defaultPorts["grpc"]
+			}
+		}
+		result = append(result, backend)
+	}
+
+	for _, backend := range result {
+		switch {
+		case backend.Unix, backend.Variables:
+		case net.ParseIP(backend.Host) != nil:
+			backend.Addresses = []string{backend.Host}
+		case options.Resolve:
+			addresses, err := options.Resolver(backend.Host)
+			if err != nil {
+				return nil, fmt.Errorf("resolve %s in %s line %d: %w", backend.Host, backend.Directive.FileName, backend.Directive.Line, err)
+			}
+			backend.Addresses = addresses
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,74 @@
+package nginxparser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBackends(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    upstream app {
+        server app1.internal;
+        server 10.0.0.2:8080;
+        server [::1]:9000;
+        server unix:/run/app.sock;
+    }
+    server {
+        location / { proxy_pass http://app; }
+        location /api { proxy_pass https://api.example.com/v1; }
+        location /php { fastcgi_pass 127.0.0.1:9000; }
+        location /grpc { grpc_pass grpc-backend; }
+        location /dyn { proxy_pass http://$host$request_uri; }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := func(host string) ([]string, error) {
+		switch host {
+		case "app1.internal":
+			return []string{"10.0.0.1"}, nil
+		case "api.example.com":
+			return []string{"192.0.2.1", "192.0.2.2"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	_, err = Backends(directives, &BackendOptions{Resolve: true, Resolver: resolver})
+	if err == nil {
+		t.Fatal("expected error resolving grpc-backend")
+	}
+
+	backends, err := Backends(directives, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints := make([]string, 0)
+	for _, backend := range backends {
+		endpoints = append(endpoints, backend.Endpoints()...)
+	}
+	expected := []string{"app1.internal:80", "10.0.0.2:8080", "[::1]:9000", "unix:/run/app.sock", "api.example.com:443", "127.0.0.1:9000", "grpc-backend:80", "$host:80"}
+	if !reflect.DeepEqual(endpoints, expected) {
+		t.Fatalf("expected %v but got %v", expected, endpoints)
+	}
+	if backends[0].Upstream != "app" || !backends[len(backends)-1].Variables {
+		t.Fatalf("unexpected backends %+v", backends)
+	}
+
+	resolver = func(host string) ([]string, error) {
+		if host == "grpc-backend" {
+			return []string{"10.0.1.1"}, nil
+		}
+		return []string{"192.0.2.1", "192.0.2.2"}, nil
+	}
+	backends, err = Backends(directives, &BackendOptions{Resolve: true, Resolver: resolver})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoints := backends[4].Endpoints(); !reflect.DeepEqual(endpoints, []string{"192.0.2.1:443", "192.0.2.2:443"}) {
+		t.Fatalf("unexpected endpoints %v", endpoints)
+	}
+}
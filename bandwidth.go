@@ -0,0 +1,89 @@
+package nginxparser
+
+type BandwidthLimit struct {
+	Server       *Directive   `json:"-"`
+	Location     *Directive   `json:"-"`
+	Rate         int64        `json:"rate"`
+	RateVariable string       `json:"rate_variable,omitempty"`
+	After        int64        `json:"after"`
+	ProxyRate    int64        `json:"proxy_rate"`
+	Sources      []*Directive `json:"-"`
+}
+
+// Throttled reports whether responses or upstream reads are rate limited.
+func (b *BandwidthLimit) Throttled() bool {
+	return b.Rate > 0 || b.RateVariable != "" || b.ProxyRate > 0
+}
+
+// BandwidthLimits computes the effective limit_rate, limit_rate_after and
+// proxy_limit_rate of every server and location, following inheritance
+// and set $limit_rate overrides, which also apply to nested locations.
+// Rates are in bytes per second, zero meaning unlimited.
+func BandwidthLimits(directives []*Directive) []*BandwidthLimit {
+	result := make([]*BandwidthLimit, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive != "location" && (d.Directive != "server" || enclosing("http", parents) == nil) {
+			return true
+		}
+		limit := &BandwidthLimit{Sources: make([]*Directive, 0)}
+		if d.Directive == "server" {
+			limit.Server = d
+		} else {
+			limit.Server, limit.Location = enclosing("server", parents), d
+		}
+
+		size := func(name string) int64 {
+			source := lookupInherited(name, d.Block, parents)
+			if source == nil || len(source.Args) == 0 {
+				return 0
+			}
+			limit.Sources = append(limit.Sources, source)
+			if name == "limit_rate" && len(Variables(source.Args[0])) > 0 {
+				limit.RateVariable = source.Args[0]
+				return 0
+			}
+			value, _ := ParseSize(source.Args[0])
+			return value
+		}
+		limit.Rate = size("limit_rate")
+		limit.After = size("limit_rate_after")
+		limit.ProxyRate = size("proxy_limit_rate")
+
+		if set := lookupLimitRateSet(d.Block, parents); set != nil {
+			limit.Sources = append(limit.Sources, set)
+			limit.Rate, limit.RateVariable = 0, ""
+			if value, err := ParseSize(set.Args[1]); err == nil {
+				limit.Rate = value
+			} else {
+				limit.RateVariable = set.Args[1]
+			}
+		}
+		result = append(result, limit)
+		return true
+	})
+	return result
+}
+
+// lookupLimitRateSet returns the set $limit_rate that applies to block, the
+// last one in block or else in the nearest of parents. Assigning the
+// variable overrides limit_rate wherever it runs.
+func lookupLimitRateSet(block []*Directive, parents []*Directive) *Directive {
+	last := func(block []*Directive) *Directive {
+		var found *Directive
+		for _, set := range findAll(block, "set") {
+			if len(set.Args) == 2 && set.Args[0] == "$limit_rate" {
+				found = set
+			}
+		}
+		return found
+	}
+	if set := last(block); set != nil {
+		return set
+	}
+	for i := len(parents) - 1; i >= 0; i-- {
+		if set := last(parents[i].Block); set != nil {
+			return set
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestBandwidthLimits(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    limit_rate 1m;
+    server {
+        limit_rate_after 10m;
+        location / {
+        }
+        location /downloads/ {
+            limit_rate 100k;
+            proxy_limit_rate 2m;
+        }
+        location /video/ {
+            set $limit_rate 512k;
+        }
+        location /tiered/ {
+            limit_rate $tier_rate;
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limits := BandwidthLimits(directives)
+	if len(limits) != 5 {
+		t.Fatalf("expected 5 limits but got %d", len(limits))
+	}
+	expected := []struct {
+		rate, after, proxy int64
+		variable           string
+	}{
+		{1 << 20, 10 << 20, 0, ""},
+		{1 << 20, 10 << 20, 0, ""},
+		{100 << 10, 10 << 20, 2 << 20, ""},
+		{512 << 10, 10 << 20, 0, ""},
+		{0, 10 << 20, 0, "$tier_rate"},
+	}
+	for i, limit := range limits {
+		if limit.Rate != expected[i].rate || limit.After != expected[i].after || limit.ProxyRate != expected[i].proxy || limit.RateVariable != expected[i].variable || !limit.Throttled() {
+			t.Errorf("%d: unexpected limit %+v", i, limit)
+		}
+	}
+}
+
+func TestBandwidthLimitsInheritSet(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        set $limit_rate 256k;
+        location / {
+            limit_rate 1m;
+        }
+        location /premium/ {
+            set $limit_rate $premium_rate;
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limits := BandwidthLimits(directives)
+	if len(limits) != 3 {
+		t.Fatalf("expected 3 limits but got %d", len(limits))
+	}
+	expected := []struct {
+		rate     int64
+		variable string
+		line     int
+	}{
+		{256 << 10, "", 4},
+		{256 << 10, "", 4},
+		{0, "$premium_rate", 9},
+	}
+	for i, limit := range limits {
+		source := limit.Sources[len(limit.Sources)-1]
+		if limit.Rate != expected[i].rate || limit.RateVariable != expected[i].variable || source.Line != expected[i].line {
+			t.Errorf("%d: unexpected limit %+v", i, limit)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package nginxparser
+
+// NewDirective creates a simple directive such as listen 80.
+func NewDirective(name string, args ...string) *Directive {
+	if args == nil {
+		args = make([]string, 0)
+	}
+	return &Directive{Directive: name, Args: args}
+}
+
+// NewBlock creates a block directive such as location / { ... }.
+func NewBlock(name string, args []string, children ...*Directive) *Directive {
+	d := NewDirective(name, args...)
+	d.Block = append(make([]*Directive, 0, len(children)), children...)
+	setParents(d.Block, d)
+	return d
+}
+
+// NewComment creates a comment line, text is written after the #.
+func NewComment(text string) *Directive {
+	return &Directive{Directive: "#", Args: make([]string, 0), Comment: text}
+}
+
+// Clone returns a deep copy of d and its block, including the content of
+// include directives. The copy has no parent.
+func (d *Directive) Clone() *Directive {
+	clone := cloneDirectives([]*Directive{d})[0]
+	clone.Parent = nil
+	return clone
+}
+
+// CloneAll deep copies a list of directives as Clone does.
+func CloneAll(directives []*Directive) []*Directive {
+	result := cloneDirectives(directives)
+	for _, d := range result {
+		d.Parent = nil
+	}
+	return result
+}
@@ -0,0 +1,99 @@
+package nginxparser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+)
+
+// SchemaVersion is the version of the JSON form of directives and reports,
+// raised whenever it changes incompatibly.
+const SchemaVersion = 1
+
+// features are the optional behaviors of this version of the library, by
+// name, for services that need to know before relying on one. Keep them
+// sorted.
+var features = []string{
+	"annotations",
+	"comment-nodes",
+	"compact",
+	"context",
+	"crossplane-import",
+	"fleet",
+	"fs",
+	"include-concurrency",
+	"include-cycles",
+	"include-index",
+	"include-relative-to",
+	"lossless",
+	"max-include-depth",
+	"missing-include-policy",
+	"parser-concurrency",
+	"raw",
+	"record-includes",
+	"resolve-include",
+	"share-includes",
+	"source-map",
+	"strict",
+	"version-check",
+	"watermark",
+}
+
+type LibraryCapabilities struct {
+	SchemaVersion int `json:"schema_version"`
+	// Dialects are the flavors of configuration the parser reads: plain
+	// nginx, OpenResty with its Lua blocks and the escaping rules of
+	// crossplane.
+	Dialects []string `json:"dialects"`
+	// Versions are the nginx releases the catalog tells apart, the ones
+	// directives were added or removed in, oldest first.
+	Versions []Version `json:"nginx_versions"`
+	Features []string  `json:"features"`
+	Rules    []string  `json:"rules"`
+	// Directives is the number of documented directives and Catalog a
+	// digest of their documentation, which changes with the grammar
+	// strict mode validates against.
+	Directives int    `json:"directives"`
+	Catalog    string `json:"catalog"`
+}
+
+// HasFeature reports whether the library has the named feature.
+func (c *LibraryCapabilities) HasFeature(name string) bool {
+	i := sort.SearchStrings(c.Features, name)
+	return i < len(c.Features) && c.Features[i] == name
+}
+
+// Capabilities describes what this version of the library supports, to
+// be logged or compared by services embedding it.
+func Capabilities() *LibraryCapabilities {
+	seen := make(map[Version]bool)
+	versions := make([]Version, 0)
+	for _, doc := range directiveDocs {
+		for _, v := range []Version{doc.Since, doc.Removed} {
+			if !v.IsLatest() && !seen[v] {
+				seen[v] = true
+				versions = append(versions, v)
+			}
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Before(versions[j])
+	})
+
+	rules := make([]string, 0)
+	for _, rule := range Rules() {
+		rules = append(rules, rule.Name)
+	}
+	catalog, _ := json.Marshal(directiveDocs)
+	digest := sha256.Sum256(catalog)
+	return &LibraryCapabilities{
+		SchemaVersion: SchemaVersion,
+		Dialects:      []string{"nginx", "openresty", "crossplane"},
+		Versions:      versions,
+		Features:      append([]string(nil), features...),
+		Rules:         rules,
+		Directives:    len(directiveDocs),
+		Catalog:       "sha256:" + hex.EncodeToString(digest[:]),
+	}
+}
@@ -0,0 +1,35 @@
+package nginxparser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	c := Capabilities()
+	if c.SchemaVersion != SchemaVersion || c.Directives != len(directiveDocs) || !strings.HasPrefix(c.Catalog, "sha256:") {
+		t.Fatalf("unexpected capabilities %+v", c)
+	}
+	if !c.HasFeature("strict") || !c.HasFeature("watermark") || c.HasFeature("telepathy") {
+		t.Fatalf("unexpected features %v", c.Features)
+	}
+	for i := 1; i < len(c.Versions); i++ {
+		if !c.Versions[i-1].Before(c.Versions[i]) {
+			t.Fatalf("expected versions in order, got %v", c.Versions)
+		}
+	}
+	removed := false
+	for _, v := range c.Versions {
+		removed = removed || v == MustParseVersion("1.25.1")
+	}
+	if !removed || len(c.Rules) != len(rules) {
+		t.Fatalf("unexpected capabilities %+v", c)
+	}
+	if other := Capabilities(); other.Catalog != c.Catalog {
+		t.Fatal("expected the catalog digest to be stable")
+	}
+	if _, err := json.Marshal(c); err != nil {
+		t.Fatal(err)
+	}
+}
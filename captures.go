@@ -0,0 +1,77 @@
+package nginxparser
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// CheckCaptures validates that the $1..$9 capture references in
+// rewrite replacements and other directives correspond to groups of the
+// regex that last matched: the rewrite's own regex, an enclosing if
+// condition, or the regex location.
+func CheckCaptures(directives []*Directive) []*Issue {
+	issues := make([]*Issue, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		switch d.Directive {
+		case "location", "if", "#":
+			return true
+		case "rewrite":
+			if len(d.Args) < 2 {
+				return true
+			}
+			re, err := compileRegex(d.Args[0], false)
+			if err != nil {
+				return true
+			}
+			issues = append(issues, checkCaptureRefs(d, d.Args[1:2], re, "rewrite regex")...)
+			return true
+		}
+		re, source, ok := enclosingRegex(parents)
+		if ok {
+			issues = append(issues, checkCaptureRefs(d, d.Args, re, source)...)
+		}
+		return true
+	})
+	return issues
+}
+
+// enclosingRegex returns the innermost regex whose captures are visible to
+// directives in the given context.
+func enclosingRegex(parents []*Directive) (*regexp.Regexp, string, bool) {
+	for i := len(parents) - 1; i >= 0; i-- {
+		parent := parents[i]
+		switch parent.Directive {
+		case "if":
+			if len(parent.Args) == 3 && (parent.Args[1] == "~" || parent.Args[1] == "~*") {
+				re, err := compileRegex(parent.Args[2], parent.Args[1] == "~*")
+				return re, "if condition", err == nil
+			}
+		case "location":
+			modifier, _ := locationPattern(parent)
+			if modifier != locationRegex && modifier != locationRegexNoCase {
+				return nil, "prefix location", true
+			}
+			re, err := locationRegexp(parent)
+			return re, "location regex", err == nil
+		case "server", "http":
+			return nil, parent.Directive + " context", true
+		}
+	}
+	return nil, "", false
+}
+
+func checkCaptureRefs(d *Directive, args []string, re *regexp.Regexp, source string) []*Issue {
+	issues := make([]*Issue, 0)
+	groups := 0
+	if re != nil {
+		groups = re.NumSubexp()
+	}
+	for _, arg := range args {
+		for _, name := range Variables(arg) {
+			if n, err := strconv.Atoi(name); err == nil && n > groups {
+				issues = append(issues, newIssue("regex-captures", d, "$%d is always empty, the %s has %d capture groups", n, source, groups))
+			}
+		}
+	}
+	return issues
+}
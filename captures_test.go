@@ -0,0 +1,48 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestCheckCaptures(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        rewrite ^/user/(\d+)$ /profile?id=$1 last;
+        rewrite ^/(?<lang>en|de)/(.*)$ /$2?lang=$lang&x=$3 last;
+        location ~ ^/images/(.+)\.(png|jpg)$ {
+            proxy_pass http://images/$1.$2;
+            if ($arg_size ~ ^(\d+)$) {
+                return 302 /resized/$1/$2;
+            }
+        }
+        location /static/ {
+            return 301 https://cdn.example.com/$1;
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues := CheckCaptures(directives)
+	lines := []int{5, 9, 13}
+	if len(issues) != len(lines) {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	for i, issue := range issues {
+		if issue.Line != lines[i] {
+			t.Fatalf("unexpected issues %v", issues)
+		}
+	}
+}
+
+func TestCompileRegex(t *testing.T) {
+	re, err := compileRegex(`^/(?<lang>en|de)/\(?<x`, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if re.SubexpNames()[1] != "lang" || !re.MatchString("/EN/(<x") {
+		t.Fatalf("unexpected regex %s", re)
+	}
+}
@@ -0,0 +1,32 @@
+// Command nginx-parser inspects and edits nginx configurations.
+//
+//	nginx-parser repl /etc/nginx/nginx.conf
+//
+// starts a console over the parsed configuration, type help for its
+// commands.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	nginxparser "github.com/faceair/nginx-parser"
+)
+
+func main() {
+	if len(os.Args) != 3 || os.Args[1] != "repl" {
+		fmt.Fprintln(os.Stderr, "usage: nginx-parser repl <nginx.conf>")
+		os.Exit(2)
+	}
+	filename := os.Args[2]
+	directives, err := nginxparser.New(&nginxparser.ParseOptions{Root: filepath.Dir(filename), Lossless: true}).ParseFile(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := nginxparser.NewConsole(directives, nil).Run(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
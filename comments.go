@@ -0,0 +1,33 @@
+package nginxparser
+
+// attachComments fills in the LeadingComments and TrailingComment of the
+// directives in a block from the comments around them. Leading comments
+// must be on consecutive lines ending right above the directive.
+func attachComments(directives []*Directive, block *Directive) {
+	previous := block
+	leading := make([]*Directive, 0)
+	for _, d := range directives {
+		if d.Directive == "#" {
+			switch {
+			case d.Inline && previous != nil:
+				previous.TrailingComment = d.Comment
+			case len(leading) > 0 && leading[len(leading)-1].Line+1 != d.Line:
+				leading = append(leading[:0], d)
+			default:
+				leading = append(leading, d)
+			}
+			continue
+		}
+		if len(leading) > 0 && leading[len(leading)-1].Line+1 == d.Line {
+			d.LeadingComments = make([]string, 0, len(leading))
+			for _, comment := range leading {
+				d.LeadingComments = append(d.LeadingComments, comment.Comment)
+			}
+		}
+		leading = leading[:0]
+		if d.Directive != "include" {
+			attachComments(d.Block, d)
+		}
+		previous = d
+	}
+}
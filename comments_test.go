@@ -0,0 +1,81 @@
+package nginxparser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestComments(t *testing.T) {
+	directives, err := New(nil).ParseFile(filepath.Join("testdata", "with-comments", "nginx.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	http := directives[2]
+	if !reflect.DeepEqual(http.LeadingComments, []string{"comment"}) {
+		t.Fatalf("unexpected leading comments %q", http.LeadingComments)
+	}
+	server := http.Block[0]
+	listen, location := server.Block[0], server.Block[3]
+	if listen.TrailingComment != "listen" || listen.LeadingComments != nil {
+		t.Fatalf("unexpected comments %q %q", listen.LeadingComments, listen.TrailingComment)
+	}
+	if location.TrailingComment != "# this is brace" {
+		t.Fatalf("unexpected trailing comment %q", location.TrailingComment)
+	}
+	if ret := location.Block[2]; !reflect.DeepEqual(ret.LeadingComments, []string{" location /"}) || ret.TrailingComment != "" {
+		t.Fatalf("unexpected comments %q %q", ret.LeadingComments, ret.TrailingComment)
+	}
+
+	directives, err = New(&ParseOptions{SingleFile: true}).ParseString(`
+# detached
+
+# first
+# second
+server_tokens off;
+# last`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(directives[3].LeadingComments, []string{" first", " second"}) {
+		t.Fatalf("unexpected leading comments %q", directives[3].LeadingComments)
+	}
+}
+
+func TestCommentNodes(t *testing.T) {
+	filename := filepath.Join("testdata", "comments-between-args", "nginx.conf")
+	directives, err := New(&ParseOptions{CommentNodes: true}).ParseFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := directives[0].Block
+	if len(block) != 6 {
+		t.Fatalf("expected a node per comment, got %d directives", len(block))
+	}
+	for i, comment := range []string{"comment 1", "comment 2", "comment 3", "comment 4", "comment 5"} {
+		if block[i].Directive != "#" || block[i].Comment != comment || block[i].Line != i+1 {
+			t.Fatalf("unexpected comment node %+v", block[i])
+		}
+	}
+	if logFormat := block[5]; logFormat.Directive != "log_format" || logFormat.Comment != "" || logFormat.Line != 2 {
+		t.Fatalf("unexpected directive %+v", logFormat)
+	}
+
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	directives, err = New(&ParseOptions{CommentNodes: true, Lossless: true}).ParseFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Dump(directives, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != string(src) {
+		t.Fatalf("expected the lossless output to match the source, got\n%s", buf.String())
+	}
+}
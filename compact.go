@@ -0,0 +1,227 @@
+package nginxparser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Merge is one merge made by Compact: Merged was folded into Into and
+// removed from the configuration.
+type Merge struct {
+	Into     *Directive `json:"-"`
+	Merged   *Directive `json:"-"`
+	FileName string     `json:"filename"`
+	Line     int        `json:"line"`
+	Message  string     `json:"message"`
+}
+
+func (m *Merge) String() string {
+	return fmt.Sprintf("%s:%d: %s", m.FileName, m.Line, m.Message)
+}
+
+// Compact shrinks a configuration by merging sibling blocks where nginx
+// behaves the same afterwards, also when the siblings come from different
+// included files:
+//
+//   - a location repeating the modifier, path and body of an earlier one
+//     is removed,
+//   - consecutive regex locations with the same body are merged into one
+//     regex alternation, unless the body uses captures,
+//   - repeated types blocks are merged into the first one,
+//   - map entries repeating an earlier key and value are removed and
+//     consecutive regex entries with the same value are merged.
+//
+// Bodies are compared ignoring comments. The directives are modified in
+// place, the returned slice replaces the top-level ones.
+func Compact(directives []*Directive) ([]*Directive, []*Merge) {
+	merges := make([]*Merge, 0)
+	removed := make(map[*Directive]bool)
+	compactBlock(directives, removed, &merges)
+	return removeDirectives(directives, removed), merges
+}
+
+var (
+	captureReference = regexp.MustCompile(`\$\{?[0-9]`)
+	backReference    = regexp.MustCompile(`\\[0-9gk]`)
+	// inlineOption matches an option setting such as (?i) that would
+	// carry over into the following alternatives.
+	inlineOption = regexp.MustCompile(`\(\?[a-zA-Z-]+\)`)
+)
+
+func compactBlock(block []*Directive, removed map[*Directive]bool, merges *[]*Merge) {
+	siblings := children(block)
+	merge := func(into, merged *Directive, format string, args ...interface{}) {
+		removed[merged] = true
+		*merges = append(*merges, &Merge{
+			Into:     into,
+			Merged:   merged,
+			FileName: merged.FileName,
+			Line:     merged.Line,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	var types, regex *Directive
+	for i, d := range siblings {
+		switch d.Directive {
+		case "types":
+			if types == nil {
+				types = d
+				continue
+			}
+			for _, entry := range d.Block {
+				MarkMoved(entry)
+				entry.Parent = types
+			}
+			setIncludedBy(d.Block, d.IncludedBy, types.IncludedBy)
+			types.Block = append(types.Block, d.Block...)
+			merge(types, d, "types block merged into %s:%d", types.FileName, types.Line)
+		case "location":
+			if original := duplicateLocation(siblings[:i], d, removed); original != nil {
+				merge(original, d, "duplicate location %s removed, same as %s:%d", strings.Join(d.Args, " "), original.FileName, original.Line)
+				continue
+			}
+			modifier, pattern := locationPattern(d)
+			if modifier != locationRegex && modifier != locationRegexNoCase {
+				continue
+			}
+			if regex != nil && mergeableRegex(regex, d) {
+				before := strings.Join(regex.Args, " ")
+				regexModifier, regexPattern := locationPattern(regex)
+				setLocationPattern(regex, regexModifier, alternation(regexPattern, pattern))
+				merge(regex, d, "location %s merged into location %s at %s:%d", strings.Join(d.Args, " "), before, regex.FileName, regex.Line)
+				continue
+			}
+			regex = d
+		case "map":
+			compactMap(d, merge)
+		}
+	}
+	for _, d := range siblings {
+		if !removed[d] && !isLuaBlock(d) && !opaqueBlocks[d.Directive] {
+			compactBlock(d.Block, removed, merges)
+		}
+	}
+}
+
+func duplicateLocation(before []*Directive, d *Directive, removed map[*Directive]bool) *Directive {
+	modifier, pattern := locationPattern(d)
+	for _, other := range before {
+		if other.Directive != "location" || removed[other] {
+			continue
+		}
+		if m, p := locationPattern(other); m == modifier && p == pattern && Equal(other.Block, d.Block, IgnoreLines(), IgnoreFileNames(), IgnoreComments()) {
+			return other
+		}
+	}
+	return nil
+}
+
+// mergeableRegex reports whether the regex locations a and b, a matched
+// right before b, can become one location.
+func mergeableRegex(a, b *Directive) bool {
+	modifierA, patternA := locationPattern(a)
+	modifierB, patternB := locationPattern(b)
+	if modifierA != modifierB || !mergeablePattern(patternA) || !mergeablePattern(patternB) {
+		return false
+	}
+	if !Equal(a.Block, b.Block, IgnoreLines(), IgnoreFileNames(), IgnoreComments()) {
+		return false
+	}
+	uses := false
+	Walk(a.Block, func(d *Directive, parents []*Directive) bool {
+		for _, arg := range d.Args {
+			uses = uses || captureReference.MatchString(arg)
+		}
+		return !uses
+	})
+	return !uses
+}
+
+// mergeablePattern rejects patterns with named groups or backreferences,
+// which an alternation would break.
+func mergeablePattern(pattern string) bool {
+	return pattern != "" && !strings.Contains(pattern, "(?<") && !strings.Contains(pattern, "(?P<") && !strings.Contains(pattern, "(?'") && !backReference.MatchString(pattern)
+}
+
+// alternation matches what either a or b matches.
+func alternation(a, b string) string {
+	if inlineOption.MatchString(a) {
+		a = "(?:" + a + ")"
+	}
+	if inlineOption.MatchString(b) {
+		b = "(?:" + b + ")"
+	}
+	return a + "|" + b
+}
+
+func setLocationPattern(d *Directive, modifier, pattern string) {
+	if len(d.Args) == 1 {
+		d.Args[0] = modifier + pattern
+		return
+	}
+	d.Args[1] = pattern
+}
+
+// compactMap removes repeated entries of a map and merges consecutive
+// regex entries with the same value.
+func compactMap(d *Directive, merge func(into, merged *Directive, format string, args ...interface{})) {
+	seen := make(map[string]*Directive)
+	var regex *Directive
+	for _, entry := range children(d.Block) {
+		if len(entry.Args) != 1 {
+			continue
+		}
+		key := entry.Directive + "\x00" + entry.Args[0]
+		if original, ok := seen[key]; ok {
+			merge(original, entry, "duplicate map entry %s removed, same as %s:%d", entry.Directive, original.FileName, original.Line)
+			continue
+		}
+		seen[key] = entry
+		if !strings.HasPrefix(entry.Directive, "~") {
+			continue
+		}
+		if regex != nil && mergeableMapEntry(regex, entry) {
+			before := regex.Directive
+			modifier := mapRegexModifier(regex.Directive)
+			regex.Directive = modifier + alternation(regex.Directive[len(modifier):], entry.Directive[len(modifier):])
+			merge(regex, entry, "map entry %s merged into %s at %s:%d", entry.Directive, before, regex.FileName, regex.Line)
+			continue
+		}
+		regex = entry
+	}
+}
+
+func mapRegexModifier(key string) string {
+	if strings.HasPrefix(key, "~*") {
+		return "~*"
+	}
+	return "~"
+}
+
+func mergeableMapEntry(a, b *Directive) bool {
+	modifier := mapRegexModifier(a.Directive)
+	return modifier == mapRegexModifier(b.Directive) &&
+		a.Args[0] == b.Args[0] &&
+		!strings.Contains(a.Args[0], "$") &&
+		mergeablePattern(a.Directive[len(modifier):]) &&
+		mergeablePattern(b.Directive[len(modifier):])
+}
+
+// removeDirectives drops removed from block and every nested block,
+// including those of include directives.
+func removeDirectives(block []*Directive, removed map[*Directive]bool) []*Directive {
+	if block == nil {
+		return nil
+	}
+	result := block[:0]
+	for _, d := range block {
+		if removed[d] {
+			continue
+		}
+		d.Block = removeDirectives(d.Block, removed)
+		result = append(result, d)
+	}
+	return result
+}
@@ -0,0 +1,148 @@
+package nginxparser
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestCompact(t *testing.T) {
+	fsys := fstest.MapFS{
+		"nginx.conf": {Data: []byte(`http {
+    types {
+        text/html html;
+    }
+    types {
+        image/png png;
+    }
+    map $uri $kind {
+        default other;
+        /a page;
+        ~^/img/ image;
+        ~\.png$ image;
+        /b page;
+        ~^/static/(.*) $1;
+        ~^/assets/(.*) $1;
+        /a page;
+    }
+    server {
+        location / {
+            root /srv;
+        }
+        include locations.conf;
+        location ~ \.jpg$ {
+            expires 1d;
+        }
+        location ~ \.gif$ {
+            # generated
+            expires 1d;
+        }
+        location ~* \.css$ {
+            expires 1d;
+        }
+        location ~ ^/u/(\d+)$ {
+            proxy_pass http://users/$1;
+        }
+        location ~ ^/v/(\d+)$ {
+            proxy_pass http://users/$1;
+        }
+    }
+}
+`)},
+		"locations.conf": {Data: []byte(`location / {
+    root /srv;
+}
+location /other {
+    root /srv;
+}
+location ~ \.png$ {
+    expires 1d;
+}
+`)},
+	}
+	directives, err := New(&ParseOptions{FS: fsys, Root: ".", RawEscapes: true}).ParseFile("nginx.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	directives, merges := Compact(directives)
+	expected := []string{
+		"nginx.conf:5: types block merged into nginx.conf:2",
+		"nginx.conf:12: map entry ~\\.png$ merged into ~^/img/ at nginx.conf:11",
+		"nginx.conf:16: duplicate map entry /a removed, same as nginx.conf:10",
+		"locations.conf:1: duplicate location / removed, same as nginx.conf:19",
+		"nginx.conf:23: location ~ \\.jpg$ merged into location ~ \\.png$ at locations.conf:7",
+		"nginx.conf:26: location ~ \\.gif$ merged into location ~ \\.png$|\\.jpg$ at locations.conf:7",
+	}
+	if len(merges) != len(expected) {
+		t.Fatalf("unexpected merges %v", merges)
+	}
+	for i, merge := range merges {
+		if merge.String() != expected[i] {
+			t.Fatalf("expected %q but got %q", expected[i], merge)
+		}
+	}
+
+	var out string
+	for _, d := range directives {
+		out += d.String()
+	}
+	compacted, err := New(&ParseOptions{SingleFile: true, RawEscapes: true}).ParseString(`http {
+    types {
+        text/html html;
+        image/png png;
+    }
+    map $uri $kind {
+        default other;
+        /a page;
+        ~^/img/|\.png$ image;
+        /b page;
+        ~^/static/(.*) $1;
+        ~^/assets/(.*) $1;
+    }
+    server {
+        location / {
+            root /srv;
+        }
+        include locations.conf;
+        location ~* \.css$ {
+            expires 1d;
+        }
+        location ~ ^/u/(\d+)$ {
+            proxy_pass http://users/$1;
+        }
+        location ~ ^/v/(\d+)$ {
+            proxy_pass http://users/$1;
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	include := compacted[0].Block[2].Block[1]
+	include.Block, err = New(&ParseOptions{SingleFile: true, RawEscapes: true}).ParseString(`location /other {
+    root /srv;
+}
+location ~ \.png$|\.jpg$|\.gif$ {
+    expires 1d;
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(directives, compacted, IgnoreLines(), IgnoreFileNames(), IgnoreComments()) {
+		t.Fatalf("unexpected compacted configuration:\n%s", out)
+	}
+	if types := directives[0].Block[0]; types.Block[1].Provenance == nil || types.Block[1].Provenance.Line != 6 {
+		t.Fatalf("expected the moved type to record its position, got %+v", types.Block[1].Provenance)
+	}
+}
+
+func TestAlternation(t *testing.T) {
+	for _, c := range [][3]string{
+		{`\.png$`, `\.jpg$`, `\.png$|\.jpg$`},
+		{`(?i)\.png$`, `\.jpg$`, `(?:(?i)\.png$)|\.jpg$`},
+		{`^/a|^/b`, `(?i)^/c`, `^/a|^/b|(?:(?i)^/c)`},
+	} {
+		if got := alternation(c[0], c[1]); got != c[2] {
+			t.Fatalf("expected %q but got %q", c[2], got)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package nginxparser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Operators of if conditions, a negated operator is reported with the
+// positive one and Negate set.
+const (
+	// ConditionVariable is true when the variable is neither empty nor
+	// "0".
+	ConditionVariable         = ""
+	ConditionEqual            = "="
+	ConditionMatch            = "~"
+	ConditionMatchInsensitive = "~*"
+	ConditionFile             = "-f"
+	ConditionDirectory        = "-d"
+	ConditionExists           = "-e"
+	ConditionExecutable       = "-x"
+)
+
+var fileConditions = map[string]bool{
+	ConditionFile:       true,
+	ConditionDirectory:  true,
+	ConditionExists:     true,
+	ConditionExecutable: true,
+}
+
+type Condition struct {
+	Directive *Directive `json:"-"`
+	Operator  string     `json:"operator,omitempty"`
+	Negate    bool       `json:"negate,omitempty"`
+	// Variable is the name, without $, of the variable compared or tested.
+	// It is empty for file tests.
+	Variable string `json:"variable,omitempty"`
+	// Operand is the string compared to, the regex, or the path of a file
+	// test. Strings and paths may contain variables.
+	Operand string         `json:"operand,omitempty"`
+	Regex   *regexp.Regexp `json:"-"`
+}
+
+// ParseCondition reads the condition of an if directive the way nginx
+// does, and fails on conditions nginx rejects.
+func ParseCondition(d *Directive) (*Condition, error) {
+	args := d.Args
+	c := &Condition{Directive: d}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty if condition")
+	}
+	if operator := strings.TrimPrefix(args[0], "!"); fileConditions[operator] {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s takes a single path in if condition", args[0])
+		}
+		c.Operator, c.Negate, c.Operand = operator, operator != args[0], args[1]
+		return c, nil
+	}
+	if len(args[0]) < 2 || args[0][0] != '$' {
+		return nil, fmt.Errorf("invalid if condition %q, expected a variable or a file test", args[0])
+	}
+	c.Variable = strings.Trim(args[0][1:], "{}")
+	if len(args) == 1 {
+		return c, nil
+	}
+	if len(args) != 3 {
+		return nil, fmt.Errorf("invalid number of arguments in if condition")
+	}
+	operator := strings.TrimPrefix(args[1], "!")
+	c.Operator, c.Negate, c.Operand = operator, operator != args[1], args[2]
+	switch operator {
+	case ConditionEqual:
+	case ConditionMatch, ConditionMatchInsensitive:
+		re, err := compileRegex(args[2], operator == ConditionMatchInsensitive)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q in if condition: %s", args[2], err)
+		}
+		c.Regex = re
+	default:
+		return nil, fmt.Errorf("unexpected %q in if condition", args[1])
+	}
+	return c, nil
+}
+
+// Compare evaluates a condition on a variable against its value, and the
+// operand with its variables expanded. It returns the captures of a
+// matching regex. File tests are never true, their result depends on the
+// file system.
+func (c *Condition) Compare(value, operand string) (bool, []string) {
+	var result bool
+	var captures []string
+	switch c.Operator {
+	case ConditionVariable:
+		result = value != "" && value != "0"
+	case ConditionEqual:
+		result = value == operand
+	case ConditionMatch, ConditionMatchInsensitive:
+		captures = c.Regex.FindStringSubmatch(value)
+		result = captures != nil
+	default:
+		return false, nil
+	}
+	if c.Negate {
+		return !result, nil
+	}
+	return result, captures
+}
+
+func (c *Condition) String() string {
+	operator := c.Operator
+	if c.Negate {
+		operator = "!" + operator
+	}
+	words := make([]string, 0, 3)
+	if c.Variable != "" {
+		words = append(words, "$"+c.Variable)
+	}
+	if operator != "" {
+		words = append(words, operator)
+	}
+	if c.Operand != "" || c.Operator == ConditionEqual {
+		words = append(words, c.Operand)
+	}
+	return strings.Join(words, " ")
+}
+
+// Conditions returns the parsed condition of every if directive, with the
+// issues for the conditions nginx would reject.
+func Conditions(directives []*Directive) ([]*Condition, []*Issue) {
+	conditions := make([]*Condition, 0)
+	issues := make([]*Issue, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive != "if" {
+			return true
+		}
+		c, err := ParseCondition(d)
+		if err != nil {
+			issues = append(issues, newIssue("if-condition", d, "%s", err))
+			return true
+		}
+		conditions = append(conditions, c)
+		return true
+	})
+	return conditions, issues
+}
+
+func checkConditions(directives []*Directive) []*Issue {
+	_, issues := Conditions(directives)
+	return issues
+}
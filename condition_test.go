@@ -0,0 +1,53 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestParseCondition(t *testing.T) {
+	directives, err := New(&ParseOptions{}).ParseString(`server {
+    if ($http_upgrade) { return 426; }
+    if ($request_method != GET) { return 405; }
+    if ($http_user_agent ~* "^curl/(\d+)") { return 403; }
+    if (!-f $request_filename) { return 404; }
+    if ($host == example.com) { return 400; }
+    if (-f) { return 400; }
+    if (host = example.com) { return 400; }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conditions, issues := Conditions(directives)
+	if len(conditions) != 4 || len(issues) != 3 {
+		t.Fatalf("expected 4 conditions and 3 issues, got %d and %+v", len(conditions), issues)
+	}
+	for _, issue := range issues {
+		if issue.Rule != "if-condition" || issue.Line < 6 {
+			t.Fatalf("unexpected issue %+v", issue)
+		}
+	}
+
+	upgrade, method, agent, file := conditions[0], conditions[1], conditions[2], conditions[3]
+	if upgrade.Operator != ConditionVariable || upgrade.Variable != "http_upgrade" {
+		t.Fatalf("unexpected condition %+v", upgrade)
+	}
+	if ok, _ := upgrade.Compare("websocket", ""); !ok {
+		t.Fatal("expected a set variable to be true")
+	}
+	if ok, _ := upgrade.Compare("0", ""); ok {
+		t.Fatal("expected 0 to be false")
+	}
+	if method.Operator != ConditionEqual || !method.Negate || method.Operand != "GET" || method.String() != "$request_method != GET" {
+		t.Fatalf("unexpected condition %+v", method)
+	}
+	if ok, _ := method.Compare("POST", "GET"); !ok {
+		t.Fatal("expected POST to differ from GET")
+	}
+	if ok, captures := agent.Compare("Curl/8.1", ""); !ok || len(captures) != 2 || captures[1] != "8" {
+		t.Fatalf("expected a case insensitive match, got %v", captures)
+	}
+	if file.Operator != ConditionFile || !file.Negate || file.Operand != "$request_filename" || file.Variable != "" {
+		t.Fatalf("unexpected condition %+v", file)
+	}
+}
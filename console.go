@@ -0,0 +1,288 @@
+package nginxparser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const consoleHelp = `commands:
+  query <path>               print the values GetStrings finds for path
+  explain <directive>        show the documentation of a directive
+  simulate <host> <uri> [port]
+                             show the server and location handling a request
+  set <path> [args...]       replace the arguments of the directives path
+                             finds, or add the directive to their parents
+  rm <path>                  remove the directives path finds
+  write                      write the modified files back
+  help                       show this help
+  quit                       leave, asking again when changes are not written
+`
+
+// Console is an interactive session over a parsed configuration: it
+// queries and edits the tree in memory and writes the files back only when
+// asked to.
+type Console struct {
+	Directives []*Directive
+	options    *WriteOptions
+	// modified holds the files changed since the last write.
+	modified map[string]bool
+	quitting bool
+}
+
+// NewConsole starts a session over directives, options is used by the
+// write command.
+func NewConsole(directives []*Directive, options *WriteOptions) *Console {
+	return &Console{Directives: directives, options: options, modified: make(map[string]bool)}
+}
+
+// Run reads commands from r one per line, printing a prompt before each
+// and the results to w, until r ends or the session is quit.
+func (c *Console) Run(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	for {
+		fmt.Fprint(w, "nginx> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(w)
+			return scanner.Err()
+		}
+		quit, err := c.Exec(scanner.Text(), w)
+		if err != nil {
+			fmt.Fprintf(w, "error: %s\n", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// Exec runs one command line and writes its output to w. quit is set once
+// the session should end.
+func (c *Console) Exec(line string, w io.Writer) (quit bool, err error) {
+	words := splitConsoleWords(line)
+	if len(words) == 0 {
+		return false, nil
+	}
+	command, args := words[0], words[1:]
+	if command != "quit" && command != "exit" {
+		c.quitting = false
+	}
+	switch command {
+	case "help":
+		fmt.Fprint(w, consoleHelp)
+	case "query":
+		if len(args) != 1 {
+			return false, fmt.Errorf("usage: query <path>")
+		}
+		values := GetStrings(c.Directives, args[0])
+		if len(values) == 0 {
+			fmt.Fprintln(w, "no match")
+		}
+		for _, value := range values {
+			fmt.Fprintln(w, value)
+		}
+	case "explain":
+		if len(args) != 1 {
+			return false, fmt.Errorf("usage: explain <directive>")
+		}
+		doc, ok := Lookup(args[0])
+		if !ok {
+			return false, fmt.Errorf("unknown directive %s", args[0])
+		}
+		fmt.Fprintf(w, "syntax:  %s\n", doc.Syntax)
+		if doc.Default != "" {
+			fmt.Fprintf(w, "default: %s %s;\n", doc.Name, doc.Default)
+		}
+		fmt.Fprintf(w, "context: %s\n", strings.Join(doc.Contexts, ", "))
+		fmt.Fprintf(w, "module:  %s\n", doc.Module)
+		fmt.Fprintln(w, doc.URL())
+	case "simulate":
+		if len(args) < 2 || len(args) > 3 {
+			return false, fmt.Errorf("usage: simulate <host> <uri> [port]")
+		}
+		port := "80"
+		if len(args) == 3 {
+			port = args[2]
+		}
+		route := RouteRequest(c.Directives, args[0], port, args[1])
+		if route.Server == nil {
+			fmt.Fprintln(w, "no server")
+			return false, nil
+		}
+		fmt.Fprintf(w, "server   %s:%d %s\n", route.Server.FileName, route.Server.Line, strings.Join(serverNames(route.Server), " "))
+		if route.Location == nil {
+			fmt.Fprintln(w, "no location")
+			return false, nil
+		}
+		fmt.Fprintf(w, "location %s:%d %s\n", route.Location.FileName, route.Location.Line, strings.Join(route.Location.Args, " "))
+	case "set":
+		if len(args) < 1 {
+			return false, fmt.Errorf("usage: set <path> [args...]")
+		}
+		values := make([]string, 0, len(args)-1)
+		for _, arg := range args[1:] {
+			if unquoted, err := strconv.Unquote(arg); err == nil && strings.HasPrefix(arg, `"`) {
+				arg = unquoted
+			}
+			values = append(values, arg)
+		}
+		message, err := c.set(args[0], values)
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintln(w, message)
+	case "rm":
+		if len(args) != 1 {
+			return false, fmt.Errorf("usage: rm <path>")
+		}
+		found, err := c.find(args[0])
+		if err != nil {
+			return false, err
+		}
+		removed := make(map[*Directive]bool, len(found))
+		for _, d := range found {
+			removed[d] = true
+			c.modified[d.FileName] = true
+		}
+		c.Directives = removeDirectives(c.Directives, removed)
+		fmt.Fprintf(w, "removed %s\n", countDirectives(len(found)))
+	case "write":
+		written, err := c.write()
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintf(w, "wrote %s\n", countFiles(written))
+	case "quit", "exit":
+		if len(c.modified) > 0 && !c.quitting {
+			c.quitting = true
+			fmt.Fprintln(w, "changes are not written, write them or quit again to discard them")
+			return false, nil
+		}
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown command %s, try help", command)
+	}
+	return false, nil
+}
+
+// find returns the directives path ends on.
+func (c *Console) find(path string) ([]*Directive, error) {
+	segments := splitGetPath(path)
+	if segments == nil {
+		return nil, fmt.Errorf("invalid path %s", path)
+	}
+	found, _ := getPath(c.Directives, nil, segments)
+	if len(found) == 0 {
+		return nil, fmt.Errorf("no directive matches %s", path)
+	}
+	return found, nil
+}
+
+func (c *Console) set(path string, args []string) (string, error) {
+	segments := splitGetPath(path)
+	if segments == nil {
+		return "", fmt.Errorf("invalid path %s", path)
+	}
+	if found, _ := getPath(c.Directives, nil, segments); len(found) > 0 {
+		for _, d := range found {
+			d.Args, d.Quotes, d.ArgPositions = append([]string{}, args...), nil, nil
+			c.modified[d.FileName] = true
+		}
+		return "set " + countDirectives(len(found)), nil
+	}
+
+	name := segments[len(segments)-1]
+	if name == "*" || name == "args" || strings.HasPrefix(name, "#") {
+		return "", fmt.Errorf("no directive matches %s", path)
+	}
+	if len(segments) == 1 {
+		d := NewDirective(name, args...)
+		if len(c.Directives) > 0 {
+			d.FileName = c.Directives[0].FileName
+		}
+		c.Directives = append(c.Directives, d)
+		c.modified[d.FileName] = true
+		return "added 1 directive", nil
+	}
+	parents, _ := getPath(c.Directives, nil, segments[:len(segments)-1])
+	if len(parents) == 0 {
+		return "", fmt.Errorf("no directive matches %s", strings.Join(segments[:len(segments)-1], "."))
+	}
+	for _, parent := range parents {
+		d := NewDirective(name, args...)
+		d.FileName, d.Parent = parent.FileName, parent
+		parent.Block = append(parent.Block, d)
+		c.modified[d.FileName] = true
+	}
+	return "added " + countDirectives(len(parents)), nil
+}
+
+// write writes the modified files back and returns how many it wrote.
+func (c *Console) write() (int, error) {
+	options := &WriteOptions{}
+	if c.options != nil {
+		*options = *c.options
+	}
+	write := options.Write
+	if write == nil {
+		write = writeFile
+	}
+	written := 0
+	options.Write = func(name string, data []byte) error {
+		if !c.modified[name] {
+			return nil
+		}
+		written++
+		return write(name, data)
+	}
+	if err := WriteFiles(c.Directives, options); err != nil {
+		return written, err
+	}
+	c.modified = make(map[string]bool)
+	return written, nil
+}
+
+func countFiles(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
+
+func countDirectives(n int) string {
+	if n == 1 {
+		return "1 directive"
+	}
+	return fmt.Sprintf("%d directives", n)
+}
+
+// splitConsoleWords splits a command line at spaces outside of quoted
+// strings, keeping the quotes.
+func splitConsoleWords(line string) []string {
+	words := make([]string, 0)
+	quoted, start := false, -1
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case !quoted && (c == ' ' || c == '\t'):
+			if start >= 0 {
+				words = append(words, line[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, line[start:])
+	}
+	return words
+}
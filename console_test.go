@@ -0,0 +1,70 @@
+package nginxparser
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestConsole(t *testing.T) {
+	fsys := fstest.MapFS{
+		"nginx.conf":     {Data: []byte("http {\n    server {\n        listen 80;\n        server_name example.com;\n        include locations.conf;\n    }\n}\n")},
+		"locations.conf": {Data: []byte("location / {\n    root /srv;\n}\nlocation /api/ {\n    proxy_pass http://api;\n}\n")},
+	}
+	directives, err := New(&ParseOptions{FS: fsys, Root: "."}).ParseFile("nginx.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := make(map[string]string)
+	console := NewConsole(directives, &WriteOptions{Write: func(name string, data []byte) error {
+		written[name] = string(data)
+		return nil
+	}})
+
+	script := strings.Join([]string{
+		`query http.server.location.#(args.0=="/api/").proxy_pass.args.0`,
+		`explain proxy_pass`,
+		`explain gzip`,
+		`simulate example.com /api/users`,
+		`set http.server.location.#(args.0=="/api/").proxy_pass http://api-v2`,
+		`set http.server.location.#(args.0=="/").expires "1 d"`,
+		`rm http.server.location.#(args.0=="/").root`,
+		`rm http.server.nope`,
+		`frobnicate`,
+		`quit`,
+		`write`,
+		`quit`,
+	}, "\n")
+	var out strings.Builder
+	if err := console.Run(strings.NewReader(script), &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"nginx> http://api\n",
+		"syntax:  proxy_pass URL;\n",
+		"default: gzip off;\n",
+		"server   nginx.conf:2 example.com\nlocation locations.conf:4 /api/\n",
+		"nginx> set 1 directive\n",
+		"nginx> added 1 directive\n",
+		"nginx> removed 1 directive\n",
+		"error: no directive matches http.server.nope\n",
+		"error: unknown command frobnicate, try help\n",
+		"nginx> changes are not written, write them or quit again to discard them\n",
+		"nginx> wrote 1 file\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Fatalf("expected %q in the output:\n%s", expected, out.String())
+		}
+	}
+	expected := "location / {\n    expires \"1 d\";\n}\nlocation /api/ {\n    proxy_pass http://api-v2;\n}\n"
+	if _, ok := written["nginx.conf"]; ok || written["locations.conf"] != expected {
+		t.Fatalf("expected only locations.conf to be written as %q, got %q", expected, written)
+	}
+}
+
+func TestSplitConsoleWords(t *testing.T) {
+	words := splitConsoleWords(`  set a.#(b=="c d") "e \" f"  g`)
+	if !equalStrings(words, []string{"set", `a.#(b=="c d")`, `"e \" f"`, "g"}) {
+		t.Fatalf("unexpected words %q", words)
+	}
+}
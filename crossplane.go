@@ -0,0 +1,113 @@
+package nginxparser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type crossplanePayload struct {
+	Status string             `json:"status"`
+	Errors []*crossplaneError `json:"errors"`
+	Config []*crossplaneFile  `json:"config"`
+}
+
+type crossplaneFile struct {
+	File   string       `json:"file"`
+	Parsed []*Directive `json:"parsed"`
+}
+
+type crossplaneError struct {
+	File  string `json:"file"`
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// FromCrossplane loads the JSON written by crossplane parse into a tree,
+// as if the first file of the payload had been parsed with its includes
+// followed: include directives hold the directives of the files they
+// name, taken from the payload. Comments come along when the payload has
+// them. When the payload reports errors the directives that were parsed
+// are returned with a ParseError of kind ErrSyntax for the first error.
+func FromCrossplane(r io.Reader) ([]*Directive, error) {
+	payload := &crossplanePayload{}
+	if err := json.NewDecoder(r).Decode(payload); err != nil {
+		return nil, err
+	}
+	if len(payload.Config) == 0 {
+		return nil, errors.New("crossplane payload has no config")
+	}
+	for _, file := range payload.Config {
+		if file.Parsed == nil {
+			file.Parsed = make([]*Directive, 0)
+		}
+		setCrossplaneFile(file.Parsed, file.File, nil)
+		annotate(file.Parsed, nil)
+		attachComments(file.Parsed, nil)
+	}
+
+	directives := payload.Config[0].Parsed
+	if err := expandCrossplane(directives, payload.Config, []int{0}); err != nil {
+		return nil, err
+	}
+	setParents(directives, nil)
+	if len(payload.Errors) > 0 {
+		first := payload.Errors[0]
+		return directives, &ParseError{Kind: ErrSyntax, FileName: first.File, Line: first.Line, Message: first.Error}
+	}
+	return directives, nil
+}
+
+// setCrossplaneFile sets the file name of the directives of a file and
+// marks the comments on the line of the directive before them, or of the
+// opening brace, as inline.
+func setCrossplaneFile(directives []*Directive, filename string, block *Directive) {
+	previous := block
+	for _, d := range directives {
+		d.FileName = filename
+		if d.Args == nil {
+			d.Args = make([]string, 0)
+		}
+		if d.Directive == "#" {
+			d.Inline = previous != nil && previous.Line == d.Line
+			continue
+		}
+		setCrossplaneFile(d.Block, filename, d)
+		previous = d
+	}
+}
+
+// expandCrossplane fills the include directives of directives with copies
+// of the files their Includes point to. stack holds the files being
+// expanded, to stop on cycles.
+func expandCrossplane(directives []*Directive, files []*crossplaneFile, stack []int) error {
+	for _, d := range directives {
+		if d.Directive != "include" || d.Includes == nil {
+			if err := expandCrossplane(d.Block, files, stack); err != nil {
+				return err
+			}
+			continue
+		}
+		block := make([]*Directive, 0)
+		for _, i := range d.Includes {
+			if i < 0 || i >= len(files) {
+				return fmt.Errorf("%s:%d: include of config %d, the payload has %d", d.FileName, d.Line, i, len(files))
+			}
+			for _, j := range stack {
+				if i == j {
+					return fmt.Errorf("%s:%d: %w: %s is already being included", d.FileName, d.Line, ErrIncludeCycle, files[i].File)
+				}
+			}
+			included := cloneDirectives(files[i].Parsed)
+			if err := expandCrossplane(included, files, append(stack[:len(stack):len(stack)], i)); err != nil {
+				return err
+			}
+			setIncludedBy(included, nil, d)
+			block = append(block, included...)
+			d.pulled = append(d.pulled, files[i].File)
+		}
+		d.Block, d.Includes = block, nil
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package nginxparser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const crossplanePayloadJSON = `{
+  "status": "ok",
+  "errors": [],
+  "config": [
+    {
+      "file": "/etc/nginx/nginx.conf",
+      "status": "ok",
+      "errors": [],
+      "parsed": [
+        {"directive": "#", "line": 1, "args": [], "comment": " main"},
+        {"directive": "events", "line": 2, "args": [], "block": []},
+        {"directive": "http", "line": 3, "args": [], "block": [
+          {"directive": "include", "line": 4, "args": ["conf.d/*.conf"], "includes": [1, 2]}
+        ]}
+      ]
+    },
+    {
+      "file": "/etc/nginx/conf.d/a.conf",
+      "status": "ok",
+      "errors": [],
+      "parsed": [
+        {"directive": "server", "line": 1, "args": [], "block": [
+          {"directive": "listen", "line": 2, "args": ["80"]},
+          {"directive": "#", "line": 2, "args": [], "comment": " plain http"},
+          {"directive": "return", "line": 3, "args": ["200", "a b"]}
+        ]}
+      ]
+    },
+    {
+      "file": "/etc/nginx/conf.d/b.conf",
+      "status": "ok",
+      "errors": [],
+      "parsed": []
+    }
+  ]
+}`
+
+func TestFromCrossplane(t *testing.T) {
+	directives, err := FromCrossplane(strings.NewReader(crossplanePayloadJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := New(&ParseOptions{SingleFile: true}).ParseString(`# main
+events {
+}
+http {
+    include conf.d/*.conf;
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected[2].Block[0].Block, err = New(&ParseOptions{SingleFile: true}).ParseString(`server {
+    listen 80; # plain http
+    return 200 "a b";
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(directives, expected, IgnoreLines(), IgnoreFileNames()) {
+		t.Fatalf("unexpected tree %s", directives[2])
+	}
+	include := directives[2].Block[0]
+	server := include.Block[0]
+	if include.Includes != nil || server.FileName != "/etc/nginx/conf.d/a.conf" || server.IncludedBy != include || server.Parent != directives[2] {
+		t.Fatalf("unexpected include %+v", server)
+	}
+	if listen := server.Block[0]; listen.TrailingComment != " plain http" || !server.Block[1].Inline {
+		t.Fatalf("expected an inline comment, got %+v", listen)
+	}
+
+	failed := strings.Replace(crossplanePayloadJSON, `"status": "ok",
+  "errors": [],`, `"status": "failed",
+  "errors": [{"file": "/etc/nginx/conf.d/b.conf", "line": 3, "error": "unexpected \"}\" in /etc/nginx/conf.d/b.conf:3"}],`, 1)
+	directives, err = FromCrossplane(strings.NewReader(failed))
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || !errors.Is(err, ErrSyntax) || parseErr.Line != 3 || len(directives) != 3 {
+		t.Fatalf("expected the payload error, got %v", err)
+	}
+
+	cycle := strings.Replace(crossplanePayloadJSON, `"parsed": []`, `"parsed": [{"directive": "include", "line": 1, "args": ["../nginx.conf"], "includes": [0]}]`, 1)
+	if _, err := FromCrossplane(strings.NewReader(cycle)); !errors.Is(err, ErrIncludeCycle) {
+		t.Fatalf("expected an include cycle, got %v", err)
+	}
+	if _, err := FromCrossplane(strings.NewReader(`{"status": "ok", "config": []}`)); err == nil {
+		t.Fatal("expected an empty payload to fail")
+	}
+}
@@ -0,0 +1,135 @@
+package nginxparser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrNoDecoder = errors.New("no decoder registered")
+
+// Decoder turns the arguments of a directive into a typed value.
+type Decoder func(d *Directive) (interface{}, error)
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]Decoder{
+		"listen": func(d *Directive) (interface{}, error) {
+			return ParseListen(d), nil
+		},
+		"resolver": func(d *Directive) (interface{}, error) {
+			return ParseResolver(d)
+		},
+		"ssl_protocols": func(d *Directive) (interface{}, error) {
+			return ParseSSLProtocols(d)
+		},
+		"proxy_ssl_protocols": func(d *Directive) (interface{}, error) {
+			return ParseSSLProtocols(d)
+		},
+	}
+)
+
+// RegisterDecoder sets the decoder used for a directive name, replacing
+// any decoder registered before, including the built-in ones.
+func RegisterDecoder(name string, decoder Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[name] = decoder
+}
+
+// Decode decodes the arguments of d with the decoder registered for its
+// name. The error wraps ErrNoDecoder when there is none.
+func Decode(d *Directive) (interface{}, error) {
+	decodersMu.RLock()
+	decoder, ok := decoders[d.Directive]
+	decodersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", d.Directive, ErrNoDecoder)
+	}
+	return decoder(d)
+}
+
+type Resolver struct {
+	Directive *Directive `json:"-"`
+	Addresses []string   `json:"addresses"`
+	// Valid overrides the TTL of responses, zero when not set.
+	Valid time.Duration `json:"valid,omitempty"`
+	IPv4  bool          `json:"ipv4"`
+	IPv6  bool          `json:"ipv6"`
+	// StatusZone is only available in the commercial subscription.
+	StatusZone string `json:"status_zone,omitempty"`
+}
+
+// ParseResolver splits a resolver directive into the name servers and the
+// parameters that follow them.
+func ParseResolver(d *Directive) (*Resolver, error) {
+	resolver := &Resolver{Directive: d, Addresses: make([]string, 0), IPv4: true, IPv6: true}
+	for _, arg := range d.Args {
+		i := strings.IndexByte(arg, '=')
+		if i < 0 {
+			resolver.Addresses = append(resolver.Addresses, arg)
+			continue
+		}
+		value := arg[i+1:]
+		switch arg[:i] {
+		case "valid":
+			valid, err := ParseDuration(value)
+			if err != nil {
+				return nil, err
+			}
+			resolver.Valid = valid
+		case "ipv4", "ipv6":
+			if value != "on" && value != "off" {
+				return nil, fmt.Errorf("invalid resolver parameter %q", arg)
+			}
+			if arg[:i] == "ipv4" {
+				resolver.IPv4 = value == "on"
+			} else {
+				resolver.IPv6 = value == "on"
+			}
+		case "status_zone":
+			resolver.StatusZone = value
+		default:
+			return nil, fmt.Errorf("invalid resolver parameter %q", arg)
+		}
+	}
+	if len(resolver.Addresses) == 0 {
+		return nil, fmt.Errorf("resolver without addresses")
+	}
+	return resolver, nil
+}
+
+var sslProtocols = []string{"SSLv2", "SSLv3", "TLSv1", "TLSv1.1", "TLSv1.2", "TLSv1.3"}
+
+// SSLProtocols is the set of protocols enabled by ssl_protocols.
+type SSLProtocols map[string]bool
+
+// ParseSSLProtocols reads the protocols enabled by an ssl_protocols or
+// proxy_ssl_protocols directive.
+func ParseSSLProtocols(d *Directive) (SSLProtocols, error) {
+	protocols := make(SSLProtocols)
+	for _, arg := range d.Args {
+		known := false
+		for _, name := range sslProtocols {
+			known = known || name == arg
+		}
+		if !known {
+			return nil, fmt.Errorf("invalid protocol %q", arg)
+		}
+		protocols[arg] = true
+	}
+	return protocols, nil
+}
+
+// List returns the enabled protocols, oldest first.
+func (s SSLProtocols) List() []string {
+	result := make([]string, 0, len(s))
+	for _, name := range sslProtocols {
+		if s[name] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
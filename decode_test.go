@@ -0,0 +1,51 @@
+package nginxparser
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDecode(t *testing.T) {
+	resolver, err := Decode(NewDirective("resolver", "127.0.0.53", "[::1]:5353", "valid=30s", "ipv6=off"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Resolver{Addresses: []string{"127.0.0.53", "[::1]:5353"}, Valid: 30 * time.Second, IPv4: true}
+	if r := resolver.(*Resolver); !reflect.DeepEqual(r.Addresses, expected.Addresses) || r.Valid != expected.Valid || !r.IPv4 || r.IPv6 {
+		t.Fatalf("unexpected resolver %+v", r)
+	}
+	if _, err := Decode(NewDirective("resolver", "valid=30s")); err == nil {
+		t.Fatal("expected an error for a resolver without addresses")
+	}
+
+	protocols, err := Decode(NewDirective("ssl_protocols", "TLSv1.3", "TLSv1.2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := protocols.(SSLProtocols).List(); !reflect.DeepEqual(list, []string{"TLSv1.2", "TLSv1.3"}) {
+		t.Fatalf("unexpected protocols %v", list)
+	}
+	if _, err := Decode(NewDirective("ssl_protocols", "TLSv2")); err == nil {
+		t.Fatal("expected an error for an unknown protocol")
+	}
+
+	if listen, err := Decode(NewDirective("listen", "443", "ssl")); err != nil || !listen.(*Listen).Has("ssl") {
+		t.Fatalf("unexpected listen %+v %v", listen, err)
+	}
+
+	if _, err := Decode(NewDirective("worker_processes", "4")); !errors.Is(err, ErrNoDecoder) {
+		t.Fatalf("expected ErrNoDecoder, got %v", err)
+	}
+	RegisterDecoder("worker_processes", func(d *Directive) (interface{}, error) {
+		if len(d.Args) == 1 && d.Args[0] == "auto" {
+			return 0, nil
+		}
+		return strconv.Atoi(d.Args[0])
+	})
+	if n, err := Decode(NewDirective("worker_processes", "4")); err != nil || n != 4 {
+		t.Fatalf("expected the registered decoder to be used, got %v %v", n, err)
+	}
+}
@@ -0,0 +1,217 @@
+package nginxparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+	// ChangeMoved is a directive removed from one file and added unchanged
+	// in another, such as a server moved out of nginx.conf into conf.d.
+	ChangeMoved = "moved"
+	// ChangeReordered is a directive whose position among the siblings
+	// nginx evaluates in order, such as regex locations or rewrite rules,
+	// changed.
+	ChangeReordered = "reordered"
+)
+
+// Change is a difference between two configurations. Path names the
+// blocks leading to the changed directive, and Server is the server block
+// it belongs to, nil for changes outside any server. For moves OldPath and
+// OldServer are where the directive was before.
+type Change struct {
+	Kind      string     `json:"kind"`
+	Path      []string   `json:"path"`
+	OldPath   []string   `json:"old_path,omitempty"`
+	Old       *Directive `json:"-"`
+	New       *Directive `json:"-"`
+	Server    *Directive `json:"-"`
+	OldServer *Directive `json:"-"`
+}
+
+func (c *Change) String() string {
+	d := c.New
+	if d == nil {
+		d = c.Old
+	}
+	if c.Kind == ChangeMoved {
+		return fmt.Sprintf("%s %s (%s) to %s (%s)", c.Kind, diffPath(c.OldPath, c.Old), c.Old.FileName, diffPath(c.Path, c.New), c.New.FileName)
+	}
+	return fmt.Sprintf("%s %s", c.Kind, diffPath(c.Path, d))
+}
+
+func diffPath(path []string, d *Directive) string {
+	return strings.Join(append(path[:len(path):len(path)], diffLabel(d)), " > ")
+}
+
+// Diff compares two parsed configurations by their effective structure:
+// includes are expanded and comments ignored. Blocks are matched by name
+// and arguments, servers by their names and listen addresses, and
+// directives that occur once on both sides are reported as modified when
+// only their arguments changed. A directive removed from one file and
+// added with the same effective content in another is reported as moved.
+// Siblings whose order matters to nginx are reported as reordered when
+// they are found in a different order.
+func Diff(old, new []*Directive) []*Change {
+	changes := make([]*Change, 0)
+	diffBlock("", old, new, nil, nil, nil, &changes)
+	return diffMoves(changes)
+}
+
+// diffMoves pairs every removed directive with an identical one added in
+// another file and replaces the pair with a single move.
+func diffMoves(changes []*Change) []*Change {
+	moved := make(map[*Change]bool)
+	for _, removed := range changes {
+		if removed.Kind != ChangeRemoved {
+			continue
+		}
+		for _, added := range changes {
+			if added.Kind != ChangeAdded || moved[added] || added.New.FileName == removed.Old.FileName || !sameEffect(removed.Old, added.New) {
+				continue
+			}
+			moved[removed], moved[added] = true, true
+			added.Kind, added.Old, added.OldPath, added.OldServer = ChangeMoved, removed.Old, removed.Path, removed.Server
+			break
+		}
+	}
+	result := make([]*Change, 0, len(changes))
+	for _, change := range changes {
+		if change.Kind != ChangeRemoved || !moved[change] {
+			result = append(result, change)
+		}
+	}
+	return result
+}
+
+// sameEffect reports whether two directives have the same arguments and,
+// with includes expanded and comments ignored, the same contents.
+func sameEffect(a, b *Directive) bool {
+	if a.Directive != b.Directive || !equalStrings(a.Args, b.Args) || isBlock(a) != isBlock(b) {
+		return false
+	}
+	inner := make([]*Change, 0)
+	diffBlock(a.Directive, a.Block, b.Block, nil, nil, nil, &inner)
+	return len(inner) == 0
+}
+
+// diffBlock compares the blocks old and new of a parent directive.
+func diffBlock(parent string, old, new []*Directive, path []string, oldServer, newServer *Directive, changes *[]*Change) {
+	old, new = children(old), children(new)
+	oldCounts, newCounts := make(map[string]int), make(map[string]int)
+	for _, d := range old {
+		oldCounts[d.Directive]++
+	}
+	for _, d := range new {
+		newCounts[d.Directive]++
+	}
+	single := func(name string) bool {
+		return oldCounts[name] == 1 && newCounts[name] == 1
+	}
+
+	oldKeys := diffKeys(old, single)
+	matched := make(map[string]*Directive)
+	positions := make(map[string]int)
+	for i, d := range old {
+		matched[oldKeys[i]] = d
+		positions[oldKeys[i]] = i
+	}
+	seen := make(map[string]bool)
+	// last is the old position of the last order sensitive directive
+	// found in new, to spot the ones coming after it in old.
+	last := -1
+	for i, key := range diffKeys(new, single) {
+		d := new[i]
+		server := newServer
+		if d.Directive == "server" && isBlock(d) && newServer == nil {
+			server = d
+		}
+		o, ok := matched[key]
+		if !ok {
+			*changes = append(*changes, &Change{Kind: ChangeAdded, Path: path, New: d, Server: server})
+			continue
+		}
+		seen[key] = true
+		if orderSensitive(parent, d) && orderSensitive(parent, o) {
+			if positions[key] < last {
+				*changes = append(*changes, &Change{Kind: ChangeReordered, Path: path, Old: o, New: d, Server: server})
+			}
+			if positions[key] > last {
+				last = positions[key]
+			}
+		}
+		if !isBlock(d) && !isBlock(o) {
+			if !equalStrings(o.Args, d.Args) {
+				*changes = append(*changes, &Change{Kind: ChangeModified, Path: path, Old: o, New: d, Server: server})
+			}
+			continue
+		}
+		oldInner := oldServer
+		if server == d {
+			oldInner = o
+		}
+		diffBlock(d.Directive, o.Block, d.Block, append(path[:len(path):len(path)], diffLabel(d)), oldInner, server, changes)
+	}
+	for i, key := range oldKeys {
+		if !seen[key] {
+			server := oldServer
+			if old[i].Directive == "server" && isBlock(old[i]) && oldServer == nil {
+				server = old[i]
+			}
+			*changes = append(*changes, &Change{Kind: ChangeRemoved, Path: path, Old: old[i], Server: server})
+		}
+	}
+}
+
+// orderSensitive reports whether nginx evaluates d in order with its
+// siblings of the block of parent, so moving it changes the behavior.
+func orderSensitive(parent string, d *Directive) bool {
+	switch d.Directive {
+	case "rewrite", "if", "return", "set":
+		return true
+	case "location":
+		modifier, _ := locationPattern(d)
+		return modifier == locationRegex || modifier == locationRegexNoCase
+	}
+	return (parent == "map" || parent == "geo") && strings.HasPrefix(d.Directive, "~")
+}
+
+func diffKeys(directives []*Directive, single func(name string) bool) []string {
+	keys := make([]string, len(directives))
+	occurrences := make(map[string]int)
+	for i, d := range directives {
+		key := d.Directive
+		if isBlock(d) || !single(d.Directive) {
+			key = diffLabel(d)
+		}
+		occurrences[key]++
+		keys[i] = fmt.Sprintf("%s#%d", key, occurrences[key])
+	}
+	return keys
+}
+
+func diffLabel(d *Directive) string {
+	words := append([]string{d.Directive}, d.Args...)
+	if d.Directive == "server" && isBlock(d) {
+		words = append(words, serverNames(d)...)
+		for _, listen := range findAll(d.Block, "listen") {
+			words = append(words, listen.Args...)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,224 @@
+package nginxparser
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDiff(t *testing.T) {
+	parse := func(s string) []*Directive {
+		directives, err := New(&ParseOptions{SingleFile: true}).ParseString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return directives
+	}
+	old := parse(`
+http {
+    gzip on;
+    server {
+        server_name a.example.com;
+        location / {
+            proxy_pass http://a;
+            add_header X-A 1;
+        }
+    }
+    server {
+        server_name b.example.com;
+    }
+}`)
+	new := parse(`
+http {
+    # comments are ignored
+    gzip on;
+    server {
+        server_name a.example.com;
+        location / {
+            proxy_pass http://a2;
+            add_header X-A 1;
+            add_header X-B 2;
+        }
+        location /new {
+            return 204;
+        }
+    }
+}`)
+
+	changes := Diff(old, new)
+	result := make([]string, 0, len(changes))
+	for _, change := range changes {
+		result = append(result, change.String())
+	}
+	expected := []string{
+		"modified http > server a.example.com > location / > proxy_pass http://a2",
+		"added http > server a.example.com > location / > add_header X-B 2",
+		"added http > server a.example.com > location /new",
+		"removed http > server b.example.com",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %q but got %q", expected, result)
+	}
+	if changes[0].Old.Args[0] != "http://a" || serverNames(changes[0].Server)[0] != "a.example.com" {
+		t.Fatalf("unexpected change %+v", changes[0])
+	}
+	if len(Diff(old, old)) != 0 {
+		t.Fatal("expected no changes comparing a configuration with itself")
+	}
+}
+
+func TestDiffMoves(t *testing.T) {
+	parse := func(files fstest.MapFS) []*Directive {
+		directives, err := New(&ParseOptions{FS: files, Root: "/etc/nginx"}).ParseFile("/etc/nginx/nginx.conf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return directives
+	}
+	old := parse(fstest.MapFS{
+		"etc/nginx/nginx.conf": {Data: []byte(`
+http {
+    server {
+        server_name a.example.com;
+        location /api {
+            proxy_pass http://api;
+        }
+    }
+    server {
+        server_name b.example.com;
+    }
+}`)},
+	})
+	new := parse(fstest.MapFS{
+		"etc/nginx/nginx.conf": {Data: []byte(`
+http {
+    server {
+        server_name a.example.com;
+    }
+    server {
+        server_name b.example.com;
+        include conf.d/api.conf;
+    }
+}`)},
+		"etc/nginx/conf.d/api.conf": {Data: []byte(`
+location /api {
+    # moved
+    proxy_pass http://api;
+}`)},
+	})
+
+	changes := Diff(old, new)
+	result := make([]string, 0, len(changes))
+	for _, change := range changes {
+		result = append(result, change.String())
+	}
+	expected := []string{
+		"moved http > server a.example.com > location /api (/etc/nginx/nginx.conf) to http > server b.example.com > location /api (/etc/nginx/conf.d/api.conf)",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %q but got %q", expected, result)
+	}
+	if servers := impact(changes, old, new); !reflect.DeepEqual(servers, []string{"a.example.com", "b.example.com"}) {
+		t.Fatalf("unexpected impact %q", servers)
+	}
+
+	changed := parse(fstest.MapFS{
+		"etc/nginx/nginx.conf": {Data: []byte(`
+http {
+    server {
+        server_name a.example.com;
+    }
+    server {
+        server_name b.example.com;
+        include conf.d/api.conf;
+    }
+}`)},
+		"etc/nginx/conf.d/api.conf": {Data: []byte(`
+location /api {
+    proxy_pass http://api2;
+}`)},
+	})
+	result = result[:0]
+	for _, change := range Diff(old, changed) {
+		result = append(result, change.Kind)
+	}
+	if !reflect.DeepEqual(result, []string{ChangeRemoved, ChangeAdded}) {
+		t.Fatalf("expected a changed block not to be a move, got %q", result)
+	}
+}
+
+func TestDiffReorder(t *testing.T) {
+	parse := func(s string) []*Directive {
+		directives, err := New(&ParseOptions{SingleFile: true}).ParseString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return directives
+	}
+	old := parse(`
+http {
+    map $uri $bot {
+        ~^/a 1;
+        ~^/b 2;
+        /c 3;
+        /d 4;
+    }
+    server {
+        server_name a.example.com;
+        rewrite ^/old /new;
+        rewrite ^/new /newer;
+        location /static { root /srv; }
+        location /files { root /srv; }
+        location ~ /admin { return 403; }
+        location ~ \.php$ { proxy_pass http://php; }
+    }
+}`)
+	new := parse(`
+http {
+    map $uri $bot {
+        ~^/a 1;
+        ~^/b 2;
+        /d 4;
+        /c 3;
+    }
+    server {
+        server_name a.example.com;
+        rewrite ^/new /newer;
+        rewrite ^/old /new;
+        location /files { root /srv; }
+        location /static { root /srv; }
+        location ~ \.php$ { proxy_pass http://php; }
+        location ~ /admin { return 403; }
+    }
+}`)
+	changes := Diff(old, new)
+	result := make([]string, 0, len(changes))
+	for _, change := range changes {
+		result = append(result, change.String())
+	}
+	expected := []string{
+		"reordered http > server a.example.com > rewrite ^/old /new",
+		`reordered http > server a.example.com > location ~ /admin`,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %q but got %q", expected, result)
+	}
+
+	new = parse(`
+http {
+    map $uri $bot {
+        ~^/b 2;
+        ~^/a 1;
+    }
+}`)
+	old = parse(`
+http {
+    map $uri $bot {
+        ~^/a 1;
+        ~^/b 2;
+    }
+}`)
+	if changes := Diff(old, new); len(changes) != 1 || changes[0].Kind != ChangeReordered || changes[0].New.Directive != "~^/a" {
+		t.Fatalf("expected the map entries to be reordered, got %v", changes)
+	}
+}
@@ -0,0 +1,509 @@
+package nginxparser
+
+import (
+	"strings"
+)
+
+// DirectiveDoc describes a directive as documented on nginx.org. A zero
+// Since means the directive is available in every supported release, a
+// zero Removed that it has not been removed.
+type DirectiveDoc struct {
+	Name     string   `json:"name"`
+	Module   string   `json:"module"`
+	Syntax   string   `json:"syntax"`
+	Default  string   `json:"default,omitempty"`
+	Contexts []string `json:"contexts"`
+	Since    Version  `json:"since"`
+	Removed  Version  `json:"removed"`
+	// Block is set for directives followed by a block. MinArgs and
+	// MaxArgs bound the number of arguments, MaxArgs is -1 when there is
+	// no limit.
+	Block   bool `json:"block"`
+	MinArgs int  `json:"min_args"`
+	MaxArgs int  `json:"max_args"`
+}
+
+// URL links to the documentation of the directive on nginx.org.
+func (d DirectiveDoc) URL() string {
+	page := d.Module
+	for _, prefix := range []string{"ngx_http_", "ngx_stream_", "ngx_mail_"} {
+		if strings.HasPrefix(d.Module, prefix) {
+			page = strings.TrimSuffix(strings.TrimPrefix(prefix, "ngx_"), "_") + "/" + d.Module
+		}
+	}
+	return "https://nginx.org/en/docs/" + page + ".html#" + d.Name
+}
+
+// Available reports whether the directive exists in the given release.
+func (d DirectiveDoc) Available(v Version) bool {
+	if d.Since != (Version{}) && v.Before(d.Since) {
+		return false
+	}
+	return d.Removed == (Version{}) || v.Before(d.Removed)
+}
+
+// AllowedIn reports whether the directive may appear in the given context,
+// such as "http", "location" or "if in location".
+func (d DirectiveDoc) AllowedIn(context string) bool {
+	for _, c := range d.Contexts {
+		if c == context || c == "any" {
+			return true
+		}
+	}
+	return false
+}
+
+func doc(module, name, syntax, def, contexts, since, removed string) DirectiveDoc {
+	d := DirectiveDoc{
+		Name:     name,
+		Module:   module,
+		Syntax:   name + " " + syntax + ";",
+		Default:  def,
+		Contexts: strings.Split(contexts, ", "),
+	}
+	d.MinArgs, d.MaxArgs, d.Block = syntaxArgs(syntax)
+	if since != "" {
+		d.Since = MustParseVersion(since)
+	}
+	if removed != "" {
+		d.Removed = MustParseVersion(removed)
+	}
+	return d
+}
+
+// unboundedArgs stands for any number of arguments while counting them.
+const unboundedArgs = 1 << 20
+
+// syntaxArgs counts the arguments allowed by the syntax of a directive as
+// written in the documentation: [optional] parts, alternatives separated by
+// |, ... repeating the part before it and a trailing { ... } block.
+func syntaxArgs(syntax string) (min, max int, block bool) {
+	if strings.HasSuffix(syntax, "{ ... }") {
+		syntax, block = strings.TrimSuffix(syntax, "{ ... }"), true
+	}
+	tokens := make([]string, 0)
+	for _, word := range strings.Fields(syntax) {
+		if enclosed(word) || strings.Count(word, "[") == strings.Count(word, "]") {
+			tokens = append(tokens, word)
+			continue
+		}
+		for strings.HasPrefix(word, "[") && !enclosed(word) {
+			tokens, word = append(tokens, "["), word[1:]
+		}
+		closing := 0
+		for strings.HasSuffix(word, "]") && strings.Count(word, "[") < strings.Count(word, "]") {
+			word, closing = word[:len(word)-1], closing+1
+		}
+		if word != "" {
+			tokens = append(tokens, word)
+		}
+		for ; closing > 0; closing-- {
+			tokens = append(tokens, "]")
+		}
+	}
+	min, max, _ = countArgs(tokens)
+	if max >= unboundedArgs {
+		max = -1
+	}
+	return min, max, block
+}
+
+// enclosed reports whether the whole word is one [optional] part.
+func enclosed(word string) bool {
+	if !strings.HasPrefix(word, "[") || !strings.HasSuffix(word, "]") {
+		return false
+	}
+	depth := 0
+	for i, c := range word {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i == len(word)-1
+			}
+		}
+	}
+	return false
+}
+
+// countArgs counts the alternatives of tokens up to the "]" closing them
+// and returns the tokens after it.
+func countArgs(tokens []string) (min, max int, rest []string) {
+	min = -1
+	for {
+		altMin, altMax := 0, 0
+		for len(tokens) > 0 && tokens[0] != "|" && tokens[0] != "]" {
+			token := tokens[0]
+			tokens = tokens[1:]
+			switch {
+			case token == "[":
+				_, innerMax, after := countArgs(tokens)
+				tokens = after
+				if len(tokens) > 0 {
+					tokens = tokens[1:]
+				}
+				altMax += innerMax
+			case token == "...":
+				altMax = unboundedArgs
+			case enclosed(token):
+				altMax += argMax(token[1 : len(token)-1])
+			case strings.HasPrefix(token, "(") && strings.HasSuffix(token, ")"):
+				// The condition of if is split into several arguments.
+				altMin, altMax = altMin+1, unboundedArgs
+			default:
+				altMin, altMax = altMin+1, altMax+argMax(token)
+			}
+		}
+		if min < 0 || altMin < min {
+			min = altMin
+		}
+		if altMax > max {
+			max = altMax
+		}
+		if len(tokens) == 0 || tokens[0] == "]" {
+			return min, max, tokens
+		}
+		tokens = tokens[1:]
+	}
+}
+
+// argMax is the number of arguments a word of a syntax stands for, any
+// number for the parameters of listen and server.
+func argMax(word string) int {
+	if word == "parameters" {
+		return unboundedArgs
+	}
+	return 1
+}
+
+const (
+	coreModule     = "ngx_core_module"
+	httpCoreModule = "ngx_http_core_module"
+	proxyModule    = "ngx_http_proxy_module"
+	rewriteModule  = "ngx_http_rewrite_module"
+	sslModule      = "ngx_http_ssl_module"
+	upstreamModule = "ngx_http_upstream_module"
+	otelModule     = "ngx_otel_module"
+)
+
+// directiveDocs is ordered so that for a name documented by several
+// modules the most commonly meant one comes first.
+var directiveDocs = []DirectiveDoc{
+	doc(coreModule, "daemon", "on | off", "on", "main", "", ""),
+	doc(coreModule, "env", "variable[=value]", "TZ", "main", "", ""),
+	doc(coreModule, "error_log", "file [level]", "logs/error.log error", "main, http, mail, stream, server, location", "", ""),
+	doc(coreModule, "events", "{ ... }", "", "main", "", ""),
+	doc(coreModule, "include", "file | mask", "", "any", "", ""),
+	doc(coreModule, "load_module", "file", "", "main", "1.9.11", ""),
+	doc(coreModule, "multi_accept", "on | off", "off", "events", "", ""),
+	doc(coreModule, "pid", "file", "logs/nginx.pid", "main", "", ""),
+	doc(coreModule, "thread_pool", "name threads=number [max_queue=number]", "default threads=32 max_queue=65536", "main", "1.7.11", ""),
+	doc(coreModule, "use", "method", "", "events", "", ""),
+	doc(coreModule, "user", "user [group]", "nobody nobody", "main", "", ""),
+	doc(coreModule, "worker_connections", "number", "512", "events", "", ""),
+	doc(coreModule, "worker_processes", "number | auto", "1", "main", "", ""),
+	doc(coreModule, "worker_rlimit_nofile", "number", "", "main", "", ""),
+	doc(coreModule, "worker_cpu_affinity", "cpumask ... | auto [cpumask]", "", "main", "", ""),
+	doc(coreModule, "worker_priority", "number", "0", "main", "", ""),
+	doc(coreModule, "worker_rlimit_core", "size", "", "main", "", ""),
+	doc(coreModule, "worker_shutdown_timeout", "time", "", "main", "1.11.11", ""),
+	doc(coreModule, "working_directory", "directory", "", "main", "", ""),
+	doc(coreModule, "lock_file", "file", "logs/nginx.lock", "main", "", ""),
+	doc(coreModule, "pcre_jit", "on | off", "off", "main", "1.1.12", ""),
+	doc(coreModule, "timer_resolution", "interval", "", "main", "", ""),
+	doc(coreModule, "master_process", "on | off", "on", "main", "", ""),
+	doc(coreModule, "ssl_engine", "device", "", "main", "", ""),
+	doc(coreModule, "accept_mutex", "on | off", "off", "events", "", ""),
+	doc(coreModule, "accept_mutex_delay", "time", "500ms", "events", "", ""),
+	doc(coreModule, "worker_aio_requests", "number", "32", "events", "1.1.4", ""),
+
+	doc(httpCoreModule, "http", "{ ... }", "", "main", "", ""),
+	doc(httpCoreModule, "server", "{ ... }", "", "http", "", ""),
+	doc(httpCoreModule, "location", "[ = | ~ | ~* | ^~ ] uri { ... }", "", "server, location", "", ""),
+	doc(httpCoreModule, "listen", "address[:port] [default_server] [ssl] [http2 | quic] [proxy_protocol] [parameters]", "*:80 | *:8000", "server", "", ""),
+	doc(httpCoreModule, "server_name", "name ...", `""`, "server", "", ""),
+	doc(httpCoreModule, "root", "path", "html", "http, server, location, if in location", "", ""),
+	doc(httpCoreModule, "alias", "path", "", "location", "", ""),
+	doc(httpCoreModule, "try_files", "file ... uri | file ... =code", "", "server, location", "", ""),
+	doc(httpCoreModule, "internal", "", "", "location", "", ""),
+	doc(httpCoreModule, "error_page", "code ... [=[response]] uri", "", "http, server, location, if in location", "", ""),
+	doc(httpCoreModule, "default_type", "mime-type", "text/plain", "http, server, location", "", ""),
+	doc(httpCoreModule, "types", "{ ... }", "", "http, server, location", "", ""),
+	doc(httpCoreModule, "client_max_body_size", "size", "1m", "http, server, location", "", ""),
+	doc(httpCoreModule, "keepalive_timeout", "timeout [header_timeout]", "75s", "http, server, location", "", ""),
+	doc(httpCoreModule, "keepalive_requests", "number", "1000", "http, server, location", "0.8.0", ""),
+	doc(httpCoreModule, "limit_rate", "rate", "0", "http, server, location, if in location", "", ""),
+	doc(httpCoreModule, "limit_except", "method ... { ... }", "", "location", "", ""),
+	doc(httpCoreModule, "resolver", "address ... [valid=time] [ipv6=on|off]", "", "http, server, location", "", ""),
+	doc(httpCoreModule, "resolver_timeout", "time", "30s", "http, server, location", "", ""),
+	doc(httpCoreModule, "satisfy", "all | any", "all", "http, server, location", "", ""),
+	doc(httpCoreModule, "sendfile", "on | off", "off", "http, server, location, if in location", "", ""),
+	doc(httpCoreModule, "server_tokens", "on | off | build | string", "on", "http, server, location", "", ""),
+	doc(httpCoreModule, "tcp_nodelay", "on | off", "on", "http, server, location", "", ""),
+	doc(httpCoreModule, "tcp_nopush", "on | off", "off", "http, server, location", "", ""),
+	doc(httpCoreModule, "absolute_redirect", "on | off", "on", "http, server, location", "1.11.8", ""),
+	doc(httpCoreModule, "aio", "on | off | threads[=pool]", "off", "http, server, location", "", ""),
+	doc(httpCoreModule, "chunked_transfer_encoding", "on | off", "on", "http, server, location", "", ""),
+	doc(httpCoreModule, "client_body_buffer_size", "size", "8k|16k", "http, server, location", "", ""),
+	doc(httpCoreModule, "client_body_temp_path", "path [level1 [level2 [level3]]]", "client_body_temp", "http, server, location", "", ""),
+	doc(httpCoreModule, "client_body_timeout", "time", "60s", "http, server, location", "", ""),
+	doc(httpCoreModule, "client_header_buffer_size", "size", "1k", "http, server", "", ""),
+	doc(httpCoreModule, "client_header_timeout", "time", "60s", "http, server", "", ""),
+	doc(httpCoreModule, "directio", "size | off", "off", "http, server, location", "", ""),
+	doc(httpCoreModule, "etag", "on | off", "on", "http, server, location", "1.3.3", ""),
+	doc(httpCoreModule, "if_modified_since", "off | exact | before", "exact", "http, server, location", "", ""),
+	doc(httpCoreModule, "ignore_invalid_headers", "on | off", "on", "http, server", "", ""),
+	doc(httpCoreModule, "keepalive_time", "time", "1h", "http, server, location", "1.19.10", ""),
+	doc(httpCoreModule, "large_client_header_buffers", "number size", "4 8k", "http, server", "", ""),
+	doc(httpCoreModule, "limit_rate_after", "size", "0", "http, server, location, if in location", "", ""),
+	doc(httpCoreModule, "lingering_close", "off | on | always", "on", "http, server, location", "", ""),
+	doc(httpCoreModule, "log_not_found", "on | off", "on", "http, server, location", "", ""),
+	doc(httpCoreModule, "merge_slashes", "on | off", "on", "http, server", "", ""),
+	doc(httpCoreModule, "open_file_cache", "off | max=N [inactive=time]", "off", "http, server, location", "", ""),
+	doc(httpCoreModule, "open_file_cache_errors", "on | off", "off", "http, server, location", "", ""),
+	doc(httpCoreModule, "open_file_cache_min_uses", "number", "1", "http, server, location", "", ""),
+	doc(httpCoreModule, "open_file_cache_valid", "time", "60s", "http, server, location", "", ""),
+	doc(httpCoreModule, "port_in_redirect", "on | off", "on", "http, server, location", "", ""),
+	doc(httpCoreModule, "recursive_error_pages", "on | off", "off", "http, server, location", "", ""),
+	doc(httpCoreModule, "reset_timedout_connection", "on | off", "off", "http, server, location", "", ""),
+	doc(httpCoreModule, "send_timeout", "time", "60s", "http, server, location", "", ""),
+	doc(httpCoreModule, "server_name_in_redirect", "on | off", "off", "http, server, location", "", ""),
+	doc(httpCoreModule, "server_names_hash_bucket_size", "size", "32|64|128", "http", "", ""),
+	doc(httpCoreModule, "server_names_hash_max_size", "size", "512", "http", "", ""),
+	doc(httpCoreModule, "types_hash_bucket_size", "size", "64", "http", "", ""),
+	doc(httpCoreModule, "types_hash_max_size", "size", "1024", "http", "", ""),
+	doc(httpCoreModule, "underscores_in_headers", "on | off", "off", "http, server", "", ""),
+	doc(httpCoreModule, "variables_hash_max_size", "size", "1024", "http", "", ""),
+
+	doc("ngx_http_index_module", "index", "file ...", "index.html", "http, server, location", "", ""),
+	doc("ngx_http_log_module", "access_log", "path [format [buffer=size] [gzip[=level]] [flush=time] [if=condition]] | off", "logs/access.log combined", "http, server, location, if in location, limit_except", "", ""),
+	doc("ngx_http_log_module", "log_format", "name [escape=default|json|none] string ...", `combined "..."`, "http", "", ""),
+	doc("ngx_http_headers_module", "add_header", "name value [always]", "", "http, server, location, if in location", "", ""),
+	doc("ngx_http_headers_module", "expires", "[modified] time | epoch | max | off", "off", "http, server, location, if in location", "", ""),
+	doc("ngx_http_map_module", "map", "string $variable { ... }", "", "http", "", ""),
+	doc("ngx_http_geo_module", "geo", "[$address] $variable { ... }", "", "http", "", ""),
+	doc("ngx_http_access_module", "allow", "address | CIDR | unix: | all", "", "http, server, location, limit_except", "", ""),
+	doc("ngx_http_access_module", "deny", "address | CIDR | unix: | all", "", "http, server, location, limit_except", "", ""),
+	doc("ngx_http_auth_basic_module", "auth_basic", "string | off", "off", "http, server, location, limit_except", "", ""),
+	doc("ngx_http_auth_request_module", "auth_request", "uri | off", "off", "http, server, location", "1.5.4", ""),
+	doc("ngx_http_gzip_module", "gzip", "on | off", "off", "http, server, location, if in location", "", ""),
+	doc("ngx_http_limit_req_module", "limit_req_zone", "key zone=name:size rate=rate [sync]", "", "http", "", ""),
+	doc("ngx_http_limit_req_module", "limit_req", "zone=name [burst=number] [nodelay | delay=number]", "", "http, server, location", "", ""),
+	doc("ngx_http_limit_conn_module", "limit_conn", "zone number", "", "http, server, location", "", ""),
+	doc("ngx_http_mirror_module", "mirror", "uri | off", "off", "http, server, location", "1.13.4", ""),
+	doc("ngx_http_sub_module", "sub_filter", "string replacement", "", "http, server, location", "", ""),
+	doc("ngx_http_sub_module", "sub_filter_once", "on | off", "on", "http, server, location", "", ""),
+	doc("ngx_http_sub_module", "sub_filter_types", "mime-type ...", "text/html", "http, server, location", "", ""),
+	doc("ngx_http_realip_module", "set_real_ip_from", "address | CIDR | unix:", "", "http, server, location", "", ""),
+	doc("ngx_http_realip_module", "real_ip_header", "field | X-Real-IP | X-Forwarded-For | proxy_protocol", "X-Real-IP", "http, server, location", "", ""),
+	doc("ngx_http_realip_module", "real_ip_recursive", "on | off", "off", "http, server, location", "1.3.0", ""),
+	doc("ngx_http_map_module", "map_hash_bucket_size", "size", "32|64|128", "http", "", ""),
+	doc("ngx_http_map_module", "map_hash_max_size", "size", "2048", "http", "", ""),
+	doc("ngx_http_split_clients_module", "split_clients", "string $variable { ... }", "", "http", "", ""),
+	doc("ngx_http_headers_module", "add_trailer", "name value [always]", "", "http, server, location, if in location", "1.13.2", ""),
+	doc("ngx_http_auth_basic_module", "auth_basic_user_file", "file", "", "http, server, location, limit_except", "", ""),
+	doc("ngx_http_auth_request_module", "auth_request_set", "$variable value", "", "http, server, location", "1.5.4", ""),
+	doc("ngx_http_autoindex_module", "autoindex", "on | off", "off", "http, server, location", "", ""),
+	doc("ngx_http_autoindex_module", "autoindex_exact_size", "on | off", "on", "http, server, location", "", ""),
+	doc("ngx_http_autoindex_module", "autoindex_format", "html | xml | json | jsonp", "html", "http, server, location", "1.7.9", ""),
+	doc("ngx_http_autoindex_module", "autoindex_localtime", "on | off", "off", "http, server, location", "", ""),
+	doc("ngx_http_charset_module", "charset", "charset | off", "off", "http, server, location, if in location", "", ""),
+	doc("ngx_http_charset_module", "source_charset", "charset", "", "http, server, location, if in location", "", ""),
+	doc("ngx_http_gzip_module", "gzip_buffers", "number size", "32 4k|16 8k", "http, server, location", "", ""),
+	doc("ngx_http_gzip_module", "gzip_comp_level", "level", "1", "http, server, location", "", ""),
+	doc("ngx_http_gzip_module", "gzip_disable", "regex ...", "", "http, server, location", "", ""),
+	doc("ngx_http_gzip_module", "gzip_http_version", "1.0 | 1.1", "1.1", "http, server, location", "", ""),
+	doc("ngx_http_gzip_module", "gzip_min_length", "length", "20", "http, server, location", "", ""),
+	doc("ngx_http_gzip_module", "gzip_proxied", "off | expired | no-cache | no-store | private | no_last_modified | no_etag | auth | any ...", "off", "http, server, location", "", ""),
+	doc("ngx_http_gzip_module", "gzip_types", "mime-type ...", "text/html", "http, server, location", "", ""),
+	doc("ngx_http_gzip_module", "gzip_vary", "on | off", "off", "http, server, location", "", ""),
+	doc("ngx_http_gzip_static_module", "gzip_static", "on | off | always", "off", "http, server, location", "", ""),
+	doc("ngx_http_limit_req_module", "limit_req_log_level", "info | notice | warn | error", "error", "http, server, location", "", ""),
+	doc("ngx_http_limit_req_module", "limit_req_status", "code", "503", "http, server, location", "1.3.15", ""),
+	doc("ngx_http_limit_conn_module", "limit_conn_zone", "key zone=name:size", "", "http", "1.1.8", ""),
+	doc("ngx_http_limit_conn_module", "limit_conn_log_level", "info | notice | warn | error", "error", "http, server, location", "", ""),
+	doc("ngx_http_limit_conn_module", "limit_conn_status", "code", "503", "http, server, location", "1.3.15", ""),
+	doc("ngx_http_mirror_module", "mirror_request_body", "on | off", "on", "http, server, location", "1.13.4", ""),
+	doc("ngx_http_ssi_module", "ssi", "on | off", "off", "http, server, location, if in location", "", ""),
+	doc("ngx_http_stub_status_module", "stub_status", "", "", "server, location", "", ""),
+	doc("ngx_http_sub_module", "sub_filter_last_modified", "on | off", "off", "http, server, location", "1.5.1", ""),
+
+	doc(rewriteModule, "break", "", "", "server, location, if", "", ""),
+	doc(rewriteModule, "if", "(condition) { ... }", "", "server, location", "", ""),
+	doc(rewriteModule, "return", "code [text] | code URL | URL", "", "server, location, if", "", ""),
+	doc(rewriteModule, "rewrite", "regex replacement [flag]", "", "server, location, if", "", ""),
+	doc(rewriteModule, "set", "$variable value", "", "server, location, if", "", ""),
+	doc(rewriteModule, "rewrite_log", "on | off", "off", "http, server, location, if", "", ""),
+	doc(rewriteModule, "uninitialized_variable_warn", "on | off", "on", "http, server, location, if", "", ""),
+
+	doc(proxyModule, "proxy_pass", "URL", "", "location, if in location, limit_except", "", ""),
+	doc(proxyModule, "proxy_set_header", "field value", "Host $proxy_host", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_http_version", "1.0 | 1.1", "1.0", "http, server, location", "1.1.4", ""),
+	doc(proxyModule, "proxy_connect_timeout", "time", "60s", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_read_timeout", "time", "60s", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_send_timeout", "time", "60s", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_buffering", "on | off", "on", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_intercept_errors", "on | off", "off", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_next_upstream", "error | timeout | invalid_header | http_500 | http_502 | http_503 | http_504 | http_403 | http_404 | http_429 | non_idempotent | off ...", "error timeout", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_redirect", "default | off | redirect replacement", "default", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_bind", "address [transparent] | off", "", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_buffer_size", "size", "4k|8k", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_buffers", "number size", "8 4k|8k", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_busy_buffers_size", "size", "8k|16k", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_cache", "zone | off", "off", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_cache_bypass", "string ...", "", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_cache_key", "string", "$scheme$proxy_host$request_uri", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_cache_lock", "on | off", "off", "http, server, location", "1.1.12", ""),
+	doc(proxyModule, "proxy_cache_methods", "GET | HEAD | POST ...", "GET HEAD", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_cache_path", "path [levels=levels] keys_zone=name:size [parameters]", "", "http", "", ""),
+	doc(proxyModule, "proxy_cache_use_stale", "error | timeout | invalid_header | updating | http_500 | http_502 | http_503 | http_504 | http_403 | http_404 | http_429 | off ...", "off", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_cache_valid", "[code ...] time", "", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_cookie_domain", "off | domain replacement", "off", "http, server, location", "1.1.15", ""),
+	doc(proxyModule, "proxy_cookie_path", "off | path replacement", "off", "http, server, location", "1.1.15", ""),
+	doc(proxyModule, "proxy_headers_hash_bucket_size", "size", "64", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_headers_hash_max_size", "size", "512", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_hide_header", "field", "", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_ignore_client_abort", "on | off", "off", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_ignore_headers", "field ...", "", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_max_temp_file_size", "size", "1024m", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_method", "method", "", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_next_upstream_timeout", "time", "0", "http, server, location", "1.7.5", ""),
+	doc(proxyModule, "proxy_next_upstream_tries", "number", "0", "http, server, location", "1.7.5", ""),
+	doc(proxyModule, "proxy_no_cache", "string ...", "", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_pass_header", "field", "", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_pass_request_body", "on | off", "on", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_pass_request_headers", "on | off", "on", "http, server, location", "", ""),
+	doc(proxyModule, "proxy_request_buffering", "on | off", "on", "http, server, location", "1.7.11", ""),
+	doc(proxyModule, "proxy_socket_keepalive", "on | off", "off", "http, server, location", "1.15.6", ""),
+	doc(proxyModule, "proxy_ssl_certificate", "file", "", "http, server, location", "1.7.8", ""),
+	doc(proxyModule, "proxy_ssl_certificate_key", "file", "", "http, server, location", "1.7.8", ""),
+	doc(proxyModule, "proxy_ssl_name", "name", "$proxy_host", "http, server, location", "1.7.0", ""),
+	doc(proxyModule, "proxy_ssl_protocols", "[SSLv2] [SSLv3] [TLSv1] [TLSv1.1] [TLSv1.2] [TLSv1.3]", "TLSv1.2 TLSv1.3", "http, server, location", "1.5.6", ""),
+	doc(proxyModule, "proxy_ssl_server_name", "on | off", "off", "http, server, location", "1.7.0", ""),
+	doc(proxyModule, "proxy_ssl_trusted_certificate", "file", "", "http, server, location", "1.7.0", ""),
+	doc(proxyModule, "proxy_ssl_verify", "on | off", "off", "http, server, location", "1.7.0", ""),
+	doc(proxyModule, "proxy_temp_path", "path [level1 [level2 [level3]]]", "proxy_temp", "http, server, location", "", ""),
+	doc("ngx_http_fastcgi_module", "fastcgi_pass", "address", "", "location, if in location", "", ""),
+	doc("ngx_http_fastcgi_module", "fastcgi_param", "parameter value [if_not_empty]", "", "http, server, location", "", ""),
+	doc("ngx_http_fastcgi_module", "fastcgi_buffer_size", "size", "4k|8k", "http, server, location", "", ""),
+	doc("ngx_http_fastcgi_module", "fastcgi_buffers", "number size", "8 4k|8k", "http, server, location", "", ""),
+	doc("ngx_http_fastcgi_module", "fastcgi_cache", "zone | off", "off", "http, server, location", "", ""),
+	doc("ngx_http_fastcgi_module", "fastcgi_cache_key", "string", "", "http, server, location", "", ""),
+	doc("ngx_http_fastcgi_module", "fastcgi_cache_path", "path [levels=levels] keys_zone=name:size [parameters]", "", "http", "", ""),
+	doc("ngx_http_fastcgi_module", "fastcgi_cache_valid", "[code ...] time", "", "http, server, location", "", ""),
+	doc("ngx_http_fastcgi_module", "fastcgi_connect_timeout", "time", "60s", "http, server, location", "", ""),
+	doc("ngx_http_fastcgi_module", "fastcgi_index", "name", "", "http, server, location", "", ""),
+	doc("ngx_http_fastcgi_module", "fastcgi_intercept_errors", "on | off", "off", "http, server, location", "", ""),
+	doc("ngx_http_fastcgi_module", "fastcgi_keep_conn", "on | off", "off", "http, server, location", "1.1.4", ""),
+	doc("ngx_http_fastcgi_module", "fastcgi_next_upstream", "error | timeout | invalid_header | http_500 | http_503 | http_403 | http_404 | http_429 | non_idempotent | off ...", "error timeout", "http, server, location", "", ""),
+	doc("ngx_http_fastcgi_module", "fastcgi_read_timeout", "time", "60s", "http, server, location", "", ""),
+	doc("ngx_http_fastcgi_module", "fastcgi_send_timeout", "time", "60s", "http, server, location", "", ""),
+	doc("ngx_http_fastcgi_module", "fastcgi_split_path_info", "regex", "", "location", "", ""),
+	doc("ngx_http_uwsgi_module", "uwsgi_param", "parameter value [if_not_empty]", "", "http, server, location", "", ""),
+	doc("ngx_http_scgi_module", "scgi_param", "parameter value [if_not_empty]", "", "http, server, location", "", ""),
+	doc("ngx_http_grpc_module", "grpc_set_header", "field value", "Content-Length $content_length", "http, server, location", "1.13.10", ""),
+	doc("ngx_http_grpc_module", "grpc_read_timeout", "time", "60s", "http, server, location", "1.13.10", ""),
+	doc("ngx_http_grpc_module", "grpc_next_upstream", "error | timeout | invalid_header | http_500 | http_502 | http_503 | http_504 | http_403 | http_404 | http_429 | non_idempotent | off ...", "error timeout", "http, server, location", "1.13.10", ""),
+	doc("ngx_http_uwsgi_module", "uwsgi_pass", "[protocol://]address", "", "location, if in location", "", ""),
+	doc("ngx_http_scgi_module", "scgi_pass", "address", "", "location, if in location", "", ""),
+	doc("ngx_http_grpc_module", "grpc_pass", "address", "", "location, if in location", "1.13.10", ""),
+
+	doc(upstreamModule, "upstream", "name { ... }", "", "http", "", ""),
+	doc(upstreamModule, "hash", "key [consistent]", "", "upstream", "1.7.2", ""),
+	doc(upstreamModule, "ip_hash", "", "", "upstream", "", ""),
+	doc(upstreamModule, "least_conn", "", "", "upstream", "1.3.1", ""),
+	doc(upstreamModule, "keepalive", "connections", "", "upstream", "1.1.4", ""),
+	doc(upstreamModule, "zone", "name [size]", "", "upstream", "1.9.0", ""),
+	doc(upstreamModule, "server", "address [parameters]", "", "upstream", "", ""),
+	doc(upstreamModule, "random", "[two [method]]", "", "upstream", "1.15.1", ""),
+	doc(upstreamModule, "keepalive_requests", "number", "1000", "upstream", "1.15.3", ""),
+	doc(upstreamModule, "keepalive_time", "time", "1h", "upstream", "1.19.10", ""),
+	doc(upstreamModule, "keepalive_timeout", "timeout", "60s", "upstream", "1.15.3", ""),
+
+	doc(sslModule, "ssl_certificate", "file", "", "http, server", "", ""),
+	doc(sslModule, "ssl_certificate_key", "file", "", "http, server", "", ""),
+	doc(sslModule, "ssl_ciphers", "ciphers", "HIGH:!aNULL:!MD5", "http, server", "", ""),
+	doc(sslModule, "ssl_protocols", "[SSLv2] [SSLv3] [TLSv1] [TLSv1.1] [TLSv1.2] [TLSv1.3]", "TLSv1.2 TLSv1.3", "http, server", "", ""),
+	doc(sslModule, "ssl_early_data", "on | off", "off", "http, server", "1.15.3", ""),
+	doc(sslModule, "ssl", "on | off", "off", "http, server", "", "1.25.1"),
+	doc(sslModule, "ssl_buffer_size", "size", "16k", "http, server", "1.5.9", ""),
+	doc(sslModule, "ssl_client_certificate", "file", "", "http, server", "", ""),
+	doc(sslModule, "ssl_conf_command", "name value", "", "http, server", "1.19.4", ""),
+	doc(sslModule, "ssl_dhparam", "file", "", "http, server", "", ""),
+	doc(sslModule, "ssl_ecdh_curve", "curve", "auto", "http, server", "1.1.0", ""),
+	doc(sslModule, "ssl_password_file", "file", "", "http, server", "1.7.3", ""),
+	doc(sslModule, "ssl_prefer_server_ciphers", "on | off", "off", "http, server", "", ""),
+	doc(sslModule, "ssl_reject_handshake", "on | off", "off", "http, server", "1.19.4", ""),
+	doc(sslModule, "ssl_session_cache", "off | none | [builtin[:size]] [shared:name:size]", "none", "http, server", "", ""),
+	doc(sslModule, "ssl_session_ticket_key", "file", "", "http, server", "1.5.7", ""),
+	doc(sslModule, "ssl_session_tickets", "on | off", "on", "http, server", "1.5.9", ""),
+	doc(sslModule, "ssl_session_timeout", "time", "5m", "http, server", "", ""),
+	doc(sslModule, "ssl_stapling", "on | off", "off", "http, server", "1.3.7", ""),
+	doc(sslModule, "ssl_stapling_responder", "url", "", "http, server", "1.3.7", ""),
+	doc(sslModule, "ssl_stapling_verify", "on | off", "off", "http, server", "1.3.7", ""),
+	doc(sslModule, "ssl_trusted_certificate", "file", "", "http, server", "1.3.7", ""),
+	doc(sslModule, "ssl_verify_client", "on | off | optional | optional_no_ca", "off", "http, server", "", ""),
+	doc(sslModule, "ssl_verify_depth", "number", "1", "http, server", "", ""),
+	doc("ngx_http_v2_module", "http2", "on | off", "off", "http, server", "1.25.1", ""),
+	doc("ngx_http_v2_module", "http2_push", "uri | off", "off", "http, server, location", "1.13.9", "1.25.1"),
+	doc("ngx_http_v2_module", "http2_push_preload", "on | off", "off", "http, server, location", "1.13.9", "1.25.1"),
+	doc("ngx_http_v3_module", "http3", "on | off", "on", "http, server", "1.25.0", ""),
+	doc("ngx_http_v3_module", "http3_hq", "on | off", "off", "http, server", "1.25.0", ""),
+	doc("ngx_http_v3_module", "quic_retry", "on | off", "off", "http, server", "1.25.0", ""),
+
+	doc(otelModule, "otel_exporter", "{ ... }", "", "http", "1.23.4", ""),
+	doc(otelModule, "endpoint", "[(http|https)://]host:port", "", "otel_exporter", "1.23.4", ""),
+	doc(otelModule, "interval", "time", "5s", "otel_exporter", "1.23.4", ""),
+	doc(otelModule, "batch_size", "number", "512", "otel_exporter", "1.23.4", ""),
+	doc(otelModule, "batch_count", "number", "4", "otel_exporter", "1.23.4", ""),
+	doc(otelModule, "header", "name value", "", "otel_exporter", "1.23.4", ""),
+	doc(otelModule, "otel_service_name", "name", "unknown_service:nginx", "http", "1.23.4", ""),
+	doc(otelModule, "otel_resource_attr", "name value", "", "http", "1.23.4", ""),
+	doc(otelModule, "otel_trace", "on | off | $variable", "off", "http, server, location", "1.23.4", ""),
+	doc(otelModule, "otel_trace_context", "extract | inject | propagate | ignore", "ignore", "http, server, location", "1.23.4", ""),
+	doc(otelModule, "otel_span_name", "name", "", "http, server, location", "1.23.4", ""),
+	doc(otelModule, "otel_span_attr", "name value", "", "http, server, location", "1.23.4", ""),
+
+	doc("ngx_stream_core_module", "stream", "{ ... }", "", "main", "1.9.0", ""),
+	doc("ngx_stream_core_module", "server", "{ ... }", "", "stream", "1.9.0", ""),
+	doc("ngx_stream_core_module", "listen", "address:port [ssl] [udp] [proxy_protocol] [parameters]", "", "server", "1.9.0", ""),
+	doc("ngx_stream_proxy_module", "proxy_pass", "address", "", "server", "1.9.0", ""),
+	doc("ngx_stream_proxy_module", "proxy_connect_timeout", "time", "60s", "stream, server", "1.9.0", ""),
+	doc("ngx_stream_proxy_module", "proxy_protocol", "on | off", "off", "stream, server", "1.9.2", ""),
+	doc("ngx_stream_proxy_module", "proxy_timeout", "timeout", "10m", "stream, server", "1.9.0", ""),
+	doc("ngx_stream_upstream_module", "upstream", "name { ... }", "", "stream", "1.9.0", ""),
+	doc("ngx_stream_map_module", "map", "string $variable { ... }", "", "stream", "1.11.2", ""),
+	doc("ngx_stream_log_module", "access_log", "path format [buffer=size] [gzip[=level]] [flush=time] [if=condition] | off", "off", "stream, server", "1.11.4", ""),
+	doc("ngx_stream_log_module", "log_format", "name [escape=default|json|none] string ...", "", "stream", "1.11.4", ""),
+	doc("ngx_stream_ssl_preread_module", "ssl_preread", "on | off", "off", "stream, server", "1.11.5", ""),
+}
+
+// Lookup returns the documentation of a directive. Names documented by
+// several modules, such as server, resolve to the HTTP one, use
+// LookupContext to pick by context.
+func Lookup(name string) (DirectiveDoc, bool) {
+	for _, d := range directiveDocs {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return DirectiveDoc{}, false
+}
+
+// LookupContext returns the documentation of the directive allowed in the
+// given context, for example server in "upstream".
+func LookupContext(name, context string) (DirectiveDoc, bool) {
+	for _, d := range directiveDocs {
+		if d.Name == name && d.AllowedIn(context) {
+			return d, true
+		}
+	}
+	return DirectiveDoc{}, false
+}
+
+// DirectiveDocs returns every documented directive.
+func DirectiveDocs() []DirectiveDoc {
+	return append([]DirectiveDoc(nil), directiveDocs...)
+}
@@ -0,0 +1,68 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	d, ok := Lookup("proxy_read_timeout")
+	if !ok || d.Module != "ngx_http_proxy_module" || d.Default != "60s" || !d.AllowedIn("location") || d.AllowedIn("upstream") {
+		t.Fatalf("unexpected doc %+v", d)
+	}
+	if url := d.URL(); url != "https://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_read_timeout" {
+		t.Fatalf("unexpected url %s", url)
+	}
+	if d, _ := Lookup("worker_processes"); d.URL() != "https://nginx.org/en/docs/ngx_core_module.html#worker_processes" {
+		t.Fatalf("unexpected url %s", d.URL())
+	}
+	if d, _ := Lookup("include"); !d.AllowedIn("upstream") {
+		t.Fatalf("expected include to be allowed everywhere")
+	}
+	if _, ok := Lookup("no_such_directive"); ok {
+		t.Fatal("expected unknown directive")
+	}
+
+	if d, _ := Lookup("server"); d.Module != "ngx_http_core_module" {
+		t.Fatalf("unexpected doc %+v", d)
+	}
+	if d, ok := LookupContext("server", "upstream"); !ok || d.Module != "ngx_http_upstream_module" {
+		t.Fatalf("unexpected doc %+v", d)
+	}
+
+	for name, expected := range map[string][3]int{
+		"internal":     {0, 0, 0},
+		"location":     {1, 2, 1},
+		"error_page":   {2, -1, 0},
+		"access_log":   {1, 6, 0},
+		"return":       {1, 2, 0},
+		"geo":          {1, 2, 1},
+		"listen":       {1, -1, 0},
+		"uwsgi_pass":   {1, 1, 0},
+		"thread_pool":  {2, 3, 0},
+		"add_header":   {2, 3, 0},
+		"expires":      {1, 2, 0},
+		"limit_except": {1, -1, 1},
+	} {
+		d, _ := Lookup(name)
+		block := 0
+		if d.Block {
+			block = 1
+		}
+		if got := [3]int{d.MinArgs, d.MaxArgs, block}; got != expected {
+			t.Errorf("%s: expected arguments %v but got %v", name, expected, got)
+		}
+	}
+
+	push, _ := Lookup("http2_push")
+	for version, expected := range map[string]bool{"1.13.8": false, "1.13.9": true, "1.25.0": true, "1.25.1": false} {
+		if push.Available(MustParseVersion(version)) != expected {
+			t.Fatalf("expected http2_push available %v in %s", expected, version)
+		}
+	}
+	if push.Available(Version{}) {
+		t.Fatal("expected http2_push unavailable in the latest release")
+	}
+	if d, _ := Lookup("http2"); !d.Available(Version{}) {
+		t.Fatal("expected http2 available in the latest release")
+	}
+}
@@ -0,0 +1,536 @@
+package nginxparser
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+)
+
+type EmitOptions struct {
+	Indent                 string
+	BraceOnNewLine         bool
+	BlankLineBetweenBlocks bool
+	Provenance             bool
+	// BlankLines keeps the blank lines found between directives when
+	// parsing, see Directive.BlankLinesBefore.
+	BlankLines bool
+	// Flatten writes the directives pulled in by include directives in
+	// place of the include, like nginx -T, producing a single
+	// self-contained configuration.
+	Flatten bool
+	// IncludeMarkers surrounds flattened includes with "# begin include"
+	// and "# end include" comments.
+	IncludeMarkers bool
+	// Align pads directive names so the arguments of the simple
+	// directives in a block line up in one column.
+	Align bool
+	// Sort orders the directives of every block: those named in Order
+	// first, in that order, then the remaining simple directives and then
+	// blocks, alphabetically. Directives of the same name and the
+	// rewrite module directives keep their relative order, since nginx
+	// evaluates them in sequence, and comments move with the directive
+	// that follows them.
+	Sort  bool
+	Order []string
+	// LineEnding is "\n", the default, or "\r\n". Text kept by a lossless
+	// parse keeps its original line endings.
+	LineEnding string
+	// RawEscapes writes arguments parsed with ParseOptions.RawEscapes,
+	// their backslash sequences are written as they are.
+	RawEscapes bool
+	// SourceMap writes the origin it returns for a directive above it as
+	// a "# source: ..." comment, which parsing reads back into
+	// Annotations.Source, so errors in generated files can be traced to
+	// what they were generated from. Directives it returns nil for get
+	// none, source comments already in the tree are replaced.
+	SourceMap func(d *Directive) *Provenance
+	// Minify writes the whole configuration on one line without comments
+	// or optional whitespace, other layout options are ignored.
+	Minify bool
+	// Watermark writes a header identifying the tool that generated the
+	// file and the hash of its content above the configuration, see
+	// ReadWatermark.
+	Watermark *Watermark
+}
+
+var defaultOrder = []string{"listen", "server_name"}
+
+var rewriteDirectives = map[string]bool{
+	"break":   true,
+	"if":      true,
+	"return":  true,
+	"rewrite": true,
+	"set":     true,
+}
+
+type Emitter struct {
+	options *EmitOptions
+	nl      string
+}
+
+func NewEmitter(options *EmitOptions) *Emitter {
+	if options == nil {
+		options = &EmitOptions{}
+	}
+	if options.Indent == "" {
+		options.Indent = "    "
+	}
+	if options.Order == nil {
+		options.Order = defaultOrder
+	}
+	if options.LineEnding == "" {
+		options.LineEnding = "\n"
+	}
+	return &Emitter{options: options, nl: options.LineEnding}
+}
+
+// Dump writes directives back out as nginx configuration. Include
+// directives are written as they are, the directives they pulled in stay
+// in their own files.
+func Dump(directives []*Directive, w io.Writer) error {
+	return NewEmitter(nil).Emit(w, directives)
+}
+
+// String renders the directive, and its block, as nginx configuration.
+func (d *Directive) String() string {
+	var buf strings.Builder
+	if err := Dump([]*Directive{d}, &buf); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// Emit streams directives to w through a small buffer, the output is
+// never held in memory as a whole, except with EmitOptions.Watermark: the
+// watermark at the top hashes the content below it, which is emitted to
+// memory first.
+func (e *Emitter) Emit(w io.Writer, directives []*Directive) error {
+	if e.options.Watermark != nil {
+		return e.emitWatermarked(w, directives)
+	}
+	buf := bufio.NewWriter(w)
+	if e.options.Minify {
+		e.emitMinified(buf, directives)
+		return buf.Flush()
+	}
+	if hasTrivia(directives) {
+		e.emitLossless(buf, directives, 0)
+		if last := directives[len(directives)-1]; last.Trivia != nil && last.Trivia.End {
+			buf.WriteString(last.Trivia.Trailing)
+		} else {
+			buf.WriteString(e.nl)
+		}
+	} else {
+		e.emitBlock(buf, directives, 0)
+	}
+	return buf.Flush()
+}
+
+func (e *Emitter) emitBlock(buf *bufio.Writer, directives []*Directive, depth int) {
+	directives = e.sorted(directives)
+	width := 0
+	if e.options.Align {
+		for _, d := range directives {
+			if d.Directive != "#" && !isBlock(d) && !isLuaBlock(d) && !e.flatten(d) && len(d.Args) > 0 {
+				if n := len(e.quoteWords([]string{d.Directive}, []Quote{QuoteNone})[0]); n > width {
+					width = n
+				}
+			}
+		}
+	}
+	var previous *Directive
+	for i := 0; i < len(directives); i++ {
+		d := directives[i]
+		if e.staleSource(d) {
+			continue
+		}
+		blank := 0
+		if previous != nil && e.options.BlankLines {
+			blank = d.BlankLinesBefore
+		}
+		if previous != nil && depth == 0 && e.options.BlankLineBetweenBlocks && (isBlock(d) || isBlock(previous)) && blank == 0 {
+			blank = 1
+		}
+		buf.WriteString(strings.Repeat(e.nl, blank))
+		trailing := ""
+		if d.Directive != "#" && !e.flatten(d) && i+1 < len(directives) && isInlineComment(directives[i+1]) {
+			trailing = " #" + directives[i+1].Comment
+			i++
+		}
+		e.emitDirective(buf, d, depth, width, trailing)
+		previous = d
+	}
+}
+
+func isInlineComment(d *Directive) bool {
+	return d.Directive == "#" && d.Inline
+}
+
+func (e *Emitter) sorted(directives []*Directive) []*Directive {
+	if !e.options.Sort {
+		return directives
+	}
+	type unit struct {
+		rank       int
+		block      bool
+		name       string
+		directives []*Directive
+	}
+	rank := func(name string) int {
+		for i, o := range e.options.Order {
+			if o == name {
+				return i
+			}
+		}
+		return len(e.options.Order)
+	}
+
+	units := make([]*unit, 0, len(directives))
+	pending := make([]*Directive, 0)
+	for _, d := range directives {
+		if isInlineComment(d) && len(pending) == 0 {
+			if len(units) == 0 {
+				units = append(units, &unit{rank: -1})
+			}
+			last := units[len(units)-1]
+			last.directives = append(last.directives, d)
+			continue
+		}
+		pending = append(pending, d)
+		if d.Directive == "#" {
+			continue
+		}
+		u := &unit{rank: rank(d.Directive), block: isBlock(d), name: d.Directive, directives: pending}
+		if rewriteDirectives[d.Directive] && u.rank == len(e.options.Order) {
+			u.block, u.name = false, "rewrite"
+		}
+		units = append(units, u)
+		pending = make([]*Directive, 0)
+	}
+	sort.SliceStable(units, func(i, j int) bool {
+		a, b := units[i], units[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.block != b.block {
+			return b.block
+		}
+		return a.name < b.name
+	})
+
+	result := make([]*Directive, 0, len(directives))
+	for _, u := range units {
+		result = append(result, u.directives...)
+	}
+	return append(result, pending...)
+}
+
+func isBlock(d *Directive) bool {
+	return d.Block != nil && d.Directive != "include"
+}
+
+// isLuaBlock reports whether d is an OpenResty *_by_lua_block directive,
+// whose Lua code the parser keeps verbatim as the last argument.
+func isLuaBlock(d *Directive) bool {
+	return strings.HasSuffix(d.Directive, "_by_lua_block") && d.Block == nil && len(d.Args) > 0
+}
+
+// luaHeader writes the Lua code back between braces as it was read, the
+// parser trims the whitespace before the closing brace.
+func (e *Emitter) luaHeader(d *Directive, indent string) string {
+	last := len(d.Args) - 1
+	quotes := make([]Quote, last+1)
+	for i := 0; i < last; i++ {
+		quotes[i+1] = d.QuoteAt(i)
+	}
+	words := e.quoteWords(append([]string{d.Directive}, d.Args[:last]...), quotes)
+	return strings.Join(words, " ") + " {" + d.Args[last] + e.nl + indent + "}"
+}
+
+func hasTrivia(directives []*Directive) bool {
+	for _, d := range directives {
+		if d.Trivia != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// emitDirective writes d with the trailing comment at the end of its
+// last line. A block's inline first child goes after the opening brace.
+func (e *Emitter) emitDirective(buf *bufio.Writer, d *Directive, depth, width int, trailing string) {
+	indent := strings.Repeat(e.options.Indent, depth)
+	if e.options.Provenance && d.Provenance != nil {
+		buf.WriteString(indent + "# " + d.Provenance.String() + e.nl)
+	}
+	if source := e.source(d); source != nil {
+		buf.WriteString(indent + "# " + source.comment() + e.nl)
+	}
+	if e.flatten(d) {
+		e.emitFlattened(buf, d, depth)
+		return
+	}
+	header := e.header(d, indent, width)
+	if !isBlock(d) {
+		buf.WriteString(indent + header + trailing + e.nl)
+		return
+	}
+	children := d.Block
+	if len(children) > 0 && isInlineComment(children[0]) {
+		header += " #" + children[0].Comment
+		children = children[1:]
+	}
+	buf.WriteString(indent + header + e.nl)
+	e.emitBlock(buf, children, depth+1)
+	buf.WriteString(indent + "}" + trailing + e.nl)
+}
+
+func (e *Emitter) flatten(d *Directive) bool {
+	return e.options.Flatten && d.Directive == "include"
+}
+
+func (e *Emitter) emitFlattened(buf *bufio.Writer, d *Directive, depth int) {
+	indent := strings.Repeat(e.options.Indent, depth)
+	name := strings.Join(d.Args, " ")
+	if e.options.IncludeMarkers {
+		buf.WriteString(indent + "# begin include " + name + e.nl)
+	}
+	e.emitBlock(buf, d.Block, depth)
+	if e.options.IncludeMarkers {
+		buf.WriteString(indent + "# end include " + name + e.nl)
+	}
+}
+
+// emitLossless writes directives parsed with ParseOptions.Lossless using
+// their original text. Modified directives keep their surrounding
+// whitespace but are rendered again, new ones go on a line of their own.
+func (e *Emitter) emitLossless(buf *bufio.Writer, directives []*Directive, depth int) {
+	indent := strings.Repeat(e.options.Indent, depth)
+	for _, d := range e.sorted(directives) {
+		if e.staleSource(d) {
+			continue
+		}
+		t := d.Trivia
+		if t != nil {
+			buf.WriteString(t.Before)
+		} else {
+			buf.WriteString(e.nl + indent)
+		}
+		if e.options.Provenance && d.Provenance != nil {
+			buf.WriteString("# " + d.Provenance.String() + e.nl + indent)
+		}
+		if source := e.source(d); source != nil {
+			buf.WriteString("# " + source.comment() + e.nl + indent)
+		}
+		if e.flatten(d) {
+			// The flattened include is buffered to fit it in the
+			// original layout.
+			var flattened strings.Builder
+			fw := bufio.NewWriter(&flattened)
+			e.emitFlattened(fw, d, depth)
+			fw.Flush()
+			buf.WriteString(strings.TrimPrefix(strings.TrimSuffix(flattened.String(), e.nl), indent))
+			continue
+		}
+		if t != nil && !t.Modified(d) {
+			buf.WriteString(t.Text)
+		} else {
+			buf.WriteString(e.header(d, indent, 0))
+		}
+		if !isBlock(d) {
+			continue
+		}
+		if len(d.Block) > 0 && !hasTrivia(d.Block) {
+			buf.WriteString(e.nl)
+			e.emitBlock(buf, d.Block, depth+1)
+			buf.WriteString(indent + "}")
+			continue
+		}
+		e.emitLossless(buf, d.Block, depth+1)
+		if t != nil && t.Close != "" {
+			buf.WriteString(t.Close)
+		} else {
+			buf.WriteString(e.nl + indent + "}")
+		}
+	}
+}
+
+// header renders a directive up to its semicolon or opening brace, with
+// any trailing comment. The name of a simple directive is padded to width.
+func (e *Emitter) header(d *Directive, indent string, width int) string {
+	if d.Directive == "#" {
+		return "#" + d.Comment
+	}
+
+	if isLuaBlock(d) {
+		return e.luaHeader(d, indent)
+	}
+
+	var buf strings.Builder
+	buf.WriteString(e.words(d, width))
+	if isBlock(d) {
+		if e.options.BraceOnNewLine {
+			buf.WriteString(e.nl + indent + "{")
+		} else {
+			buf.WriteString(" {")
+		}
+	} else {
+		buf.WriteByte(';')
+	}
+	if d.Comment != "" {
+		buf.WriteString(" #" + d.Comment)
+	}
+	return buf.String()
+}
+
+// words renders the name and arguments of a directive.
+func (e *Emitter) words(d *Directive, width int) string {
+	var buf strings.Builder
+	quotes := make([]Quote, len(d.Args)+1)
+	for i := range d.Args {
+		quotes[i+1] = d.QuoteAt(i)
+	}
+	words := e.quoteWords(append([]string{d.Directive}, d.Args...), quotes)
+	buf.WriteString(words[0])
+	if !isBlock(d) && len(words) > 1 && len(words[0]) < width {
+		buf.WriteString(strings.Repeat(" ", width-len(words[0])))
+	}
+	if d.Directive == "if" {
+		buf.WriteString(" (")
+	}
+	for i, arg := range words[1:] {
+		// A quote right after the parenthesis would be read literally.
+		if i > 0 || d.Directive != "if" || arg[0] == '"' || arg[0] == '\'' {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(arg)
+	}
+	if d.Directive == "if" {
+		buf.WriteByte(')')
+	}
+	return buf.String()
+}
+
+// emitMinified writes directives without comments or optional whitespace.
+func (e *Emitter) emitMinified(buf *bufio.Writer, directives []*Directive) {
+	for _, d := range e.sorted(directives) {
+		switch {
+		case d.Directive == "#":
+		case e.flatten(d):
+			e.emitMinified(buf, d.Block)
+		case isLuaBlock(d):
+			// The newline ends a Lua comment on the last line.
+			buf.WriteString(e.luaHeader(d, ""))
+		case isBlock(d):
+			words := e.words(d, 0)
+			// ${ would start a variable, as in location ~ \.php$ {.
+			if strings.HasSuffix(words, "$") {
+				words += " "
+			}
+			buf.WriteString(words + "{")
+			e.emitMinified(buf, d.Block)
+			buf.WriteByte('}')
+		default:
+			buf.WriteString(e.words(d, 0) + ";")
+		}
+	}
+}
+
+// quoteWords quotes the words that need it, keeping the quotes a word was
+// written with where known. The parser joins adjacent strings quoted with
+// the same character, so quotes alternate between double and single for
+// consecutive quoted words.
+func (e *Emitter) quoteWords(words []string, quotes []Quote) []string {
+	result := make([]string, len(words))
+	var previous byte
+	for i, word := range words {
+		quote := byte(quotes[i])
+		if quote == 0 && !needsQuoting(word, e.options.RawEscapes) {
+			result[i], previous = word, 0
+			continue
+		}
+		if quote == 0 {
+			quote = '"'
+			// Escaping a quote would change a raw word, use the other.
+			if e.options.RawEscapes && hasBareQuote(word, quote) {
+				quote = '\''
+			}
+		}
+		if previous == quote {
+			quote = '"' + '\'' - quote
+		}
+		result[i] = quoteWith(word, quote, e.options.RawEscapes)
+		previous = quote
+	}
+	return result
+}
+
+// needsQuoting reports whether the parser would read a bare word back
+// differently: it would be empty, split at whitespace or a special
+// character, unescaped, start a // comment, or run an unterminated ${
+// past the end of the word. Backslashes are read as written with raw.
+func needsQuoting(word string, raw bool) bool {
+	if word == "" || strings.HasPrefix(word, "//") {
+		return true
+	}
+	for i := 0; i < len(word); i++ {
+		switch word[i] {
+		case '$':
+			if i+1 < len(word) && word[i+1] == '{' {
+				end := strings.IndexByte(word[i:], '}')
+				if end < 0 || strings.ContainsAny(word[i+2:i+end], " \t\r\n;{#\"'\\") {
+					return true
+				}
+				i += end
+			}
+		case '\\':
+			if raw {
+				i++
+				continue
+			}
+			return true
+		case ' ', '\t', '\r', '\n', ';', '{', '}', '#', '"', '\'':
+			return true
+		}
+	}
+	return false
+}
+
+// hasBareQuote reports whether a raw word contains an unescaped quote.
+func hasBareQuote(word string, quote byte) bool {
+	for i := 0; i < len(word); i++ {
+		switch word[i] {
+		case '\\':
+			i++
+		case quote:
+			return true
+		}
+	}
+	return false
+}
+
+// quoteWith quotes a word, escaping backslashes and the quote character,
+// the only escapes the parser resolves that can occur literally. A raw
+// word keeps its escapes, only quote characters not escaped yet are.
+func quoteWith(word string, quote byte, raw bool) string {
+	if !raw {
+		escaped := strings.NewReplacer(`\`, `\\`, string(quote), `\`+string(quote)).Replace(word)
+		return string(quote) + escaped + string(quote)
+	}
+	var buf strings.Builder
+	buf.WriteByte(quote)
+	for i := 0; i < len(word); i++ {
+		switch {
+		case word[i] == '\\' && i+1 < len(word):
+			buf.WriteString(word[i : i+2])
+			i++
+		case word[i] == quote:
+			buf.WriteString(`\` + string(quote))
+		default:
+			buf.WriteByte(word[i])
+		}
+	}
+	buf.WriteByte(quote)
+	return buf.String()
+}
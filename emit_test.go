@@ -0,0 +1,591 @@
+package nginxparser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func stripPositions(directives []*Directive) []*Directive {
+	result := make([]*Directive, 0, len(directives))
+	for _, d := range directives {
+		stripped := &Directive{Directive: d.Directive, Args: d.Args, Comment: d.Comment, Inline: d.Inline}
+		if d.Block != nil {
+			stripped.Block = stripPositions(d.Block)
+		}
+		result = append(result, stripped)
+	}
+	return result
+}
+
+func TestDump(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+events {}
+http { # main
+    server {
+        listen 80; # plain
+        server_name "example.com";
+        if ($http_x_test = "a b") {
+            return 403;
+        }
+        location / {
+            # comment
+            return 200 "foo \"bar\"";
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Dump(directives, &buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `events {
+}
+http { # main
+    server {
+        listen 80; # plain
+        server_name "example.com";
+        if ($http_x_test = "a b") {
+            return 403;
+        }
+        location / {
+            # comment
+            return 200 "foo \"bar\"";
+        }
+    }
+}
+`
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
+
+func TestDumpRoundTrip(t *testing.T) {
+	for _, name := range []string{"simple", "simple-with-if", "messy", "with-comments", "quote-behavior", "russian-text", "directive-with-space", "empty-value-map", "lua-block-simple", "lua-block-larger", "lua-block-tricky", "no-final-newline"} {
+		t.Run(name, func(t *testing.T) {
+			parser := New(&ParseOptions{SingleFile: true})
+			directives, err := parser.ParseFile(filepath.Join("testdata", name, "nginx.conf"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := Dump(directives, &buf); err != nil {
+				t.Fatal(err)
+			}
+			reparsed, err := New(&ParseOptions{SingleFile: true}).ParseString(buf.String())
+			if err != nil {
+				t.Fatalf("unexpected error %s in:\n%s", err, buf.String())
+			}
+			if !reflect.DeepEqual(stripPositions(directives), stripPositions(reparsed)) {
+				t.Fatalf("round trip changed the configuration:\n%s", buf.String())
+			}
+		})
+	}
+}
+
+func TestDumpLossless(t *testing.T) {
+	for _, name := range []string{"simple", "simple-with-if", "messy", "with-comments", "comments-between-args", "quote-behavior", "quoted-right-brace", "russian-text", "directive-with-space", "empty-value-map", "lua-block-simple", "lua-block-larger", "lua-block-tricky", "no-final-newline"} {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join("testdata", name, "nginx.conf")
+			src, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			directives, err := New(&ParseOptions{SingleFile: true, Lossless: true}).ParseFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := Dump(directives, &buf); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != string(src) {
+				t.Fatalf("expected:\n%s\nbut got:\n%s", src, buf.String())
+			}
+		})
+	}
+}
+
+func TestDumpLosslessModified(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true, Lossless: true}).ParseString(`
+http {
+  # upstreams
+  server   {
+      listen   80;
+
+      root /var/www;   # docroot
+  }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := directives[0].Block[1]
+	server.Block[1].Args[0] = "/srv/www"
+	server.Block = append(server.Block, &Directive{Directive: "index", Args: []string{"index.html"}})
+
+	var buf bytes.Buffer
+	if err := Dump(directives, &buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `
+http {
+  # upstreams
+  server   {
+      listen   80;
+
+      root /srv/www;   # docroot
+        index index.html;
+  }
+}
+`
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
+
+func TestEmitLuaBlock(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`location / {
+    content_by_lua_block {
+        local t = { "a", 'b' }
+        if t[1] == "a" then ngx.say("{}") end
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Dump(directives, &buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `location / {
+    content_by_lua_block {
+        local t = { "a", 'b' }
+        if t[1] == "a" then ngx.say("{}") end
+    }
+}
+`
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
+
+func TestEmitOptions(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`user nginx; events { worker_connections 1024; } http { server { listen 80; } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	emitter := NewEmitter(&EmitOptions{Indent: "\t", BraceOnNewLine: true, BlankLineBetweenBlocks: true})
+	if err := emitter.Emit(&buf, directives); err != nil {
+		t.Fatal(err)
+	}
+	expected := "user nginx;\n\nevents\n{\n\tworker_connections 1024;\n}\n\nhttp\n{\n\tserver\n\t{\n\t\tlisten 80;\n\t}\n}\n"
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
+
+func TestEmitBlankLines(t *testing.T) {
+	src := `user nginx;
+
+
+# workers
+worker_processes 4;
+http {
+
+    server {
+        listen 80;
+
+        location / {
+            return 204;
+        }
+    }
+    include mime.types;
+}
+`
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := directives[1].BlankLinesBefore; n != 2 {
+		t.Fatalf("expected 2 blank lines above the comment, got %d", n)
+	}
+	if n := directives[2].BlankLinesBefore; n != 0 {
+		t.Fatalf("expected no blank line under the comment, got %d", n)
+	}
+	if n := directives[3].Block[0].BlankLinesBefore; n != 1 {
+		t.Fatalf("expected a blank line after the opening brace, got %d", n)
+	}
+
+	var buf bytes.Buffer
+	if err := NewEmitter(&EmitOptions{BlankLines: true}).Emit(&buf, directives); err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Replace(src, "http {\n\n", "http {\n", 1)
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
+
+func TestEmitFlatten(t *testing.T) {
+	root := filepath.Join("testdata", "includes-regular")
+	expected := `events {
+}
+http {
+    # begin include conf.d/server.conf
+    server {
+        listen 127.0.0.1:8080;
+        server_name default_server;
+        # begin include foo.conf
+        location /foo {
+            return 200 'foo';
+        }
+        # end include foo.conf
+        # begin include bar.conf
+        # end include bar.conf
+    }
+    # end include conf.d/server.conf
+}
+`
+	directives, err := New(&ParseOptions{Root: root}).ParseFile(filepath.Join(root, "nginx.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := NewEmitter(&EmitOptions{Flatten: true, IncludeMarkers: true}).Emit(&buf, directives); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+
+	directives, err = New(&ParseOptions{Root: root, Lossless: true}).ParseFile(filepath.Join(root, "nginx.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := NewEmitter(&EmitOptions{Flatten: true}).Emit(&buf, directives); err != nil {
+		t.Fatal(err)
+	}
+	expected = `events {}
+http {
+    server {
+        listen 127.0.0.1:8080;
+        server_name default_server;
+        location /foo {
+            return 200 'foo';
+        }
+    }
+}
+`
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
+
+func TestEmitInlineComments(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`# top
+server { # opening
+    # standalone
+    listen 80; # trailing
+    location / {
+        return 204;
+    } # after block
+    # last
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inline := make([]bool, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive == "#" {
+			inline = append(inline, d.Inline)
+		}
+		return true
+	})
+	if expected := []bool{false, true, false, true, true, false}; !reflect.DeepEqual(inline, expected) {
+		t.Fatalf("expected %v but got %v", expected, inline)
+	}
+
+	var buf bytes.Buffer
+	if err := NewEmitter(&EmitOptions{Sort: true}).Emit(&buf, directives); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# top
+server { # opening
+    # standalone
+    listen 80; # trailing
+    location / {
+        return 204;
+    } # after block
+    # last
+}
+`
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
+
+func TestEmitAlign(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`server {
+    listen 443 ssl;
+    server_name example.com;
+    # headers
+    proxy_set_header Host $host;
+    internal;
+    location / { root /srv; }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := NewEmitter(&EmitOptions{Align: true}).Emit(&buf, directives); err != nil {
+		t.Fatal(err)
+	}
+	expected := `server {
+    listen           443 ssl;
+    server_name      example.com;
+    # headers
+    proxy_set_header Host $host;
+    internal;
+    location / {
+        root /srv;
+    }
+}
+`
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
+
+func TestEmitSort(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`server {
+    location /b { root /b; }
+    root /srv;
+    set $a 1;
+    # the name
+    server_name example.com;
+    location /a { gzip on; root /a; }
+    rewrite ^/old /new;
+    listen 80;
+    access_log off;
+    listen 443 ssl;
+    # trailing
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := NewEmitter(&EmitOptions{Sort: true}).Emit(&buf, directives); err != nil {
+		t.Fatal(err)
+	}
+	expected := `server {
+    listen 80;
+    listen 443 ssl;
+    # the name
+    server_name example.com;
+    access_log off;
+    set $a 1;
+    rewrite ^/old /new;
+    root /srv;
+    location /b {
+        root /b;
+    }
+    location /a {
+        gzip on;
+        root /a;
+    }
+    # trailing
+}
+`
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
+
+func TestEmitQuoting(t *testing.T) {
+	args := []string{
+		"", " ", "a b", "semi;colon", "{", "}", "#hash", "//slashes", "a//b",
+		`back\slash`, `\n`, `"`, `'`, `"'`, "new\nline", "tab\there", "cr\rhere",
+		"${host}", "${unterminated", "${a b}", "$host${uri}", "ünïcode",
+	}
+	for _, arg := range args {
+		for _, d := range []*Directive{
+			{Directive: "set", Args: []string{"$x", arg}},
+			{Directive: "add_header", Args: []string{arg, arg, arg}},
+			{Directive: "if", Args: []string{arg, "=", arg}, Block: []*Directive{{Directive: "return", Args: []string{"204"}}}},
+			{Directive: "location", Args: []string{arg}, Block: []*Directive{}},
+		} {
+			var buf bytes.Buffer
+			if err := Dump([]*Directive{d}, &buf); err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := New(&ParseOptions{SingleFile: true}).ParseString(buf.String())
+			if err != nil {
+				t.Fatalf("unexpected error %s parsing %q", err, buf.String())
+			}
+			if len(parsed) != 1 || !reflect.DeepEqual(parsed[0].Args, d.Args) {
+				t.Fatalf("%q did not round trip, parsed %q", buf.String(), parsed[0].Args)
+			}
+		}
+	}
+}
+
+type countingWriter struct {
+	writes int
+	max    int
+	err    error
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if len(p) > w.max {
+		w.max = len(p)
+	}
+	return len(p), w.err
+}
+
+func TestEmitStreaming(t *testing.T) {
+	block := make([]*Directive, 0)
+	for i := 0; i < 5000; i++ {
+		block = append(block, &Directive{Directive: "server", Args: []string{fmt.Sprintf("10.0.%d.%d:8080", i/256, i%256)}})
+	}
+	directives := []*Directive{{Directive: "upstream", Args: []string{"app"}, Block: block}}
+
+	w := &countingWriter{}
+	if err := Dump(directives, w); err != nil {
+		t.Fatal(err)
+	}
+	if w.writes < 10 || w.max > 4096 {
+		t.Fatalf("expected output in small chunks, got %d writes of up to %d bytes", w.writes, w.max)
+	}
+
+	w = &countingWriter{err: errors.New("broken pipe")}
+	if err := Dump(directives, w); err == nil || w.writes != 1 {
+		t.Fatalf("expected the write error after one write, got %v after %d", err, w.writes)
+	}
+}
+
+func TestEmitLineEnding(t *testing.T) {
+	filename := filepath.Join("testdata", "crlf", "nginx.conf")
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := New(&ParseOptions{SingleFile: true})
+	directives, err := parser.ParseFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lineEnding := parser.Files()[filename].LineEnding
+	if lineEnding != "\r\n" {
+		t.Fatalf("expected CRLF but got %q", lineEnding)
+	}
+	var buf bytes.Buffer
+	if err := NewEmitter(&EmitOptions{LineEnding: lineEnding}).Emit(&buf, directives); err != nil {
+		t.Fatal(err)
+	}
+	expected := "events {\r\n}\r\nhttp {\r\n    # crlf\r\n    server {\r\n        listen 80;\r\n    }\r\n}\r\n"
+	if buf.String() != expected {
+		t.Fatalf("expected %q but got %q", expected, buf.String())
+	}
+
+	directives, err = New(&ParseOptions{SingleFile: true, Lossless: true}).ParseFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	directives[1].Block[1].Block = append(directives[1].Block[1].Block, NewDirective("root", "/srv"))
+	buf.Reset()
+	if err := NewEmitter(&EmitOptions{LineEnding: "\r\n"}).Emit(&buf, directives); err != nil {
+		t.Fatal(err)
+	}
+	expected = strings.Replace(string(src), "listen 80;", "listen 80;\r\n        root /srv;", 1)
+	if buf.String() != expected {
+		t.Fatalf("expected %q but got %q", expected, buf.String())
+	}
+}
+
+func TestEmitMinify(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+# global
+user  nginx;
+http {
+    server {   # main
+        listen       80;
+        server_name  "example.com" 'two words';
+        if ($host = "example.com") {
+            return 301 /;
+        }
+        content_by_lua_block {
+            ngx.say("ok") -- done
+        }
+    }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := NewEmitter(&EmitOptions{Minify: true, Indent: "  ", Align: true}).Emit(&buf, directives); err != nil {
+		t.Fatal(err)
+	}
+	expected := "user nginx;http{server{listen 80;server_name \"example.com\" 'two words';if ($host = \"example.com\"){return 301 /;}content_by_lua_block {\n            ngx.say(\"ok\") -- done\n}}}"
+	if buf.String() != expected {
+		t.Fatalf("expected %q but got %q", expected, buf.String())
+	}
+
+	reparsed, err := New(&ParseOptions{SingleFile: true}).ParseString(buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var strip func([]*Directive) []*Directive
+	strip = func(directives []*Directive) []*Directive {
+		result := make([]*Directive, 0)
+		for _, d := range stripPositions(directives) {
+			if d.Directive == "#" {
+				continue
+			}
+			d.Comment, d.Inline = "", false
+			if d.Block != nil {
+				d.Block = strip(d.Block)
+			}
+			result = append(result, d)
+		}
+		return result
+	}
+	if !reflect.DeepEqual(strip(directives), strip(reparsed)) {
+		t.Fatalf("expected minified config to parse back to the same directives")
+	}
+}
+
+func TestEmitMinifyRegexLocation(t *testing.T) {
+	src := "server {\n    location ~ \\.php$ {\n        return 403;\n    }\n    location = / {\n        return 200;\n    }\n}\n"
+	directives, err := New(&ParseOptions{SingleFile: true, RawEscapes: true}).ParseString(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := NewEmitter(&EmitOptions{Minify: true, RawEscapes: true}).Emit(&buf, directives); err != nil {
+		t.Fatal(err)
+	}
+	if expected := `server{location ~ \.php$ {return 403;}location = /{return 200;}}`; buf.String() != expected {
+		t.Fatalf("expected %q but got %q", expected, buf.String())
+	}
+	reparsed, err := New(&ParseOptions{SingleFile: true, RawEscapes: true}).ParseString(buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(stripPositions(directives), stripPositions(reparsed)) {
+		t.Fatal("expected the minified config to parse back to the same directives")
+	}
+}
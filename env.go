@@ -0,0 +1,358 @@
+package nginxparser
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Env supplies the values of variables to evaluate a configuration for a
+// request: if conditions, map and geo lookups and routing.
+type Env struct {
+	// Variables maps names, without $, to values.
+	Variables map[string]string
+	// Lookup is asked for the variables missing from Variables.
+	Lookup func(name string) (string, bool)
+	// Config, when set, computes the variables defined by its map and geo
+	// blocks.
+	Config []*Directive
+	// Stat backs the file tests of if conditions, it defaults to os.Stat.
+	Stat func(name string) (os.FileInfo, error)
+
+	// captures holds the groups of the last regex matched, $1 to $9.
+	captures []string
+	// evaluating guards against maps defined in terms of themselves.
+	evaluating map[string]bool
+}
+
+// NewRequestEnv fills an environment with the variables nginx derives
+// from a request: host, scheme, request_method, request_uri, uri, args,
+// remote_addr, server_port, and the http_, arg_ and cookie_ families.
+func NewRequestEnv(r *http.Request) *Env {
+	v := make(map[string]string)
+	scheme, port := "http", "80"
+	if r.TLS != nil || r.URL.Scheme == "https" {
+		scheme, port = "https", "443"
+	}
+	host := r.Host
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		host, port = h, p
+	}
+	v["host"] = strings.ToLower(strings.TrimSuffix(host, "."))
+	v["scheme"] = scheme
+	v["https"] = ""
+	if scheme == "https" {
+		v["https"] = "on"
+	}
+	v["server_port"] = port
+	v["server_protocol"] = r.Proto
+	v["request_method"] = r.Method
+	v["request_uri"] = r.URL.RequestURI()
+	v["uri"], v["document_uri"] = r.URL.Path, r.URL.Path
+	v["args"], v["query_string"] = r.URL.RawQuery, r.URL.RawQuery
+	v["is_args"] = ""
+	if r.URL.RawQuery != "" {
+		v["is_args"] = "?"
+	}
+	for name, values := range r.URL.Query() {
+		v["arg_"+name] = values[0]
+	}
+	v["remote_addr"] = r.RemoteAddr
+	if addr, p, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		v["remote_addr"], v["remote_port"] = addr, p
+	}
+	v["http_host"] = r.Host
+	for name, values := range r.Header {
+		v["http_"+strings.ReplaceAll(strings.ToLower(name), "-", "_")] = strings.Join(values, ", ")
+	}
+	for _, cookie := range r.Cookies() {
+		v["cookie_"+cookie.Name] = cookie.Value
+	}
+	v["content_type"] = r.Header.Get("Content-Type")
+	v["content_length"] = r.Header.Get("Content-Length")
+	return &Env{Variables: v}
+}
+
+// Value returns the value of a variable, false when the environment does
+// not know it.
+func (e *Env) Value(name string) (string, bool) {
+	if len(name) == 1 && name[0] >= '0' && name[0] <= '9' {
+		i := int(name[0] - '0')
+		if i < len(e.captures) {
+			return e.captures[i], true
+		}
+		return "", true
+	}
+	if value, ok := e.Variables[name]; ok {
+		return value, true
+	}
+	if e.Lookup != nil {
+		if value, ok := e.Lookup(name); ok {
+			return value, true
+		}
+	}
+	if definition := e.definition(name); definition != nil && !e.evaluating[name] {
+		if e.evaluating == nil {
+			e.evaluating = make(map[string]bool)
+		}
+		e.evaluating[name] = true
+		defer delete(e.evaluating, name)
+		if definition.Directive == "geo" {
+			return e.Geo(definition), true
+		}
+		return e.Map(definition), true
+	}
+	return "", false
+}
+
+// definition returns the map or geo block of the configuration that
+// defines a variable.
+func (e *Env) definition(name string) *Directive {
+	var result *Directive
+	Walk(e.Config, func(d *Directive, parents []*Directive) bool {
+		if result != nil {
+			return false
+		}
+		if (d.Directive == "map" || d.Directive == "geo") && definitionKey(d) == "$"+name {
+			result = d
+			return false
+		}
+		return d.Directive != "server"
+	})
+	return result
+}
+
+// Expand replaces the variables in s with their values, unknown variables
+// expand to "".
+func (e *Env) Expand(s string) string {
+	if !strings.Contains(s, "$") {
+		return s
+	}
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			buf.WriteByte(s[i])
+			continue
+		}
+		var name string
+		j := i + 1
+		switch {
+		case s[j] == '{':
+			end := strings.IndexByte(s[j:], '}')
+			if end < 0 {
+				buf.WriteString(s[i:])
+				return buf.String()
+			}
+			name, j = s[j+1:j+end], j+end+1
+		case s[j] >= '0' && s[j] <= '9':
+			name, j = s[j:j+1], j+1
+		default:
+			for j < len(s) && isVariableByte(s[j]) {
+				j++
+			}
+			name = s[i+1 : j]
+		}
+		if name == "" {
+			buf.WriteByte('$')
+			continue
+		}
+		value, _ := e.Value(name)
+		buf.WriteString(value)
+		i = j - 1
+	}
+	return buf.String()
+}
+
+// Eval evaluates an if condition. A matching regex sets the captures $1 to
+// $9 for the directives that follow, as in nginx.
+func (e *Env) Eval(c *Condition) bool {
+	if fileConditions[c.Operator] {
+		stat := e.Stat
+		if stat == nil {
+			stat = os.Stat
+		}
+		info, err := stat(e.Expand(c.Operand))
+		var result bool
+		switch c.Operator {
+		case ConditionFile:
+			result = err == nil && info.Mode().IsRegular()
+		case ConditionDirectory:
+			result = err == nil && info.IsDir()
+		case ConditionExists:
+			result = err == nil
+		case ConditionExecutable:
+			result = err == nil && info.Mode()&0111 != 0
+		}
+		return result != c.Negate
+	}
+	value, _ := e.Value(c.Variable)
+	result, captures := c.Compare(value, e.Expand(c.Operand))
+	if captures != nil {
+		e.captures = captures
+	}
+	return result
+}
+
+// Map looks up the value a map block gives its variable: exact strings
+// first, then the wildcard names of a map with hostnames, then regexes in
+// order, and finally the default.
+func (e *Env) Map(d *Directive) string {
+	if len(d.Args) != 2 {
+		return ""
+	}
+	source := e.Expand(d.Args[0])
+	var fallback string
+	hostnames := false
+	wildcard, wildcardLen := "", -1
+	regexes := make([]*Directive, 0)
+	for _, entry := range children(d.Block) {
+		if len(entry.Args) != 1 {
+			if entry.Directive == "hostnames" {
+				hostnames = true
+			}
+			continue
+		}
+		key, value := entry.Directive, entry.Args[0]
+		switch {
+		case key == "default":
+			fallback = value
+		case strings.HasPrefix(key, "~"):
+			regexes = append(regexes, entry)
+		case strings.TrimPrefix(key, "\\") == source:
+			return e.Expand(value)
+		case hostnames && matchWildcardName(key, source) && len(key) > wildcardLen:
+			wildcard, wildcardLen = value, len(key)
+		}
+	}
+	if wildcardLen >= 0 {
+		return e.Expand(wildcard)
+	}
+	for _, entry := range regexes {
+		caseless := strings.HasPrefix(entry.Directive, "~*")
+		pattern := strings.TrimPrefix(strings.TrimPrefix(entry.Directive, "~*"), "~")
+		re, err := compileRegex(pattern, caseless)
+		if err != nil {
+			continue
+		}
+		if captures := re.FindStringSubmatch(source); captures != nil {
+			saved := e.captures
+			e.captures = captures
+			value := e.Expand(entry.Args[0])
+			e.captures = saved
+			return value
+		}
+	}
+	return e.Expand(fallback)
+}
+
+// matchWildcardName matches a server name such as *.example.com,
+// .example.com or www.example.* against host.
+func matchWildcardName(name, host string) bool {
+	switch {
+	case strings.HasPrefix(name, "*."):
+		return strings.HasSuffix(host, name[1:])
+	case strings.HasPrefix(name, "."):
+		return host == name[1:] || strings.HasSuffix(host, name)
+	case strings.HasSuffix(name, ".*"):
+		return strings.HasPrefix(host, name[:len(name)-1])
+	}
+	return false
+}
+
+// Geo looks up the value a geo block gives its variable for the client
+// address, $remote_addr unless the block names another variable: the
+// longest matching network, or the range containing it with ranges.
+func (e *Env) Geo(d *Directive) string {
+	if len(d.Args) == 0 {
+		return ""
+	}
+	source := "$remote_addr"
+	if len(d.Args) > 1 {
+		source = d.Args[0]
+	}
+	ip := net.ParseIP(e.Expand(source))
+	var fallback, result string
+	ranges := false
+	bestLen := -1
+	for _, entry := range children(d.Block) {
+		switch {
+		case entry.Directive == "ranges":
+			ranges = true
+			continue
+		case len(entry.Args) != 1:
+			continue
+		case entry.Directive == "default":
+			fallback = entry.Args[0]
+			continue
+		case ip == nil:
+			continue
+		}
+		if ranges {
+			bounds := strings.SplitN(entry.Directive, "-", 2)
+			if len(bounds) != 2 {
+				continue
+			}
+			low, high := net.ParseIP(bounds[0]), net.ParseIP(bounds[1])
+			if low != nil && high != nil && bytes.Compare(ip.To16(), low.To16()) >= 0 && bytes.Compare(ip.To16(), high.To16()) <= 0 {
+				return entry.Args[0]
+			}
+			continue
+		}
+		network := entry.Directive
+		if !strings.Contains(network, "/") {
+			if strings.Contains(network, ":") {
+				network += "/128"
+			} else {
+				network += "/32"
+			}
+		}
+		_, ipnet, err := net.ParseCIDR(network)
+		if err != nil || !ipnet.Contains(ip) {
+			continue
+		}
+		if ones, _ := ipnet.Mask.Size(); ones > bestLen {
+			result, bestLen = entry.Args[0], ones
+		}
+	}
+	if bestLen >= 0 {
+		return result
+	}
+	return fallback
+}
+
+// Route simulates how nginx handles the request of the environment,
+// taken from $host, $server_port and $uri. The if blocks of the server
+// and of the selected location whose conditions hold are reported in
+// Route.Ifs, in the order nginx evaluates them.
+func (e *Env) Route(directives []*Directive) *Route {
+	host, _ := e.Value("host")
+	port, _ := e.Value("server_port")
+	uri, _ := e.Value("uri")
+	e.Config = directives
+	route := &Route{Host: host, Port: port, URI: uri, Ifs: make([]*Directive, 0)}
+	route.Server = FindServer(directives, host, port)
+	if route.Server == nil {
+		return route
+	}
+	e.ifs(route.Server.Block, route)
+	route.Location = InternalRedirect(route.Server, uri)
+	if route.Location != nil {
+		if modifier, _ := locationPattern(route.Location); modifier == locationRegex || modifier == locationRegexNoCase {
+			if re, err := locationRegexp(route.Location); err == nil {
+				e.captures = re.FindStringSubmatch(uri)
+			}
+		}
+		e.ifs(route.Location.Block, route)
+	}
+	return route
+}
+
+func (e *Env) ifs(block []*Directive, route *Route) {
+	for _, d := range findAll(block, "if") {
+		if c, err := ParseCondition(d); err == nil && e.Eval(c) {
+			route.Ifs = append(route.Ifs, d)
+		}
+	}
+}
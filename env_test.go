@@ -0,0 +1,90 @@
+package nginxparser
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestEnv(t *testing.T) {
+	directives, err := New(&ParseOptions{}).ParseString(`http {
+    map $http_user_agent $client {
+        default       browser;
+        ~*^curl/([0-9]+) curl$1;
+        bot           crawler;
+    }
+    map $host $site {
+        hostnames;
+        default        unknown;
+        *.example.com  example;
+        api.example.com api;
+    }
+    geo $office {
+        default        0;
+        10.0.0.0/8     1;
+        10.1.0.0/16    2;
+    }
+    server {
+        listen 80;
+        server_name example.com *.example.com;
+        if ($client = bot) { return 403; }
+        location ~ ^/files/(.+)$ {
+            if (!-f /srv/$1) { return 404; }
+            if ($office) { return 200; }
+        }
+    }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "http://www.example.com/files/a.txt?x=1", nil)
+	r.RemoteAddr = "10.1.2.3:5000"
+	r.Header.Set("User-Agent", "curl/8.1")
+	env := NewRequestEnv(r)
+	env.Config = directives
+	env.Stat = func(name string) (os.FileInfo, error) {
+		return nil, os.ErrNotExist
+	}
+	for name, expected := range map[string]string{
+		"host":        "www.example.com",
+		"server_port": "80",
+		"uri":         "/files/a.txt",
+		"arg_x":       "1",
+		"is_args":     "?",
+		"client":      "curl8",
+		"site":        "example",
+		"office":      "2",
+	} {
+		if value, _ := env.Value(name); value != expected {
+			t.Fatalf("expected $%s to be %q, got %q", name, expected, value)
+		}
+	}
+	if s := env.Expand("$scheme://${host}$request_uri $missing"); s != "http://www.example.com/files/a.txt?x=1 " {
+		t.Fatalf("unexpected expansion %q", s)
+	}
+
+	route := env.Route(directives)
+	if route.Server == nil || route.Location == nil || route.Location.Args[0] != "~" {
+		t.Fatalf("unexpected route %+v", route)
+	}
+	if len(route.Ifs) != 2 || route.Ifs[0].Line != 23 || route.Ifs[1].Line != 24 {
+		t.Fatalf("expected both location ifs to hold, got %d", len(route.Ifs))
+	}
+
+	env = &Env{Variables: map[string]string{"host": "api.example.com", "http_user_agent": "bot", "remote_addr": "192.0.2.1"}, Config: directives}
+	if site, _ := env.Value("site"); site != "api" {
+		t.Fatalf("expected the exact name to win, got %q", site)
+	}
+	if office, _ := env.Value("office"); office != "0" {
+		t.Fatalf("expected the default, got %q", office)
+	}
+	c, err := ParseCondition(directives[0].FirstChild("server").FirstChild("if"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Eval(c) {
+		t.Fatal("expected $client to be crawler")
+	}
+}
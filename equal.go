@@ -0,0 +1,83 @@
+package nginxparser
+
+type equalOptions struct {
+	lines     bool
+	fileNames bool
+	comments  bool
+}
+
+// EqualOption relaxes the comparison made by Equal.
+type EqualOption func(options *equalOptions)
+
+// IgnoreLines compares directives wherever they are in their file.
+func IgnoreLines() EqualOption {
+	return func(options *equalOptions) {
+		options.lines = false
+	}
+}
+
+// IgnoreFileNames compares directives whatever file they were read from.
+func IgnoreFileNames() EqualOption {
+	return func(options *equalOptions) {
+		options.fileNames = false
+	}
+}
+
+// IgnoreComments skips comment lines and the inline comments of
+// directives.
+func IgnoreComments() EqualOption {
+	return func(options *equalOptions) {
+		options.comments = false
+	}
+}
+
+// Equal reports whether two trees hold the same directives, arguments and
+// blocks, in the same order, including the content of include
+// directives. Line, FileName and comments are compared unless an option
+// says otherwise; how arguments were quoted and formatted never is.
+func Equal(a, b []*Directive, opts ...EqualOption) bool {
+	options := &equalOptions{lines: true, fileNames: true, comments: true}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return equalBlock(a, b, options)
+}
+
+func equalBlock(a, b []*Directive, options *equalOptions) bool {
+	if !options.comments {
+		a, b = withoutComments(a), withoutComments(b)
+	}
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equalDirective(a[i], b[i], options) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalDirective(a, b *Directive, options *equalOptions) bool {
+	switch {
+	case a.Directive != b.Directive, !equalStrings(a.Args, b.Args), isBlock(a) != isBlock(b):
+		return false
+	case options.lines && a.Line != b.Line:
+		return false
+	case options.fileNames && a.FileName != b.FileName:
+		return false
+	case options.comments && a.Comment != b.Comment:
+		return false
+	}
+	return equalBlock(a.Block, b.Block, options)
+}
+
+func withoutComments(directives []*Directive) []*Directive {
+	result := make([]*Directive, 0, len(directives))
+	for _, d := range directives {
+		if d.Directive != "#" {
+			result = append(result, d)
+		}
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	a, err := New(&ParseOptions{}).ParseString(`http {
+    server {
+        listen 80; # plain
+        location / { return 204; }
+    }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New(&ParseOptions{}).ParseString(`# generated
+http {
+
+    server {
+        listen "80";
+        location / {
+            return 204;
+        }
+    }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(a, a) || !Equal(a, CloneAll(a)) {
+		t.Fatal("expected a tree to equal itself")
+	}
+	if Equal(a, b) || Equal(a, b, IgnoreLines()) || Equal(a, b, IgnoreComments()) {
+		t.Fatal("expected lines and comments to be compared by default")
+	}
+	if !Equal(a, b, IgnoreLines(), IgnoreComments()) {
+		t.Fatal("expected the trees to be equal without lines and comments")
+	}
+
+	c := CloneAll(a)
+	for _, d := range c {
+		d.FileName = "other.conf"
+	}
+	if Equal(a, c) || !Equal(a, c, IgnoreFileNames()) {
+		t.Fatal("expected file names to be compared unless ignored")
+	}
+	c = CloneAll(a)
+	c[0].FirstChild("server").FirstChild("location").Block[0].Args[0] = "404"
+	if Equal(a, c, IgnoreLines(), IgnoreFileNames(), IgnoreComments()) {
+		t.Fatal("expected arguments to be compared")
+	}
+	if Equal([]*Directive{NewBlock("server", nil)}, []*Directive{NewDirective("server")}) {
+		t.Fatal("expected an empty block to differ from no block")
+	}
+}
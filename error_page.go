@@ -0,0 +1,115 @@
+package nginxparser
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	ErrorPageNamed = "named"
+	ErrorPageURI   = "uri"
+	ErrorPageURL   = "url"
+)
+
+type ErrorPage struct {
+	Directive *Directive `json:"-"`
+	Server    *Directive `json:"-"`
+	Codes     []int      `json:"codes"`
+	Response  string     `json:"response,omitempty"`
+	Target    string     `json:"target"`
+	Kind      string     `json:"kind"`
+	Location  *Directive `json:"-"`
+}
+
+// ParseErrorPage splits an error_page directive into the codes it
+// captures, the optional =response override and its target.
+func ParseErrorPage(d *Directive) *ErrorPage {
+	page := &ErrorPage{Directive: d, Codes: make([]int, 0)}
+	if len(d.Args) == 0 {
+		return page
+	}
+	page.Target = d.Args[len(d.Args)-1]
+	for _, arg := range d.Args[:len(d.Args)-1] {
+		if strings.HasPrefix(arg, "=") {
+			page.Response = arg
+			continue
+		}
+		code, err := strconv.Atoi(arg)
+		if err != nil {
+			code = 0
+		}
+		page.Codes = append(page.Codes, code)
+	}
+	switch {
+	case strings.HasPrefix(page.Target, "@"):
+		page.Kind = ErrorPageNamed
+	case strings.Contains(page.Target, "://"), strings.HasPrefix(page.Target, "$scheme"):
+		page.Kind = ErrorPageURL
+	default:
+		page.Kind = ErrorPageURI
+	}
+	return page
+}
+
+// ErrorPages models every error_page directive and resolves its target to
+// a location of the enclosing server. Directives at http level yield one
+// entry per server.
+func ErrorPages(directives []*Directive) []*ErrorPage {
+	result := make([]*ErrorPage, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive != "error_page" {
+			return true
+		}
+		scope := []*Directive{enclosing("server", parents)}
+		if scope[0] == nil {
+			scope = servers(parents)
+		}
+		for _, server := range scope {
+			page := ParseErrorPage(d)
+			page.Server = server
+			switch page.Kind {
+			case ErrorPageNamed:
+				page.Location = NamedLocation(server, page.Target)
+			case ErrorPageURI:
+				if len(Variables(page.Target)) == 0 {
+					page.Location = FindLocation(server, page.Target)
+				}
+			}
+			result = append(result, page)
+		}
+		return true
+	})
+	return result
+}
+
+func checkErrorPages(directives []*Directive) []*Issue {
+	issues := make([]*Issue, 0)
+	for _, page := range ErrorPages(directives) {
+		for _, code := range page.Codes {
+			if code < 300 || code > 599 {
+				issues = append(issues, newIssue("error-page", page.Directive, "error_page code must be between 300 and 599"))
+			}
+		}
+		if page.Location == nil && page.Kind != ErrorPageURL && len(Variables(page.Target)) == 0 {
+			issues = append(issues, newArgIssue("error-page", page.Directive, len(page.Directive.Args)-1, "error_page target %s does not match any location", page.Target))
+		}
+	}
+
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive != "location" {
+			return true
+		}
+		for _, module := range []string{"proxy", "fastcgi", "uwsgi", "scgi", "grpc"} {
+			pass := findFirst(d.Block, module+"_pass")
+			if pass == nil || len(lookupInheritedAll("error_page", d.Block, parents)) == 0 {
+				continue
+			}
+			intercept := lookupInherited(module+"_intercept_errors", d.Block, parents)
+			if intercept == nil || len(intercept.Args) == 0 || intercept.Args[0] != "on" {
+				issues = append(issues, newIssue("error-page", pass, "error_page does not apply to upstream responses unless %s_intercept_errors is on", module))
+			}
+		}
+		return true
+	})
+	return issues
+}
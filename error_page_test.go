@@ -0,0 +1,49 @@
+package nginxparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestErrorPages(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    error_page 500 502 503 504 /50x.html;
+    server {
+        error_page 404 =200 @fallback;
+        error_page 403 https://example.com/forbidden;
+        error_page 200 /ok.html;
+        location / {
+            proxy_pass http://backend;
+        }
+        location = /50x.html {
+            internal;
+        }
+        location @fallback {
+            return 200;
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages := ErrorPages(directives)
+	if len(pages) != 4 {
+		t.Fatalf("expected 4 error pages but got %d", len(pages))
+	}
+	if !reflect.DeepEqual(pages[0].Codes, []int{500, 502, 503, 504}) || pages[0].Location == nil {
+		t.Fatalf("unexpected error page %+v", pages[0])
+	}
+	if pages[1].Kind != ErrorPageNamed || pages[1].Response != "=200" || pages[1].Location == nil {
+		t.Fatalf("unexpected error page %+v", pages[1])
+	}
+	if pages[2].Kind != ErrorPageURL {
+		t.Fatalf("unexpected error page %+v", pages[2])
+	}
+
+	issues := checkErrorPages(directives)
+	if len(issues) != 2 || issues[0].Line != 7 || issues[1].Line != 9 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+}
@@ -0,0 +1,108 @@
+package nginxparser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+var (
+	ErrSyntax     = errors.New("syntax error")
+	ErrNotFound   = errors.New("file not found")
+	ErrPermission = errors.New("permission denied")
+	ErrGlob       = errors.New("glob failed")
+	ErrInclude    = errors.New("include failed")
+	ErrIO         = errors.New("read failed")
+	// ErrIncludeDepth is wrapped by the ErrInclude error returned when
+	// includes nest deeper than ParseOptions.MaxIncludeDepth.
+	ErrIncludeDepth = errors.New("include depth exceeded")
+	// ErrIncludeCycle is wrapped by the ErrInclude error returned when a
+	// file includes itself, directly or through other files. The message
+	// names the files of the cycle.
+	ErrIncludeCycle = errors.New("include cycle")
+	// ErrUnknownDirective and ErrInvalidDirective are returned with
+	// ParseOptions.Strict for directives missing from the catalog, and for
+	// those used in the wrong context, with the wrong number of arguments
+	// or with a block they do not take.
+	ErrUnknownDirective = errors.New("unknown directive")
+	ErrInvalidDirective = errors.New("invalid directive")
+	// ErrCanceled is the kind of the error returned when the context of
+	// ParseFileContext or ParseReaderContext is done, the error unwraps to
+	// the error of the context.
+	ErrCanceled = errors.New("parse canceled")
+)
+
+// ParseError is returned for every parse failure. Its Kind is one of the
+// Err* sentinels so callers can use errors.Is instead of matching text, and
+// Includes lists the include directives, outermost first, that led to the
+// failing file.
+type ParseError struct {
+	Kind     error
+	FileName string
+	Line     int
+	Message  string
+	Includes []string
+	Err      error
+}
+
+func (e *ParseError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = e.Kind.Error()
+		if e.Err != nil {
+			msg = e.Err.Error()
+		}
+	}
+	if len(e.Includes) > 0 {
+		msg += fmt.Sprintf(" (included from %s)", strings.Join(e.Includes, " -> "))
+	}
+	return msg
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ParseError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (p *parser) syntaxError(format string, args ...interface{}) error {
+	return &ParseError{
+		Kind:     ErrSyntax,
+		FileName: p.filename,
+		Line:     p.line,
+		Message:  fmt.Sprintf(format, args...),
+		Includes: p.includes,
+	}
+}
+
+// wrapError classifies an error returned by the Open, Glob or reader of a
+// parser. Errors that already are a *ParseError are returned unchanged.
+func (p *parser) wrapError(kind error, err error) error {
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		return err
+	}
+	if kind == nil {
+		switch {
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			kind = ErrCanceled
+		case errors.Is(err, fs.ErrNotExist):
+			kind = ErrNotFound
+		case errors.Is(err, fs.ErrPermission):
+			kind = ErrPermission
+		default:
+			kind = ErrIO
+		}
+	}
+	return &ParseError{
+		Kind:     kind,
+		FileName: p.filename,
+		Line:     p.line,
+		Includes: p.includes,
+		Err:      err,
+	}
+}
@@ -0,0 +1,40 @@
+package nginxparser
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	_, err := New(nil).ParseFile("testdata/does-not-exist/nginx.conf")
+	if !errors.Is(err, ErrNotFound) || !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected ErrNotFound but got %v", err)
+	}
+
+	_, err = New(&ParseOptions{SingleFile: true}).ParseString("events {\n'unterminated;\n")
+	if !errors.Is(err, ErrSyntax) {
+		t.Fatalf("expected ErrSyntax but got %v", err)
+	}
+
+	_, err = New(&ParseOptions{
+		Root: "testdata",
+		Glob: func(pattern string) ([]string, error) {
+			return nil, filepath.ErrBadPattern
+		},
+	}).ParseString("include [;")
+	if !errors.Is(err, ErrGlob) || !errors.Is(err, filepath.ErrBadPattern) {
+		t.Fatalf("expected ErrGlob but got %v", err)
+	}
+
+	_, err = New(&ParseOptions{Root: filepath.Join("testdata", "includes-broken")}).ParseFile(filepath.Join("testdata", "includes-broken", "nginx.conf"))
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Kind != ErrSyntax {
+		t.Fatalf("expected *ParseError but got %v", err)
+	}
+	if parseErr.FileName != "testdata/includes-broken/conf.d/broken.conf" || !reflect.DeepEqual(parseErr.Includes, []string{"testdata/includes-broken/nginx.conf:2"}) {
+		t.Fatalf("unexpected error %+v", parseErr)
+	}
+}
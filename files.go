@@ -0,0 +1,44 @@
+package nginxparser
+
+const (
+	FileOK     = "ok"
+	FileFailed = "failed"
+)
+
+type FileResult struct {
+	FileName   string       `json:"file"`
+	Status     string       `json:"status"`
+	Err        error        `json:"-"`
+	Error      string       `json:"error,omitempty"`
+	LineEnding string       `json:"line_ending,omitempty"`
+	Directives []*Directive `json:"parsed"`
+	// Watermark is set with ParseOptions.Watermark for generated files.
+	Watermark *Watermark `json:"watermark,omitempty"`
+}
+
+func (p *parser) record(filename string, directives []*Directive, err error) *FileResult {
+	result := &FileResult{
+		FileName:   filename,
+		Status:     FileOK,
+		Directives: directives,
+	}
+	if err != nil {
+		result.Status, result.Err, result.Error = FileFailed, err, err.Error()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.files[filename] = result
+	return result
+}
+
+// Files returns the outcome of every file read by the last ParseFile call,
+// keyed by file name, with the top-level directives of each file. With
+// ParseOptions.CatchErrors set, files that failed to parse are skipped
+// instead of aborting the parse and are only reported here.
+func (p *Parser) Files() map[string]*FileResult {
+	last := p.lastParse()
+	if last == nil {
+		return nil
+	}
+	return last.files
+}
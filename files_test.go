@@ -0,0 +1,34 @@
+package nginxparser
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestFiles(t *testing.T) {
+	root := filepath.Join("testdata", "includes-broken")
+	main := filepath.Join(root, "nginx.conf")
+	broken := filepath.Join(root, "conf.d", "broken.conf")
+
+	parser := New(&ParseOptions{Root: root, CatchErrors: true})
+	directives, err := parser.ParseFile(main)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	files := parser.Files()
+	if len(files) != 2 || files[main].Status != FileOK || len(files[main].Directives) != len(directives) {
+		t.Fatalf("unexpected files %+v", files)
+	}
+	if files[broken].Status != FileFailed || !errors.Is(files[broken].Err, ErrSyntax) {
+		t.Fatalf("unexpected result %+v", files[broken])
+	}
+
+	parser = New(&ParseOptions{Root: root})
+	if _, err := parser.ParseFile(main); err == nil {
+		t.Fatal("expected error but got nil")
+	}
+	if files := parser.Files(); files[main].Status != FileFailed || files[broken].Status != FileFailed {
+		t.Fatalf("unexpected files %+v", files)
+	}
+}
@@ -0,0 +1,193 @@
+package nginxparser
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// FleetTarget is the configuration of one host.
+type FleetTarget struct {
+	// Host keys the report of the target, it should be unique.
+	Host string
+	// File is the main configuration file, in FS when it is set and on
+	// the disk otherwise.
+	File string
+	FS   fs.FS
+	// Root is the directory relative includes are resolved against, the
+	// directory of File when empty.
+	Root string
+}
+
+type FleetOptions struct {
+	// Parse is copied for every host, with FS and Root taken from the
+	// target. Its Glob and Open must be safe for concurrent use.
+	Parse *ParseOptions
+	// Rules names the lint rules to run, all of them when empty.
+	Rules []string
+	// Checks are run after the lint rules, for analyses that are not
+	// registered rules such as CheckTLS with options bound. They are
+	// called from several goroutines at once.
+	Checks []*Rule
+	// Concurrency is the number of hosts parsed at once, GOMAXPROCS when
+	// zero.
+	Concurrency int
+}
+
+type HostReport struct {
+	Host string `json:"host"`
+	// Err is set when the configuration of the host could not be parsed,
+	// no rules were run then.
+	Err        error        `json:"-"`
+	Error      string       `json:"error,omitempty"`
+	Issues     []*Issue     `json:"issues"`
+	Directives []*Directive `json:"-"`
+}
+
+type FleetReport struct {
+	Hosts map[string]*HostReport `json:"hosts"`
+}
+
+// Failed returns the hosts whose configuration could not be parsed,
+// sorted.
+func (r *FleetReport) Failed() []string {
+	hosts := make([]string, 0)
+	for host, report := range r.Hosts {
+		if report.Err != nil {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// ByRule returns the sorted hosts with issues for every rule reported.
+func (r *FleetReport) ByRule() map[string][]string {
+	result := make(map[string][]string)
+	for host, report := range r.Hosts {
+		seen := make(map[string]bool)
+		for _, issue := range report.Issues {
+			if !seen[issue.Rule] {
+				seen[issue.Rule] = true
+				result[issue.Rule] = append(result[issue.Rule], host)
+			}
+		}
+	}
+	for _, hosts := range result {
+		sort.Strings(hosts)
+	}
+	return result
+}
+
+// RunFleet parses the configuration of every target concurrently, lints
+// it and aggregates the results by host. Files read from the disk are
+// cached and shared between hosts, so include directories mounted into
+// every host are read once. Parse failures are reported per host, the
+// error is only for unknown rule names. Once ctx is done the remaining
+// hosts fail with ErrCanceled.
+func RunFleet(ctx context.Context, targets []*FleetTarget, options *FleetOptions) (*FleetReport, error) {
+	if options == nil {
+		options = &FleetOptions{}
+	}
+	selected, err := selectRules(options.Rules)
+	if err != nil {
+		return nil, err
+	}
+	selected = append(selected[:len(selected):len(selected)], options.Checks...)
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	reports := make([]*HostReport, len(targets))
+	cache := &fileCache{files: make(map[string]*cachedFile)}
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				reports[i] = runHost(ctx, targets[i], options.Parse, selected, cache)
+			}
+		}()
+	}
+	for i := range targets {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	report := &FleetReport{Hosts: make(map[string]*HostReport, len(reports))}
+	for _, host := range reports {
+		report.Hosts[host.Host] = host
+	}
+	return report, nil
+}
+
+func runHost(ctx context.Context, target *FleetTarget, template *ParseOptions, rules []*Rule, cache *fileCache) *HostReport {
+	options := &ParseOptions{}
+	if template != nil {
+		*options = *template
+	}
+	options.FS, options.Root = target.FS, target.Root
+	if options.Root == "" {
+		options.Root = filepath.Dir(target.File)
+		if target.FS != nil {
+			options.Root = path.Dir(target.File)
+		}
+	}
+	if options.FS == nil && options.Open == nil {
+		options.Open = cache.open
+	}
+
+	report := &HostReport{Host: target.Host, Issues: make([]*Issue, 0)}
+	directives, err := New(options).ParseFileContext(ctx, target.File)
+	if err != nil {
+		report.Err, report.Error = err, err.Error()
+		return report
+	}
+	report.Directives = directives
+	report.Issues = lintRules(directives, rules)
+	return report
+}
+
+// fileCache keeps the contents of the files read from the disk.
+type fileCache struct {
+	mu    sync.Mutex
+	files map[string]*cachedFile
+}
+
+type cachedFile struct {
+	once sync.Once
+	data []byte
+	err  error
+}
+
+func (c *fileCache) open(name string) (io.ReadCloser, error) {
+	key, err := filepath.Abs(name)
+	if err != nil {
+		key = name
+	}
+	c.mu.Lock()
+	file, ok := c.files[key]
+	if !ok {
+		file = &cachedFile{}
+		c.files[key] = file
+	}
+	c.mu.Unlock()
+	file.once.Do(func() {
+		file.data, file.err = os.ReadFile(name)
+	})
+	if file.err != nil {
+		return nil, file.err
+	}
+	return io.NopCloser(bytes.NewReader(file.data)), nil
+}
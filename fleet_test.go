@@ -0,0 +1,75 @@
+package nginxparser
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRunFleet(t *testing.T) {
+	dir := t.TempDir()
+	shared := filepath.Join(dir, "shared.conf")
+	if err := os.WriteFile(shared, []byte("location /evil {\n    if ($arg_x) {\n        proxy_pass http://backend;\n    }\n}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range []string{"a", "b"} {
+		if err := os.MkdirAll(filepath.Join(dir, host), 0700); err != nil {
+			t.Fatal(err)
+		}
+		config := "http {\n    server {\n        include " + shared + ";\n    }\n}\n"
+		if err := os.WriteFile(filepath.Join(dir, host, "nginx.conf"), []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	targets := []*FleetTarget{
+		{Host: "a", File: filepath.Join(dir, "a", "nginx.conf")},
+		{Host: "b", File: filepath.Join(dir, "b", "nginx.conf")},
+		{Host: "c", File: "etc/nginx/nginx.conf", FS: fstest.MapFS{
+			"etc/nginx/nginx.conf":    {Data: []byte("http {\n    include conf.d/*.conf;\n}\n")},
+			"etc/nginx/conf.d/x.conf": {Data: []byte("server {\n    listen 80;\n}\n")},
+		}},
+		{Host: "d", File: filepath.Join(dir, "missing.conf")},
+	}
+	custom := &Rule{Name: "servers", Check: func(directives []*Directive) []*Issue {
+		issues := make([]*Issue, 0)
+		for _, server := range servers(directives) {
+			issues = append(issues, newIssue("servers", server, "server found"))
+		}
+		return issues
+	}}
+
+	report, err := RunFleet(context.Background(), targets, &FleetOptions{Rules: []string{"if-is-evil"}, Checks: []*Rule{custom}, Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Hosts) != 4 {
+		t.Fatalf("expected a report for every host, got %v", report.Hosts)
+	}
+	if failed := report.Failed(); !reflect.DeepEqual(failed, []string{"d"}) || !errors.Is(report.Hosts["d"].Err, ErrNotFound) {
+		t.Fatalf("expected host d to fail, got %v", failed)
+	}
+	if issues := report.Hosts["c"].Issues; len(issues) != 1 || issues[0].Rule != "servers" || issues[0].FileName != "etc/nginx/conf.d/x.conf" {
+		t.Fatalf("unexpected issues for host c %v", issues)
+	}
+	expected := map[string][]string{"if-is-evil": {"a", "b"}, "servers": {"a", "b", "c"}}
+	if byRule := report.ByRule(); !reflect.DeepEqual(byRule, expected) {
+		t.Fatalf("expected %v but got %v", expected, byRule)
+	}
+
+	if _, err := RunFleet(context.Background(), targets, &FleetOptions{Rules: []string{"nope"}}); err == nil {
+		t.Fatal("expected an unknown rule to fail")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err = RunFleet(ctx, targets, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed := report.Failed(); len(failed) != 4 || !errors.Is(report.Hosts["a"].Err, ErrCanceled) {
+		t.Fatalf("expected every host to be canceled, got %v", failed)
+	}
+}
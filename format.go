@@ -0,0 +1,26 @@
+package nginxparser
+
+import (
+	"bytes"
+	"context"
+)
+
+// Format rewrites a configuration file with canonical indentation and
+// spacing, keeping its comments, quoting and line endings. Includes are
+// left as they are. Formatting its own output changes nothing.
+func Format(src []byte) ([]byte, error) {
+	p := New(&ParseOptions{SingleFile: true}).newParse(context.Background()).newParser("", nil)
+	reader, err := newSourceReader(context.Background(), bytes.NewReader(src), false)
+	if err != nil {
+		return nil, err
+	}
+	directives, err := p.parse(reader)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := NewEmitter(&EmitOptions{LineEnding: reader.lineEnding()}).Emit(&buf, directives); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
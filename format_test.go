@@ -0,0 +1,44 @@
+package nginxparser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	formatted, err := Format([]byte("http{\r\n  server { listen   80 ;# web\r\n\r\n  server_name\t\"example.com\";}\r\n}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "http {\r\n    server {\r\n        listen 80; # web\r\n        server_name \"example.com\";\r\n    }\r\n}\r\n"
+	if string(formatted) != expected {
+		t.Fatalf("expected %q but got %q", expected, formatted)
+	}
+
+	if _, err := Format([]byte("listen 80 }")); err == nil {
+		t.Fatal("expected a syntax error")
+	}
+
+	filenames, err := filepath.Glob(filepath.Join("testdata", "*", "*.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, filename := range filenames {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		once, err := Format(src)
+		if err != nil {
+			continue
+		}
+		twice, err := Format(once)
+		if err != nil {
+			t.Fatalf("%s: %s", filename, err)
+		}
+		if string(once) != string(twice) {
+			t.Fatalf("%s: formatting is not idempotent:\n%s\n%s", filename, once, twice)
+		}
+	}
+}
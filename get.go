@@ -0,0 +1,202 @@
+package nginxparser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// GetString returns the first value GetStrings finds for path, "" when
+// there is none.
+func GetString(directives []*Directive, path string) string {
+	if values := GetStrings(directives, path); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// GetStrings extracts values from a configuration by a dot separated path
+// of directive names, for example
+//
+//	http.server.#(server_name=="api.example.com").location.#(args.0=="/").proxy_pass.args.0
+//
+// A name selects the directives of that name in the blocks of the current
+// ones, with includes expanded, and * selects all of them. #(cond) keeps
+// the directives for which the path cond, relative to the directive, finds
+// a value equal (==) or not equal (!=) to a quoted or bare string, or finds
+// anything at all when no operator is given. args yields the arguments of
+// the directives, args.N the N-th one, and # the number of directives or
+// arguments. A path ending on directives yields their arguments joined by
+// spaces. Paths that match nothing, malformed ones included, yield nil.
+func GetStrings(directives []*Directive, path string) []string {
+	segments := splitGetPath(path)
+	if segments == nil {
+		return nil
+	}
+	_, values := getPath(directives, nil, segments)
+	return values
+}
+
+// getPath follows segments from current, or from the top-level directives
+// when current is nil, and returns the directives it ends on along with
+// their values.
+func getPath(directives []*Directive, current []*Directive, segments []string) ([]*Directive, []string) {
+	for i, segment := range segments {
+		switch {
+		case segment == "args":
+			return nil, getArgs(current, segments[i+1:])
+		case segment == "#":
+			if i != len(segments)-1 {
+				return nil, nil
+			}
+			return nil, []string{strconv.Itoa(len(current))}
+		case strings.HasPrefix(segment, "#("):
+			filtered := make([]*Directive, 0, len(current))
+			for _, d := range current {
+				if matchGetCondition(d, segment[2:len(segment)-1]) {
+					filtered = append(filtered, d)
+				}
+			}
+			current = filtered
+		default:
+			next := make([]*Directive, 0)
+			if current == nil {
+				current = []*Directive{{Block: directives}}
+			}
+			for _, d := range current {
+				for _, child := range children(d.Block) {
+					if segment == "*" || child.Directive == segment {
+						next = append(next, child)
+					}
+				}
+			}
+			current = next
+		}
+	}
+	values := make([]string, 0, len(current))
+	for _, d := range current {
+		values = append(values, strings.Join(d.Args, " "))
+	}
+	return current, values
+}
+
+func getArgs(current []*Directive, segments []string) []string {
+	values := make([]string, 0)
+	switch len(segments) {
+	case 0:
+		for _, d := range current {
+			values = append(values, d.Args...)
+		}
+	case 1:
+		if segments[0] == "#" {
+			for _, d := range current {
+				values = append(values, strconv.Itoa(len(d.Args)))
+			}
+			break
+		}
+		n, err := strconv.Atoi(segments[0])
+		if err != nil || n < 0 {
+			return nil
+		}
+		for _, d := range current {
+			if n < len(d.Args) {
+				values = append(values, d.Args[n])
+			}
+		}
+	default:
+		return nil
+	}
+	return values
+}
+
+func matchGetCondition(d *Directive, condition string) bool {
+	path, operator, operand := condition, "", ""
+	if i := indexUnquoted(condition, "=="); i >= 0 {
+		path, operator, operand = condition[:i], "==", condition[i+2:]
+	} else if i := indexUnquoted(condition, "!="); i >= 0 {
+		path, operator, operand = condition[:i], "!=", condition[i+2:]
+	}
+	segments := splitGetPath(strings.TrimSpace(path))
+	if segments == nil {
+		return false
+	}
+	found, values := getPath(nil, []*Directive{d}, segments)
+	if operator == "" {
+		return len(found) > 0 || len(values) > 0
+	}
+	if len(found) > 0 {
+		// Compare against every argument of directives, so server_name
+		// matches any of the names of a server.
+		values = getArgs(found, nil)
+	}
+	operand = strings.TrimSpace(operand)
+	if strings.HasPrefix(operand, `"`) {
+		unquoted, err := strconv.Unquote(operand)
+		if err != nil {
+			return false
+		}
+		operand = unquoted
+	}
+	equal := false
+	for _, value := range values {
+		if value == operand {
+			equal = true
+			break
+		}
+	}
+	return equal == (operator == "==")
+}
+
+// splitGetPath splits a path at the dots outside of conditions and quoted
+// strings, nil when it is malformed.
+func splitGetPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	segments := make([]string, 0)
+	depth, quoted, start := 0, false, 0
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return nil
+			}
+		case c == '.' && depth == 0:
+			segments = append(segments, path[start:i])
+			start = i + 1
+		}
+	}
+	if depth != 0 || quoted {
+		return nil
+	}
+	segments = append(segments, path[start:])
+	for _, segment := range segments {
+		if segment == "" || strings.HasPrefix(segment, "#(") != strings.HasSuffix(segment, ")") {
+			return nil
+		}
+	}
+	return segments
+}
+
+// indexUnquoted is strings.Index skipping quoted strings.
+func indexUnquoted(s, substr string) int {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quoted && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(s[i:], substr):
+			return i
+		}
+	}
+	return -1
+}
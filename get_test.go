@@ -0,0 +1,59 @@
+package nginxparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetStrings(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        server_name www.example.com;
+        location / {
+            proxy_pass http://www;
+        }
+    }
+    server {
+        server_name api.example.com api.example.net;
+        location /v1 {
+            proxy_pass http://v1;
+        }
+        location / {
+            proxy_pass http://api;
+            proxy_set_header Host "api.example.com";
+        }
+        location /internal {
+            internal;
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, expected := range map[string][]string{
+		`http.server.#(server_name=="api.example.com").location.#(args.0=="/").proxy_pass.args.0`: {"http://api"},
+		`http.server.#(server_name==api.example.net).location.#(args.0!="/").args.0`:              {"/v1", "/internal"},
+		`http.server.location.proxy_pass.args.0`:                                                  {"http://www", "http://v1", "http://api"},
+		`http.server.server_name`:                                                                 {"www.example.com", "api.example.com api.example.net"},
+		`http.server.server_name.args`:                                                            {"www.example.com", "api.example.com", "api.example.net"},
+		`http.server.location.#(internal).args.0`:                                                 {"/internal"},
+		`http.server.location.#(proxy_set_header.args.1=="api.example.com").args.0`:               {"/"},
+		`http.server.#`: {"2"},
+		`http.*.*.#`:    {"6"},
+		`http.server.location.proxy_set_header.args.#`: {"2"},
+		`http.missing`:   {},
+		`http.server.#(`: nil,
+		`http..server`:   nil,
+	} {
+		if got := GetStrings(directives, path); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %q but got %q", path, expected, got)
+		}
+	}
+	if got := GetString(directives, `http.server.#(server_name=="www.example.com").location.proxy_pass.args.0`); got != "http://www" {
+		t.Fatalf("unexpected value %q", got)
+	}
+	if got := GetString(directives, `http.server.#(server_name=="nope").server_name`); got != "" {
+		t.Fatalf("expected no value, got %q", got)
+	}
+}
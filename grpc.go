@@ -0,0 +1,99 @@
+package nginxparser
+
+import (
+	"strings"
+	"time"
+)
+
+const maxConnectTimeout = 75 * time.Second
+
+// CheckGRPC validates locations using grpc_pass: the server must accept
+// HTTP/2, timeouts must be valid and proxy_* settings, which the gRPC
+// module ignores, must not be mixed in. proxy_pass is not allowed in the
+// location, in the locations nested in it or in those around it.
+func CheckGRPC(directives []*Directive) []*Issue {
+	issues := make([]*Issue, 0)
+	report := func(d *Directive, format string, args ...interface{}) {
+		issues = append(issues, newIssue("grpc", d, format, args...))
+	}
+	// mixed holds the proxy_pass directives already reported.
+	mixed := make(map[*Directive]bool)
+
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive == "proxy_pass" && len(d.Args) > 0 && strings.HasPrefix(d.Args[0], "grpc") {
+			report(d, "proxy_pass cannot speak gRPC, use grpc_pass %s", d.Args[0])
+		}
+		if d.Directive != "location" {
+			return true
+		}
+		grpc := findFirst(d.Block, "grpc_pass")
+		if grpc == nil {
+			return true
+		}
+		for _, proxy := range locationTreeProxies(d, parents) {
+			if !mixed[proxy] {
+				mixed[proxy] = true
+				report(proxy, "location mixes proxy_pass with grpc_pass on line %d", grpc.Line)
+			}
+		}
+		for _, child := range children(d.Block) {
+			if strings.HasPrefix(child.Directive, "proxy_") && child.Directive != "proxy_pass" {
+				report(child, "%s has no effect on grpc_pass, use the grpc_ equivalent", child.Directive)
+			}
+		}
+		for _, name := range []string{"grpc_connect_timeout", "grpc_read_timeout", "grpc_send_timeout"} {
+			timeout := lookupInherited(name, d.Block, parents)
+			if timeout == nil || len(timeout.Args) == 0 {
+				continue
+			}
+			value, err := ParseDuration(timeout.Args[0])
+			switch {
+			case err != nil:
+				report(timeout, "invalid %s %q", name, timeout.Args[0])
+			case name == "grpc_connect_timeout" && value > maxConnectTimeout:
+				report(timeout, "grpc_connect_timeout usually cannot exceed %s", maxConnectTimeout)
+			}
+		}
+
+		server := enclosing("server", parents)
+		if server != nil && !serverHTTP2(server, parents) {
+			report(grpc, "grpc_pass requires HTTP/2 but the server enables it neither with \"http2 on\" nor on any listen")
+		}
+		return true
+	})
+	return issues
+}
+
+// locationTreeProxies returns the proxy_pass directives of location, of the
+// locations nested in it and of the locations around it.
+func locationTreeProxies(location *Directive, parents []*Directive) []*Directive {
+	proxies := make([]*Directive, 0)
+	for _, parent := range parents {
+		if parent.Directive == "location" {
+			proxies = append(proxies, findAll(parent.Block, "proxy_pass")...)
+		}
+	}
+	Walk(location.Block, func(d *Directive, parents []*Directive) bool {
+		if d.Directive == "proxy_pass" {
+			proxies = append(proxies, d)
+		}
+		return true
+	})
+	return proxies
+}
+
+func serverHTTP2(server *Directive, parents []*Directive) bool {
+	for _, listen := range serverListens(server) {
+		if listen.Has("http2") {
+			return true
+		}
+	}
+	var outer []*Directive
+	for i, parent := range parents {
+		if parent == server {
+			outer = parents[:i]
+		}
+	}
+	http2 := lookupInherited("http2", server.Block, outer)
+	return http2 != nil && len(http2.Args) > 0 && http2.Args[0] == "on"
+}
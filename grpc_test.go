@@ -0,0 +1,85 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestCheckGRPC(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        listen 443 ssl;
+        location /helloworld.Greeter/ {
+            grpc_pass grpc://backend;
+            proxy_read_timeout 1h;
+            grpc_connect_timeout 2m;
+        }
+        location /legacy/ {
+            proxy_pass grpcs://backend;
+        }
+    }
+    server {
+        listen 443 ssl;
+        http2 on;
+        location / {
+            grpc_pass grpc://backend;
+            grpc_read_timeout 1x;
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues := CheckGRPC(directives)
+	lines := []int{7, 8, 6, 11, 19}
+	if len(issues) != len(lines) {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	for i, issue := range issues {
+		if issue.Line != lines[i] {
+			t.Fatalf("unexpected issues %v", issues)
+		}
+	}
+}
+
+func TestCheckGRPCNestedLocations(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        http2 on;
+        location /helloworld.Greeter/ {
+            grpc_pass grpc://backend;
+            location /helloworld.Greeter/legacy/ {
+                proxy_pass http://legacy;
+            }
+        }
+        location /api/ {
+            proxy_pass http://api;
+            location /api/grpc/ {
+                grpc_pass grpc://backend;
+                location /api/grpc/nested/ {
+                    grpc_pass grpc://backend;
+                }
+            }
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues := CheckGRPC(directives)
+	messages := map[int]string{
+		8:  "location mixes proxy_pass with grpc_pass on line 6",
+		12: "location mixes proxy_pass with grpc_pass on line 14",
+	}
+	if len(issues) != len(messages) {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	for _, issue := range issues {
+		if messages[issue.Line] != issue.Message {
+			t.Fatalf("unexpected issue %v", issue)
+		}
+	}
+}
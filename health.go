@@ -0,0 +1,142 @@
+package nginxparser
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrBackendPreflight = errors.New("backend preflight failed")
+
+type BackendPreflightOptions struct {
+	// Backends is passed to Backends, set Resolve to probe every address a
+	// host name resolves to.
+	Backends *BackendOptions
+	// Probe checks one endpoint of a backend, a host:port pair or the path
+	// of a unix socket. It defaults to TCPProbe with Timeout, use HTTPProbe
+	// or a gRPC health check of your own for deeper checks. It is called
+	// from several goroutines at once.
+	Probe   func(backend *Backend, endpoint string) error
+	Timeout time.Duration
+}
+
+// TCPProbe returns a probe that connects to the endpoint.
+func TCPProbe(timeout time.Duration) func(backend *Backend, endpoint string) error {
+	return func(backend *Backend, endpoint string) error {
+		network := "tcp"
+		if backend.Unix {
+			network, endpoint = "unix", strings.TrimPrefix(endpoint, "unix:")
+		}
+		conn, err := net.DialTimeout(network, endpoint, timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// HTTPProbe returns a probe that requests path from the endpoint over
+// plain HTTP and fails on server errors. client defaults to
+// http.DefaultClient.
+func HTTPProbe(client *http.Client, path string) func(backend *Backend, endpoint string) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(backend *Backend, endpoint string) error {
+		if backend.Unix {
+			return TCPProbe(client.Timeout)(backend, endpoint)
+		}
+		resp, err := client.Get("http://" + endpoint + path)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("%s", resp.Status)
+		}
+		return nil
+	}
+}
+
+// CheckBackends probes every endpoint of the backends the configuration
+// proxies to and reports those that do not answer. Targets built from
+// variables are skipped. Endpoints are probed concurrently, once each.
+func CheckBackends(directives []*Directive, options *BackendPreflightOptions) ([]*Issue, error) {
+	if options == nil {
+		options = &BackendPreflightOptions{}
+	}
+	probe := options.Probe
+	if probe == nil {
+		timeout := options.Timeout
+		if timeout == 0 {
+			timeout = 3 * time.Second
+		}
+		probe = TCPProbe(timeout)
+	}
+	backends, err := Backends(directives, options.Backends)
+	if err != nil {
+		return nil, err
+	}
+
+	type check struct {
+		backend  *Backend
+		endpoint string
+		err      error
+	}
+	checks := make([]*check, 0)
+	seen := make(map[string]bool)
+	for _, backend := range backends {
+		if backend.Variables {
+			continue
+		}
+		for _, endpoint := range backend.Endpoints() {
+			if !seen[endpoint] {
+				seen[endpoint] = true
+				checks = append(checks, &check{backend: backend, endpoint: endpoint})
+			}
+		}
+	}
+	var wg sync.WaitGroup
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c *check) {
+			defer wg.Done()
+			c.err = probe(c.backend, c.endpoint)
+		}(c)
+	}
+	wg.Wait()
+
+	issues := make([]*Issue, 0)
+	for _, c := range checks {
+		if c.err == nil {
+			continue
+		}
+		name := c.endpoint
+		if c.backend.Upstream != "" {
+			name = fmt.Sprintf("%s in upstream %s", c.endpoint, c.backend.Upstream)
+		}
+		issues = append(issues, newIssue("backend-reachable", c.backend.Directive, "backend %s is unreachable: %s", name, c.err))
+	}
+	return issues, nil
+}
+
+// PreflightBackends fails with ErrBackendPreflight when CheckBackends
+// finds an unreachable backend, meant to gate promoting a configuration.
+func PreflightBackends(directives []*Directive, options *BackendPreflightOptions) error {
+	issues, err := CheckBackends(directives, options)
+	if err != nil {
+		return err
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		messages = append(messages, issue.String())
+	}
+	return fmt.Errorf("%w: %s", ErrBackendPreflight, strings.Join(messages, "; "))
+}
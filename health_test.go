@@ -0,0 +1,91 @@
+package nginxparser
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheckBackends(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := closed.Addr().String()
+	closed.Close()
+
+	directives, err := New(&ParseOptions{}).ParseString(fmt.Sprintf(`http {
+    upstream app {
+        server %s;
+        server %s;
+    }
+    server {
+        location / { proxy_pass http://app; }
+        location /api { proxy_pass http://%s; }
+        location /dynamic { proxy_pass http://$backend; }
+    }
+}
+`, listener.Addr(), down, listener.Addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := CheckBackends(directives, &BackendPreflightOptions{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Rule != "backend-reachable" || issues[0].Line != 4 || !strings.Contains(issues[0].Message, "upstream app") {
+		t.Fatalf("unexpected issues %+v", issues)
+	}
+	if err := PreflightBackends(directives, nil); !errors.Is(err, ErrBackendPreflight) {
+		t.Fatalf("expected a preflight error, got %v", err)
+	}
+
+	var mu sync.Mutex
+	probed := make([]string, 0)
+	probe := func(backend *Backend, endpoint string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		probed = append(probed, endpoint)
+		return nil
+	}
+	if err := PreflightBackends(directives, &BackendPreflightOptions{Probe: probe}); err != nil {
+		t.Fatal(err)
+	}
+	if len(probed) != 2 {
+		t.Fatalf("expected every endpoint to be probed once, got %v", probed)
+	}
+}
+
+func TestHTTPProbe(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	probe := HTTPProbe(nil, "/healthz")
+	backend := &Backend{}
+	endpoint := strings.TrimPrefix(server.URL, "http://")
+	if err := probe(backend, endpoint); err != nil {
+		t.Fatal(err)
+	}
+	status = http.StatusBadGateway
+	if err := probe(backend, endpoint); err == nil {
+		t.Fatal("expected server errors to fail the probe")
+	}
+}
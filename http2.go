@@ -0,0 +1,105 @@
+package nginxparser
+
+import (
+	"strings"
+)
+
+var (
+	versionHTTP3        = MustParseVersion("1.25.0")
+	versionHTTP2Toggle  = MustParseVersion("1.25.1")
+	versionSSLEarlyData = MustParseVersion("1.15.3")
+)
+
+// CheckHTTPVersions validates the HTTP/2 and HTTP/3 setup of every server
+// against the given nginx version, the zero Version meaning the latest.
+func CheckHTTPVersions(directives []*Directive, version Version) []*Issue {
+	issues := make([]*Issue, 0)
+	report := func(d *Directive, format string, args ...interface{}) {
+		issues = append(issues, newIssue("http-versions", d, format, args...))
+	}
+
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		switch d.Directive {
+		case "http2":
+			if version.Before(versionHTTP2Toggle) {
+				report(d, "the http2 directive is not available before nginx %s, use the http2 parameter of listen", versionHTTP2Toggle)
+			}
+		case "http2_push", "http2_push_preload":
+			if !version.Before(versionHTTP2Toggle) {
+				report(d, "%s is obsolete since nginx %s and ignored", d.Directive, versionHTTP2Toggle)
+			}
+		case "http3", "http3_hq", "quic_retry", "quic_gso", "quic_host_key":
+			if version.Before(versionHTTP3) {
+				report(d, "%s is not available before nginx %s", d.Directive, versionHTTP3)
+			}
+		case "ssl_early_data":
+			if version.Before(versionSSLEarlyData) {
+				report(d, "ssl_early_data is not available before nginx %s", versionSSLEarlyData)
+			}
+		}
+		return true
+	})
+
+	for _, server := range servers(directives) {
+		tcp := make(map[string]bool)
+		quic := make([]*Listen, 0)
+		for _, listen := range serverListens(server) {
+			if listen.Has("quic") {
+				quic = append(quic, listen)
+				if version.Before(versionHTTP3) {
+					report(listen.Directive, "the quic parameter of listen is not available before nginx %s", versionHTTP3)
+				}
+				continue
+			}
+			tcp[listen.Port] = true
+			if listen.Has("http2") && !version.Before(versionHTTP2Toggle) {
+				report(listen.Directive, "the http2 parameter of listen is deprecated since nginx %s, use \"http2 on;\" instead", versionHTTP2Toggle)
+			}
+		}
+
+		if len(quic) > 0 && !hasAltSvc(server) {
+			report(quic[0].Directive, "server listens for QUIC but never advertises it, add an Alt-Svc header such as add_header Alt-Svc 'h3=\":%s\"; ma=86400'", quic[0].Port)
+		}
+		for _, listen := range quic {
+			if !tcp[listen.Port] {
+				report(listen.Directive, "QUIC port %s has no TCP listener, clients cannot discover HTTP/3 without one", listen.Port)
+			}
+		}
+
+		earlyData := findFirst(server.Block, "ssl_early_data")
+		if earlyData != nil && len(earlyData.Args) > 0 && earlyData.Args[0] == "on" && !passesEarlyData(server) {
+			report(earlyData, "ssl_early_data allows replayed requests, pass $ssl_early_data to the backend with proxy_set_header Early-Data $ssl_early_data")
+		}
+	}
+	return issues
+}
+
+func hasAltSvc(server *Directive) bool {
+	found := false
+	Walk([]*Directive{server}, func(d *Directive, parents []*Directive) bool {
+		if d.Directive == "add_header" && len(d.Args) > 0 && strings.EqualFold(d.Args[0], "Alt-Svc") {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func passesEarlyData(server *Directive) bool {
+	found := false
+	Walk([]*Directive{server}, func(d *Directive, parents []*Directive) bool {
+		if strings.HasSuffix(d.Directive, "_set_header") || d.Directive == "fastcgi_param" {
+			for _, arg := range d.Args {
+				if strings.Contains(arg, "$ssl_early_data") {
+					found = true
+				}
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+func checkHTTPVersions(directives []*Directive) []*Issue {
+	return CheckHTTPVersions(directives, Version{})
+}
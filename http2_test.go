@@ -0,0 +1,42 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestCheckHTTPVersions(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        listen 443 ssl http2;
+        listen 8443 quic reuseport;
+        http2_push /style.css;
+        ssl_early_data on;
+    }
+    server {
+        listen 443 ssl;
+        listen 443 quic;
+        http2 on;
+        add_header Alt-Svc 'h3=":443"; ma=86400';
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues := CheckHTTPVersions(directives, Version{})
+	lines := []int{6, 4, 5, 5, 7}
+	if len(issues) != len(lines) {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	for i, issue := range issues {
+		if issue.Line != lines[i] {
+			t.Fatalf("unexpected issues %v", issues)
+		}
+	}
+
+	issues = CheckHTTPVersions(directives, MustParseVersion("1.24.0"))
+	if len(issues) != 6 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+}
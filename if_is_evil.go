@@ -0,0 +1,32 @@
+package nginxparser
+
+// safeInLocationIf are the only directives that are safe inside an if
+// block in location context, see https://www.nginx.com/resources/wiki/start/topics/depth/ifisevil/
+var safeInLocationIf = map[string]bool{
+	"return": true,
+}
+
+func isSafeIfDirective(d *Directive) bool {
+	if safeInLocationIf[d.Directive] {
+		return true
+	}
+	return d.Directive == "rewrite" && len(d.Args) > 2 && d.Args[len(d.Args)-1] == "last"
+}
+
+// CheckIfIsEvil flags every directive inside an if block in location
+// context other than return and rewrite ... last.
+func CheckIfIsEvil(directives []*Directive) []*Issue {
+	issues := make([]*Issue, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive != "if" || enclosing("location", parents) == nil {
+			return true
+		}
+		for _, child := range children(d.Block) {
+			if !isSafeIfDirective(child) {
+				issues = append(issues, newIssue("if-is-evil", child, "%s inside if in location context is unsafe, only return and rewrite ... last are, see if block on line %d", child.Directive, d.Line))
+			}
+		}
+		return true
+	})
+	return issues
+}
@@ -0,0 +1,34 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestCheckIfIsEvil(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        if ($host = old.example.com) {
+            set $legacy 1;
+        }
+        location / {
+            if ($request_method = POST) {
+                return 405;
+            }
+            if ($arg_v = 2) {
+                rewrite ^ /v2$uri last;
+                rewrite ^ /v3$uri break;
+                proxy_pass http://backend;
+            }
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues := CheckIfIsEvil(directives)
+	if len(issues) != 2 || issues[0].Line != 13 || issues[1].Line != 14 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+}
@@ -0,0 +1,367 @@
+package nginxparser
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+func (p *parser) include(d *Directive) error {
+	for _, arg := range d.Args {
+		arg, err := p.includePattern(arg, p.filename)
+		if err != nil {
+			return p.wrapError(ErrInclude, err)
+		}
+		filenames, err := p.options.Glob(arg)
+		if err != nil {
+			return p.wrapError(ErrGlob, err)
+		}
+		if len(filenames) == 0 {
+			if err := p.missingInclude(d, arg); err != nil {
+				return err
+			}
+		}
+		if p.options.RecordIncludes {
+			d.IncludeFiles = append(d.IncludeFiles, filenames...)
+			continue
+		}
+		files := make([]string, 0, len(filenames))
+		for _, filename := range filenames {
+			if p.index != nil {
+				includes := append(p.includes[:len(p.includes):len(p.includes)], fmt.Sprintf("%s:%d", p.filename, d.Line))
+				d.Includes = append(d.Includes, p.index.add(filename, includes))
+				continue
+			}
+			files = append(files, filename)
+		}
+		if err := p.parseIncludes(d, files); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseIncludes parses the files matched by one pattern of the include d
+// and appends their directives to its block in the order of filenames.
+// With ParseOptions.IncludeConcurrency the files are parsed at the same
+// time as long as workers are free, the others in the calling goroutine.
+func (p *parser) parseIncludes(d *Directive, filenames []string) error {
+	blocks := make([][]*Directive, len(filenames))
+	errs := make([]error, len(filenames))
+	// parsed is false for the files skipped with CatchErrors.
+	parsed := make([]bool, len(filenames))
+	parse := func(i int, parser *parser) {
+		blocks[i], errs[i] = parser.parseFile()
+		if errs[i] != nil && p.options.CatchErrors && p.ctx.Err() == nil {
+			blocks[i], errs[i] = nil, nil
+			return
+		}
+		if errs[i] == nil {
+			setIncludedBy(blocks[i], nil, d)
+			p.share(filenames[i], blocks[i])
+			parsed[i] = true
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, filename := range filenames {
+		if shared, ok := p.sharedFile(filename); ok && p.options.ShareIncludes {
+			blocks[i], parsed[i] = shared, true
+			continue
+		}
+		if cycle := p.includeCycle(filename); cycle != nil {
+			errs[i] = p.wrapError(ErrInclude, fmt.Errorf("%w: %s", ErrIncludeCycle, strings.Join(cycle, " -> ")))
+			break
+		}
+		if len(p.includes) >= p.options.MaxIncludeDepth {
+			errs[i] = p.wrapError(ErrInclude, fmt.Errorf("%w: %s:%d includes %s %d levels deep", ErrIncludeDepth, p.filename, d.Line, filename, len(p.includes)+1))
+			break
+		}
+		parser := p.newParser(filename, append(p.includes[:len(p.includes):len(p.includes)], fmt.Sprintf("%s:%d", p.filename, d.Line)))
+		select {
+		case p.workers <- struct{}{}:
+			wg.Add(1)
+			go func(i int) {
+				defer func() {
+					<-p.workers
+					wg.Done()
+				}()
+				parse(i, parser)
+			}(i)
+		default:
+			parse(i, parser)
+		}
+	}
+	wg.Wait()
+
+	for i := range filenames {
+		if errs[i] != nil {
+			return errs[i]
+		}
+	}
+	for i, filename := range filenames {
+		if parsed[i] {
+			d.Block = append(d.Block, blocks[i]...)
+			d.pulled = append(d.pulled, filename)
+		}
+	}
+	return nil
+}
+
+func (p *parser) sharedFile(filename string) ([]*Directive, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	directives, ok := p.shared[filename]
+	return directives, ok
+}
+
+func (p *parser) share(filename string, directives []*Directive) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.shared[filename] = directives
+}
+
+// RelativeTo is the directory relative include patterns are resolved
+// against.
+type RelativeTo int
+
+const (
+	// RelativeToRoot resolves them against ParseOptions.Root, as nginx
+	// does with its configuration directory.
+	RelativeToRoot RelativeTo = iota
+	// RelativeToIncluder resolves them against the directory of the file
+	// containing the include, falling back to Root for configurations
+	// parsed from a reader.
+	RelativeToIncluder
+)
+
+// includePattern resolves the pattern of an include in the file includer.
+func (s *parseState) includePattern(pattern, includer string) (string, error) {
+	switch {
+	case strings.HasPrefix(pattern, "/"):
+		return pattern, nil
+	case s.options.IncludeRelativeTo == RelativeToIncluder && includer != "":
+		return path.Join(path.Dir(includer), pattern), nil
+	case s.root == "":
+		return "", fmt.Errorf("not found `root` dir in options")
+	}
+	return path.Join(s.root, pattern), nil
+}
+
+// ResolveInclude parses the files an include directive recorded with
+// ParseOptions.RecordIncludes, or those its patterns match when it recorded
+// none, with the options of p and makes them its Block. The includes of
+// those files are recorded in turn, to be resolved when needed. Resolving
+// an include again parses its files again, unless ParseOptions.ShareIncludes
+// shares them.
+func (d *Directive) ResolveInclude(p *Parser) error {
+	if d.Directive != "include" {
+		return fmt.Errorf("%s is not an include directive", d.Directive)
+	}
+	state := p.resolveParse()
+	includes := make([]string, 0)
+	for _, include := range d.IncludeChain() {
+		includes = append(includes, fmt.Sprintf("%s:%d", include.FileName, include.Line))
+	}
+	parser := state.newParser(d.FileName, includes)
+
+	filenames := d.IncludeFiles
+	if filenames == nil {
+		for _, arg := range d.Args {
+			arg, err := state.includePattern(arg, d.FileName)
+			if err != nil {
+				return parser.wrapError(ErrInclude, err)
+			}
+			matches, err := p.options.Glob(arg)
+			if err != nil {
+				return parser.wrapError(ErrGlob, err)
+			}
+			filenames = append(filenames, matches...)
+		}
+	}
+	block, pulled := d.Block, d.pulled
+	d.Block, d.pulled = make([]*Directive, 0), nil
+	if err := parser.parseIncludes(d, filenames); err != nil {
+		d.Block, d.pulled = block, pulled
+		return err
+	}
+	setParents(d.Block, d.Parent)
+	return nil
+}
+
+// resolveParse returns the parse ResolveInclude adds files to: the last
+// parse of p, without its context, or a new one.
+func (p *Parser) resolveParse() *parseState {
+	if last := p.lastParse(); last != nil {
+		state := *last
+		state.ctx = context.Background()
+		return &state
+	}
+	state := p.newParse(context.Background())
+	if p.options.Prefix != "" || p.options.ConfPath != "" {
+		_, state.root = p.options.nginxPaths("")
+	}
+	p.finish(state)
+	return state
+}
+
+// MissingInclude is what the parser does with an include matching no
+// files.
+type MissingInclude int
+
+const (
+	// MissingIncludeIgnore leaves the block of the include empty.
+	MissingIncludeIgnore MissingInclude = iota
+	// MissingIncludeWarn also records a warning in Parser.Warnings.
+	MissingIncludeWarn
+	// MissingIncludeError fails like nginx with ErrNotFound when the
+	// include names a file that does not exist, and warns when a glob
+	// matches nothing. With CatchErrors the missing file is marked failed
+	// in Files and parsing goes on.
+	MissingIncludeError
+)
+
+func (p *parser) missingInclude(d *Directive, pattern string) error {
+	if p.options.OnMissingInclude == MissingIncludeError && !strings.ContainsAny(pattern, "*?[") {
+		err := &ParseError{
+			Kind:     ErrNotFound,
+			FileName: p.filename,
+			Line:     d.Line,
+			Includes: p.includes,
+			Err:      &fs.PathError{Op: "open", Path: pattern, Err: fs.ErrNotExist},
+		}
+		if !p.options.CatchErrors {
+			return err
+		}
+		p.record(pattern, nil, err)
+		return nil
+	}
+	if p.options.OnMissingInclude != MissingIncludeIgnore {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.warnings = append(p.warnings, newIssue("missing-include", d, "include %s matches no files", pattern))
+	}
+	return nil
+}
+
+// Warnings returns the problems the last parse noticed without failing,
+// see ParseOptions.OnMissingInclude.
+func (p *Parser) Warnings() []*Issue {
+	last := p.lastParse()
+	if last == nil || last.warnings == nil {
+		return []*Issue{}
+	}
+	return last.warnings
+}
+
+// includeCycle returns the files from the one including filename up to
+// the file being parsed, followed by filename, when filename is already
+// being parsed, nil otherwise.
+func (p *parser) includeCycle(filename string) []string {
+	files := make([]string, 0, len(p.includes)+1)
+	for _, include := range p.includes {
+		files = append(files, include[:strings.LastIndexByte(include, ':')])
+	}
+	files = append(files, p.filename)
+	for i, file := range files {
+		if path.Clean(file) == path.Clean(filename) {
+			return append(files[i:], filename)
+		}
+	}
+	return nil
+}
+
+// Unshare gives an include directive a private deep copy of its block, so
+// it can be modified without affecting the other places the same file was
+// included when ParseOptions.ShareIncludes is set.
+func Unshare(include *Directive) {
+	include.Block = cloneDirectives(include.Block)
+}
+
+func cloneDirectives(directives []*Directive) []*Directive {
+	if directives == nil {
+		return nil
+	}
+	result := make([]*Directive, 0, len(directives))
+	for _, d := range directives {
+		clone := *d
+		clone.Args = append(make([]string, 0, len(d.Args)), d.Args...)
+		if d.Quotes != nil {
+			clone.Quotes = append(make([]Quote, 0, len(d.Quotes)), d.Quotes...)
+		}
+		if d.ArgPositions != nil {
+			clone.ArgPositions = append(make([]Position, 0, len(d.ArgPositions)), d.ArgPositions...)
+		}
+		if d.LeadingComments != nil {
+			clone.LeadingComments = append(make([]string, 0, len(d.LeadingComments)), d.LeadingComments...)
+		}
+		clone.Block = cloneDirectives(d.Block)
+		if d.Directive == "include" {
+			setIncludedBy(clone.Block, d, &clone)
+			setParents(clone.Block, clone.Parent)
+		} else {
+			setParents(clone.Block, &clone)
+		}
+		if d.Provenance != nil {
+			provenance := *d.Provenance
+			clone.Provenance = &provenance
+		}
+		if d.Includes != nil {
+			clone.Includes = append(make([]int, 0, len(d.Includes)), d.Includes...)
+		}
+		if d.IncludeFiles != nil {
+			clone.IncludeFiles = append(make([]string, 0, len(d.IncludeFiles)), d.IncludeFiles...)
+		}
+		if d.pulled != nil {
+			clone.pulled = append(make([]string, 0, len(d.pulled)), d.pulled...)
+		}
+		if d.Annotations != nil {
+			clone.Annotations = cloneAnnotations(d.Annotations)
+		}
+		result = append(result, &clone)
+	}
+	return result
+}
+
+func cloneAnnotations(annotations *Annotations) *Annotations {
+	clone := *annotations
+	if annotations.Disable != nil {
+		clone.Disable = append(make([]string, 0, len(annotations.Disable)), annotations.Disable...)
+	}
+	if annotations.Enable != nil {
+		clone.Enable = append(make([]string, 0, len(annotations.Enable)), annotations.Enable...)
+	}
+	if annotations.Labels != nil {
+		clone.Labels = make(map[string]string, len(annotations.Labels))
+		for key, value := range annotations.Labels {
+			clone.Labels[key] = value
+		}
+	}
+	return &clone
+}
+
+// setIncludedBy points the directives of block that were included by from,
+// and their descendants, to include.
+func setIncludedBy(block []*Directive, from, include *Directive) {
+	for _, d := range block {
+		if d.IncludedBy == from {
+			d.IncludedBy = include
+		}
+		setIncludedBy(d.Block, from, include)
+	}
+}
+
+// IncludeChain returns the include directives that pulled d in, starting
+// from the one in the main file. It is empty for directives of the main
+// file. With ParseOptions.ShareIncludes a file included more than once
+// reports the first include.
+func (d *Directive) IncludeChain() []*Directive {
+	chain := make([]*Directive, 0)
+	for include := d.IncludedBy; include != nil; include = include.IncludedBy {
+		chain = append([]*Directive{include}, chain...)
+	}
+	return chain
+}
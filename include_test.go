@@ -0,0 +1,406 @@
+package nginxparser
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestShareIncludes(t *testing.T) {
+	root := filepath.Join("testdata", "includes-globbed")
+	find := func(directives []*Directive) []*Directive {
+		includes := make([]*Directive, 0)
+		Walk(directives, func(d *Directive, parents []*Directive) bool {
+			if d.Directive == "include" && len(d.Args) == 1 && d.Args[0] == "locations/*.conf" {
+				includes = append(includes, d)
+			}
+			return true
+		})
+		return includes
+	}
+
+	directives, err := New(&ParseOptions{Root: root}).ParseFile(filepath.Join(root, "nginx.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if includes := find(directives); len(includes) != 2 || includes[0].Block[0] == includes[1].Block[0] {
+		t.Fatal("expected separate include blocks")
+	}
+
+	directives, err = New(&ParseOptions{Root: root, ShareIncludes: true}).ParseFile(filepath.Join(root, "nginx.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	includes := find(directives)
+	if len(includes) != 2 || len(includes[0].Block) != 2 || includes[0].Block[0] != includes[1].Block[0] {
+		t.Fatal("expected shared include blocks")
+	}
+
+	Unshare(includes[0])
+	includes[0].Block[0].Args[0] = "/changed"
+	if includes[1].Block[0].Args[0] != "/foo" {
+		t.Fatal("expected unshared include block to be a copy")
+	}
+}
+
+func TestClone(t *testing.T) {
+	root := filepath.Join("testdata", "includes-regular")
+	directives, err := New(&ParseOptions{Root: root}).ParseFile(filepath.Join(root, "nginx.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	http := directives[1]
+	http.SetLabel("team", "edge")
+
+	clone := http.Clone()
+	if clone == http || clone.Parent != nil {
+		t.Fatal("expected a detached copy")
+	}
+	if clone.String() != http.String() {
+		t.Fatalf("expected the same configuration, got\n%s", clone.String())
+	}
+	server := clone.Block[0].Block[0]
+	if server.Parent != clone {
+		t.Fatal("expected the included server to belong to the copied http block")
+	}
+	server.Args = append(server.Args, "changed")
+	server.Block[0].Args[0] = "127.0.0.1:9090"
+	clone.SetLabel("team", "core")
+	if original := http.Block[0].Block[0]; len(original.Args) != 0 || original.Block[0].Args[0] != "127.0.0.1:8080" {
+		t.Fatal("expected the original tree to be left unchanged")
+	}
+	if team, _ := http.Label("team"); team != "edge" {
+		t.Fatalf("expected the original label to be kept, got %q", team)
+	}
+
+	all := CloneAll(directives)
+	if len(all) != len(directives) || all[1] == http || all[1].Block[0].Block[0].Parent != all[1] {
+		t.Fatal("expected CloneAll to copy every directive")
+	}
+}
+
+func TestIncludeChain(t *testing.T) {
+	root := filepath.Join("testdata", "includes-regular")
+	directives, err := New(&ParseOptions{Root: root}).ParseFile(filepath.Join(root, "nginx.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	http := directives[1]
+	serverInclude := http.Block[0]
+	server := serverInclude.Block[0]
+	fooInclude := server.Block[2]
+	ret := fooInclude.Block[0].Block[0]
+
+	if len(http.IncludeChain()) != 0 {
+		t.Fatal("expected no chain in the main file")
+	}
+	if chain := server.Block[0].IncludeChain(); len(chain) != 1 || chain[0] != serverInclude {
+		t.Fatalf("unexpected chain %v", chain)
+	}
+	chain := ret.IncludeChain()
+	if len(chain) != 2 || chain[0] != serverInclude || chain[1] != fooInclude {
+		t.Fatalf("unexpected chain %v", chain)
+	}
+
+	clone := http.Clone()
+	cloned := clone.Block[0].Block[0].Block[2].Block[0].Block[0].IncludeChain()
+	if len(cloned) != 2 || cloned[0] != clone.Block[0] || cloned[1] != clone.Block[0].Block[0].Block[2] {
+		t.Fatal("expected the copy to point to the copied includes")
+	}
+}
+
+func TestMaxIncludeDepth(t *testing.T) {
+	fsys := fstest.MapFS{
+		"nginx.conf": {Data: []byte("http {\n    include 1.conf;\n}\n")},
+	}
+	for i := 1; i <= DefaultMaxIncludeDepth+1; i++ {
+		fsys[fmt.Sprintf("%d.conf", i)] = &fstest.MapFile{Data: []byte(fmt.Sprintf("include %d.conf;\n", i+1))}
+	}
+	_, err := New(&ParseOptions{FS: fsys, Root: ".", MaxIncludeDepth: 3}).ParseFile("nginx.conf")
+	if !errors.Is(err, ErrInclude) || !errors.Is(err, ErrIncludeDepth) {
+		t.Fatalf("expected the include depth to be exceeded, got %v", err)
+	}
+	expected := "include depth exceeded: 3.conf:1 includes 4.conf 4 levels deep (included from nginx.conf:2 -> 1.conf:1 -> 2.conf:1)"
+	if err.Error() != expected {
+		t.Fatalf("expected %q but got %q", expected, err.Error())
+	}
+
+	_, err = New(&ParseOptions{FS: fsys, Root: "."}).ParseFile("nginx.conf")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || len(parseErr.Includes) != DefaultMaxIncludeDepth {
+		t.Fatalf("expected the default depth to stop the recursion, got %v", err)
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"nginx.conf": {Data: []byte("http {\n    include a.conf;\n}\n")},
+		"a.conf":     {Data: []byte("include b.conf;\n")},
+		"b.conf":     {Data: []byte("include ./a.conf;\n")},
+		"self.conf":  {Data: []byte("include self.conf;\n")},
+		"twice.conf": {Data: []byte("include c.conf;\ninclude c.conf;\n")},
+		"c.conf":     {Data: []byte("worker_processes 1;\n")},
+	}
+	_, err := New(&ParseOptions{FS: fsys, Root: "."}).ParseFile("nginx.conf")
+	if !errors.Is(err, ErrInclude) || !errors.Is(err, ErrIncludeCycle) {
+		t.Fatalf("expected an include cycle, got %v", err)
+	}
+	expected := "include cycle: a.conf -> b.conf -> a.conf (included from nginx.conf:2 -> a.conf:1)"
+	if err.Error() != expected {
+		t.Fatalf("expected %q but got %q", expected, err.Error())
+	}
+
+	if _, err := New(&ParseOptions{FS: fsys, Root: "."}).ParseFile("self.conf"); err == nil || !strings.HasPrefix(err.Error(), "include cycle: self.conf -> self.conf") {
+		t.Fatalf("expected a file including itself to fail, got %v", err)
+	}
+	if _, err := New(&ParseOptions{FS: fsys, Root: "."}).ParseFile("twice.conf"); err != nil {
+		t.Fatalf("including a file twice is not a cycle, got %v", err)
+	}
+}
+
+func TestOnMissingInclude(t *testing.T) {
+	fsys := fstest.MapFS{
+		"nginx.conf":   {Data: []byte("http {\n    include conf.d/*.conf;\n    include missing.conf;\n    include a.conf;\n}\n")},
+		"a.conf":       {Data: []byte("include gone.conf;\n")},
+		"conf.d/.keep": {Data: []byte{}},
+	}
+
+	parser := New(&ParseOptions{FS: fsys, Root: "."})
+	if _, err := parser.ParseFile("nginx.conf"); err != nil || len(parser.Warnings()) != 0 {
+		t.Fatalf("expected missing includes to be ignored, got %v %v", err, parser.Warnings())
+	}
+
+	parser = New(&ParseOptions{FS: fsys, Root: ".", OnMissingInclude: MissingIncludeWarn})
+	if _, err := parser.ParseFile("nginx.conf"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"nginx.conf:2: include conf.d/*.conf matches no files (missing-include)",
+		"nginx.conf:3: include missing.conf matches no files (missing-include)",
+		"a.conf:1: include gone.conf matches no files (missing-include)",
+	}
+	warnings := parser.Warnings()
+	if len(warnings) != len(expected) {
+		t.Fatalf("unexpected warnings %v", warnings)
+	}
+	for i, warning := range warnings {
+		if warning.String() != expected[i] {
+			t.Fatalf("expected %q but got %q", expected[i], warning)
+		}
+	}
+
+	parser = New(&ParseOptions{FS: fsys, Root: ".", OnMissingInclude: MissingIncludeError})
+	_, err := parser.ParseFile("nginx.conf")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || !errors.Is(err, ErrNotFound) || parseErr.FileName != "nginx.conf" || parseErr.Line != 3 {
+		t.Fatalf("expected missing.conf not to be found, got %v", err)
+	}
+	if len(parser.Warnings()) != 1 {
+		t.Fatalf("expected the empty glob to warn, got %v", parser.Warnings())
+	}
+
+	parser = New(&ParseOptions{FS: fsys, Root: ".", OnMissingInclude: MissingIncludeError, CatchErrors: true})
+	if _, err := parser.ParseFile("nginx.conf"); err != nil {
+		t.Fatal(err)
+	}
+	files := parser.Files()
+	if files["missing.conf"] == nil || !errors.Is(files["missing.conf"].Err, ErrNotFound) || files["gone.conf"] == nil || files["a.conf"].Err != nil {
+		t.Fatalf("expected the missing files to be marked failed, got %v", files)
+	}
+}
+
+func TestRecordIncludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"nginx.conf":       {Data: []byte("http {\n    include conf.d/*.conf;\n    include missing.conf;\n}\n")},
+		"conf.d/a.conf":    {Data: []byte("server {\n    include broken.conf;\n}\n")},
+		"conf.d/b.conf":    {Data: []byte("server {\n")},
+		"conf.d/readme.md": {Data: []byte("not a config\n")},
+	}
+	parser := New(&ParseOptions{FS: fsys, Root: ".", RecordIncludes: true, OnMissingInclude: MissingIncludeWarn})
+	directives, err := parser.ParseFile("nginx.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	includes := directives[0].Block
+	if len(includes) != 2 || len(includes[0].Block) != 0 || !equalStrings(includes[0].IncludeFiles, []string{"conf.d/a.conf", "conf.d/b.conf"}) {
+		t.Fatalf("expected the matched files to be recorded, got %+v", includes)
+	}
+	if includes[1].IncludeFiles != nil || len(parser.Warnings()) != 1 {
+		t.Fatalf("expected the missing include to warn, got %v", parser.Warnings())
+	}
+	if files := parser.Files(); len(files) != 1 || files["nginx.conf"] == nil {
+		t.Fatalf("expected only the main file to be read, got %v", files)
+	}
+	if clone := includes[0].Clone(); !equalStrings(clone.IncludeFiles, includes[0].IncludeFiles) {
+		t.Fatalf("expected clones to keep the recorded files, got %v", clone.IncludeFiles)
+	}
+}
+
+func TestResolveInclude(t *testing.T) {
+	fsys := fstest.MapFS{
+		"nginx.conf":        {Data: []byte("http {\n    include sites/*.conf;\n    include loop.conf;\n}\n")},
+		"sites/a.conf":      {Data: []byte("server {\n    include snippets/tls.conf;\n}\n")},
+		"snippets/tls.conf": {Data: []byte("ssl_protocols TLSv1.3;\n")},
+		"loop.conf":         {Data: []byte("include loop.conf;\n")},
+	}
+	parser := New(&ParseOptions{FS: fsys, Root: ".", RecordIncludes: true})
+	directives, err := parser.ParseFile("nginx.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	http := directives[0]
+	if err := http.Block[0].ResolveInclude(parser); err != nil {
+		t.Fatal(err)
+	}
+	server := http.Block[0].Block[0]
+	if server.Directive != "server" || server.Parent != http || server.IncludedBy != http.Block[0] || server.FileName != "sites/a.conf" {
+		t.Fatalf("unexpected resolved server %+v", server)
+	}
+	nested := server.Block[0]
+	if len(nested.Block) != 0 || !equalStrings(nested.IncludeFiles, []string{"snippets/tls.conf"}) {
+		t.Fatalf("expected the nested include to be recorded, got %+v", nested)
+	}
+	if err := nested.ResolveInclude(parser); err != nil {
+		t.Fatal(err)
+	}
+	protocols := nested.Block[0]
+	if protocols.Parent != server || !equalStrings(ancestorNames(protocols), []string{"http", "server"}) || len(protocols.IncludeChain()) != 2 {
+		t.Fatalf("unexpected resolved directive %+v", protocols)
+	}
+
+	loop := http.Block[1]
+	if err := loop.ResolveInclude(parser); err != nil {
+		t.Fatal(err)
+	}
+	if err := loop.Block[0].ResolveInclude(parser); !errors.Is(err, ErrIncludeCycle) {
+		t.Fatalf("expected an include cycle, got %v", err)
+	}
+	if err := http.ResolveInclude(parser); err == nil {
+		t.Fatal("expected resolving a block to fail")
+	}
+
+	directives, err = New(&ParseOptions{FS: fsys, Root: ".", SingleFile: true}).ParseFile("nginx.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := directives[0].Block[0].ResolveInclude(New(&ParseOptions{FS: fsys, Root: ".", RecordIncludes: true})); err != nil || len(directives[0].Block[0].Block) != 1 {
+		t.Fatalf("expected the patterns to be globbed, got %v", err)
+	}
+}
+
+func ancestorNames(d *Directive) []string {
+	names := make([]string, 0)
+	for _, ancestor := range d.Ancestors() {
+		names = append(names, ancestor.Directive)
+	}
+	return names
+}
+
+func TestPrefixConfPath(t *testing.T) {
+	fsys := fstest.MapFS{
+		"etc/nginx/conf/nginx.conf":               {Data: []byte("http {\n    include conf.d/*.conf;\n}\n")},
+		"etc/nginx/conf/conf.d/a.conf":            {Data: []byte("server {\n    include snippets/tls.conf;\n}\n")},
+		"etc/nginx/conf/snippets/tls.conf":        {Data: []byte("ssl_protocols TLSv1.3;\n")},
+		"etc/nginx/conf/conf.d/snippets/tls.conf": {Data: []byte("ssl_protocols TLSv1;\n")},
+	}
+	protocols := func(directives []*Directive) string {
+		return GetString(directives, "http.server.ssl_protocols")
+	}
+
+	for _, options := range []*ParseOptions{
+		{FS: fsys, Prefix: "etc/nginx"},
+		{FS: fsys, Prefix: "etc/nginx", ConfPath: "conf/nginx.conf"},
+		{FS: fsys, Prefix: "/var/empty", ConfPath: "/etc/nginx/conf/nginx.conf"},
+	} {
+		directives, err := New(options).ParseFile("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if protocols(directives) != "TLSv1.3" {
+			t.Fatalf("expected includes relative to the configuration directory, got %s", directives[0])
+		}
+	}
+
+	parser := New(&ParseOptions{FS: fsys, Prefix: "etc/nginx"})
+	directives, err := parser.ParseFile("conf/nginx.conf")
+	if err != nil || protocols(directives) != "TLSv1.3" {
+		t.Fatalf("expected the file to be taken relative to the prefix, got %v", err)
+	}
+	if _, ok := parser.Files()["etc/nginx/conf/conf.d/a.conf"]; !ok {
+		t.Fatalf("unexpected files %v", parser.Files())
+	}
+
+	directives, err = New(&ParseOptions{FS: fsys, Prefix: "etc/nginx"}).ParseString("http {\n    include conf.d/a.conf;\n}\n")
+	if err != nil || protocols(directives) != "TLSv1.3" {
+		t.Fatalf("expected the default configuration directory, got %v", err)
+	}
+}
+
+func TestIncludeRelativeTo(t *testing.T) {
+	fsys := fstest.MapFS{
+		"nginx.conf":              {Data: []byte("http {\n    include sites/*.conf;\n}\n")},
+		"sites/a.conf":            {Data: []byte("server {\n    include snippets/tls.conf;\n}\n")},
+		"sites/snippets/tls.conf": {Data: []byte("ssl_protocols TLSv1.3;\n")},
+		"snippets/tls.conf":       {Data: []byte("ssl_protocols TLSv1;\n")},
+	}
+	for expected, relativeTo := range map[string]RelativeTo{"TLSv1": RelativeToRoot, "TLSv1.3": RelativeToIncluder} {
+		directives, err := New(&ParseOptions{FS: fsys, Root: ".", IncludeRelativeTo: relativeTo}).ParseFile("nginx.conf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if protocols := GetString(directives, "http.server.ssl_protocols"); protocols != expected {
+			t.Fatalf("expected %s but got %s", expected, protocols)
+		}
+	}
+
+	parser := New(&ParseOptions{FS: fsys, IncludeRelativeTo: RelativeToIncluder, RecordIncludes: true})
+	directives, err := parser.ParseFile("sites/a.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files := directives[0].Block[0].IncludeFiles; !equalStrings(files, []string{"sites/snippets/tls.conf"}) {
+		t.Fatalf("expected the include next to the file, got %v", files)
+	}
+	if _, err := New(&ParseOptions{FS: fsys, IncludeRelativeTo: RelativeToIncluder}).ParseString("include snippets/tls.conf;"); !errors.Is(err, ErrInclude) {
+		t.Fatalf("expected a reader without root to fail, got %v", err)
+	}
+}
+
+func TestIncludeConcurrency(t *testing.T) {
+	fsys := fstest.MapFS{
+		"nginx.conf":        {Data: []byte("http {\n    include conf.d/*.conf;\n}\n")},
+		"snippets/ssl.conf": {Data: []byte("ssl_protocols TLSv1.3;\n")},
+	}
+	for i := 0; i < 50; i++ {
+		fsys[fmt.Sprintf("conf.d/%02d.conf", i)] = &fstest.MapFile{Data: []byte(fmt.Sprintf("server {\n    server_name host%d;\n    include snippets/*.conf;\n}\n", i))}
+	}
+	serial, err := New(&ParseOptions{FS: fsys, Root: "."}).ParseFile("nginx.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := New(&ParseOptions{FS: fsys, Root: ".", IncludeConcurrency: 8})
+	directives, err := parser.ParseFile("nginx.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(serial, directives) {
+		t.Fatal("expected the same tree as a serial parse")
+	}
+	if names := GetStrings(directives, "http.server.server_name"); len(names) != 50 || names[0] != "host0" || names[49] != "host49" {
+		t.Fatalf("expected the servers in order, got %v", names)
+	}
+	if len(parser.Files()) != 52 {
+		t.Fatalf("expected 52 files but got %d", len(parser.Files()))
+	}
+
+	fsys["conf.d/10.conf"] = &fstest.MapFile{Data: []byte("}\nserver {}\n")}
+	fsys["conf.d/40.conf"] = &fstest.MapFile{Data: []byte("}\nserver {}\n")}
+	_, err = New(&ParseOptions{FS: fsys, Root: ".", IncludeConcurrency: 8}).ParseFile("nginx.conf")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.FileName != "conf.d/10.conf" {
+		t.Fatalf("expected the error of the first failing file, got %v", err)
+	}
+}
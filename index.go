@@ -0,0 +1,55 @@
+package nginxparser
+
+// includeIndex numbers the files pulled in by include directives when
+// ParseOptions.IndexIncludes is set. Files are parsed once each, in the
+// order they were first included.
+type includeIndex struct {
+	files    []string
+	includes [][]string
+	index    map[string]int
+}
+
+func newIncludeIndex(filename string) *includeIndex {
+	return &includeIndex{
+		files:    []string{filename},
+		includes: [][]string{nil},
+		index:    map[string]int{filename: 0},
+	}
+}
+
+func (x *includeIndex) add(filename string, includes []string) int {
+	if i, ok := x.index[filename]; ok {
+		return i
+	}
+	x.index[filename] = len(x.files)
+	x.files = append(x.files, filename)
+	x.includes = append(x.includes, includes)
+	return len(x.files) - 1
+}
+
+func (p *parser) parseIndexed() error {
+	for i := 1; i < len(p.index.files); i++ {
+		if _, err := p.newParser(p.index.files[i], p.index.includes[i]).parseFile(); err != nil && (!p.options.CatchErrors || p.ctx.Err() != nil) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Configs returns the files read by the last parse in include index order
+// when ParseOptions.IndexIncludes is set. The Includes of an include
+// directive are positions in this slice, the first entry being the file
+// passed to the parser.
+func (p *Parser) Configs() []*FileResult {
+	result := make([]*FileResult, 0)
+	last := p.lastParse()
+	if last == nil || last.index == nil {
+		return result
+	}
+	for _, filename := range last.index.files {
+		if file, ok := last.files[filename]; ok {
+			result = append(result, file)
+		}
+	}
+	return result
+}
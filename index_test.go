@@ -0,0 +1,68 @@
+package nginxparser
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIndexIncludes(t *testing.T) {
+	root := filepath.Join("testdata", "includes-regular")
+	parser := New(&ParseOptions{Root: root, IndexIncludes: true})
+	directives, err := parser.ParseFile(filepath.Join(root, "nginx.conf"))
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	include := directives[1].Block[0]
+	if include.Block != nil || !reflect.DeepEqual(include.Includes, []int{1}) {
+		t.Fatalf("unexpected include %+v", include)
+	}
+
+	configs := parser.Configs()
+	names := make([]string, 0, len(configs))
+	for _, config := range configs {
+		names = append(names, strings.TrimPrefix(filepath.ToSlash(config.FileName), "testdata/includes-regular/"))
+	}
+	if expected := []string{"nginx.conf", "conf.d/server.conf", "foo.conf"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected %v but got %v", expected, names)
+	}
+	server := configs[1].Directives[0]
+	if !reflect.DeepEqual(server.Block[2].Includes, []int{2}) || server.Block[3].Includes != nil {
+		t.Fatalf("unexpected server %+v", server.Block)
+	}
+}
+
+func TestIndexIncludesTwice(t *testing.T) {
+	files := map[string]string{
+		"/etc/nginx/nginx.conf":  "http { server { include /etc/nginx/common.conf; } server { include /etc/nginx/common.conf; } }",
+		"/etc/nginx/common.conf": "gzip on;",
+	}
+	opened := 0
+	parser := New(&ParseOptions{
+		IndexIncludes: true,
+		Glob:          func(pattern string) ([]string, error) { return []string{pattern}, nil },
+		Open: func(name string) (io.ReadCloser, error) {
+			opened++
+			s, ok := files[name]
+			if !ok {
+				return nil, os.ErrNotExist
+			}
+			return io.NopCloser(strings.NewReader(s)), nil
+		},
+	})
+	directives, err := parser.ParseFile("/etc/nginx/nginx.conf")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if opened != 2 || len(parser.Configs()) != 2 {
+		t.Fatalf("expected each file read once, got %d reads and %d configs", opened, len(parser.Configs()))
+	}
+	for _, server := range directives[0].Block {
+		if !reflect.DeepEqual(server.Block[0].Includes, []int{1}) {
+			t.Fatalf("unexpected include %+v", server.Block[0])
+		}
+	}
+}
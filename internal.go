@@ -0,0 +1,149 @@
+package nginxparser
+
+import (
+	"strings"
+)
+
+type InternalLocation struct {
+	Location   *Directive   `json:"-"`
+	Server     *Directive   `json:"-"`
+	Internal   bool         `json:"internal"`
+	References []*Directive `json:"-"`
+}
+
+type InternalAuditOptions struct {
+	// AccelRedirects are URIs that backends send in X-Accel-Redirect
+	// headers, which nginx follows as internal redirects.
+	AccelRedirects []string
+}
+
+// InternalLocations lists the locations that are marked internal or are
+// the target of an internal redirect: error_page, auth_request, mirror,
+// try_files fallbacks, rewrites and X-Accel-Redirect responses.
+func InternalLocations(directives []*Directive, options *InternalAuditOptions) []*InternalLocation {
+	if options == nil {
+		options = &InternalAuditOptions{}
+	}
+	result := make([]*InternalLocation, 0)
+	index := make(map[*Directive]*InternalLocation)
+	reference := func(server, location, d *Directive) {
+		if location == nil {
+			return
+		}
+		if modifier, _ := locationPattern(location); modifier == locationNamed {
+			return
+		}
+		entry := index[location]
+		if entry == nil {
+			entry = &InternalLocation{
+				Location:   location,
+				Server:     server,
+				Internal:   findFirst(location.Block, "internal") != nil,
+				References: make([]*Directive, 0),
+			}
+			index[location] = entry
+			result = append(result, entry)
+		}
+		if d != nil {
+			entry.References = append(entry.References, d)
+		}
+	}
+
+	for _, server := range servers(directives) {
+		Walk(server.Block, func(d *Directive, parents []*Directive) bool {
+			if d.Directive == "location" && findFirst(d.Block, "internal") != nil {
+				reference(server, d, nil)
+			}
+			return true
+		})
+		for _, uri := range options.AccelRedirects {
+			reference(server, FindLocation(server, uri), nil)
+		}
+	}
+	for _, auth := range AuthRequests(directives) {
+		reference(auth.Server, auth.Target, auth.Directive)
+	}
+	for _, page := range ErrorPages(directives) {
+		reference(page.Server, page.Location, page.Directive)
+	}
+	for _, mirror := range Mirrors(directives) {
+		for _, target := range mirror.Targets {
+			reference(mirror.Server, target.Location, mirror.Location)
+		}
+	}
+	for _, redirect := range internalRedirects(directives) {
+		reference(redirect.server, FindLocation(redirect.server, redirect.uri), redirect.directive)
+	}
+	return result
+}
+
+type internalRedirect struct {
+	server    *Directive
+	directive *Directive
+	uri       string
+}
+
+// internalRedirects collects the literal URIs that rewrite and try_files
+// redirect to inside a server. Targets containing variables are skipped.
+func internalRedirects(directives []*Directive) []*internalRedirect {
+	result := make([]*internalRedirect, 0)
+	for _, server := range servers(directives) {
+		Walk(server.Block, func(d *Directive, parents []*Directive) bool {
+			uri := ""
+			switch d.Directive {
+			case "rewrite":
+				if len(d.Args) < 2 || (len(d.Args) > 2 && (d.Args[2] == "redirect" || d.Args[2] == "permanent")) {
+					return true
+				}
+				uri = d.Args[1]
+			case "try_files":
+				if len(d.Args) < 2 {
+					return true
+				}
+				uri = d.Args[len(d.Args)-1]
+			default:
+				return true
+			}
+			if i := strings.IndexByte(uri, '?'); i >= 0 {
+				uri = uri[:i]
+			}
+			if strings.HasPrefix(uri, "/") && len(Variables(uri)) == 0 {
+				result = append(result, &internalRedirect{server: server, directive: d, uri: uri})
+			}
+			return true
+		})
+	}
+	return result
+}
+
+// CheckInternalLocations flags public locations that rewrite into internal
+// ones, and locations reached through error_page or X-Accel-Redirect that
+// are missing the internal flag. auth_request and mirror targets are
+// covered by their own rules.
+func CheckInternalLocations(directives []*Directive, options *InternalAuditOptions) []*Issue {
+	issues := make([]*Issue, 0)
+	for _, entry := range InternalLocations(directives, options) {
+		if entry.Internal {
+			for _, ref := range entry.References {
+				if ref.Directive == "rewrite" || ref.Directive == "try_files" {
+					issues = append(issues, newIssue("internal-location", ref, "%s redirects into internal location on line %d", ref.Directive, entry.Location.Line))
+				}
+			}
+			continue
+		}
+		for _, ref := range entry.References {
+			if ref.Directive == "error_page" {
+				issues = append(issues, newIssue("internal-location", entry.Location, "location is the target of error_page on line %d but is not internal", ref.Line))
+				break
+			}
+		}
+		if len(entry.References) == 0 {
+			issues = append(issues, newIssue("internal-location", entry.Location, "location serves X-Accel-Redirect responses but is not internal"))
+		}
+	}
+	return issues
+}
+
+func checkInternalLocations(directives []*Directive) []*Issue {
+	return CheckInternalLocations(directives, nil)
+}
@@ -0,0 +1,47 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestInternalLocations(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        error_page 404 /404.html;
+        location / {
+            rewrite ^/old$ /protected/file last;
+            proxy_pass http://backend;
+        }
+        location /protected/ {
+            internal;
+            alias /data/;
+        }
+        location = /404.html {
+            root /srv/errors;
+        }
+        location /downloads/ {
+            alias /data/downloads/;
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locations := InternalLocations(directives, &InternalAuditOptions{AccelRedirects: []string{"/downloads/a.zip"}})
+	if len(locations) != 3 {
+		t.Fatalf("expected 3 internal locations but got %d", len(locations))
+	}
+
+	issues := CheckInternalLocations(directives, &InternalAuditOptions{AccelRedirects: []string{"/downloads/a.zip"}})
+	lines := []int{6, 16, 13}
+	if len(issues) != len(lines) {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	for i, issue := range issues {
+		if issue.Line != lines[i] {
+			t.Fatalf("unexpected issues %v", issues)
+		}
+	}
+}
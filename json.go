@@ -0,0 +1,32 @@
+package nginxparser
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// MarshalJSON writes an empty block as [] rather than leaving it out, so
+// server {} and a directive without a block read back as they were.
+func (d *Directive) MarshalJSON() ([]byte, error) {
+	type directive Directive
+	v := struct {
+		*directive
+		Block *[]*Directive `json:"block,omitempty"`
+	}{directive: (*directive)(d)}
+	if d.Block != nil {
+		v.Block = &d.Block
+	}
+	return json.Marshal(v)
+}
+
+// LoadJSON reads directives marshaled to JSON back into a tree, with
+// parents set. What is not marshaled, such as quoting and the source
+// layout, is lost and the emitter falls back to its defaults.
+func LoadJSON(r io.Reader) ([]*Directive, error) {
+	directives := make([]*Directive, 0)
+	if err := json.NewDecoder(r).Decode(&directives); err != nil {
+		return nil, err
+	}
+	SetParents(directives)
+	return directives, nil
+}
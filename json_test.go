@@ -0,0 +1,41 @@
+package nginxparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadJSON(t *testing.T) {
+	root := filepath.Join("testdata", "messy")
+	directives, err := New(&ParseOptions{Root: root}).ParseFile(filepath.Join(root, "nginx.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(directives)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadJSON(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(directives, loaded) {
+		t.Fatal("expected the tree to survive a JSON round trip")
+	}
+	server := loaded[3].FirstChild("server")
+	if server == nil || server.Parent != loaded[3] {
+		t.Fatal("expected parents to be set")
+	}
+	empty, err := LoadJSON(bytes.NewReader([]byte(`[{"directive":"server","block":[]},{"directive":"server"}]`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty[0].Block == nil || empty[1].Block != nil {
+		t.Fatal("expected an empty block to differ from no block")
+	}
+	if _, err := LoadJSON(bytes.NewReader([]byte(`{`))); err == nil {
+		t.Fatal("expected invalid JSON to fail")
+	}
+}
@@ -0,0 +1,169 @@
+package nginxparser
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultKeepaliveTimeout  = 75 * time.Second
+	defaultKeepaliveRequests = 1000
+	// defaultUpstreamKeepaliveTimeout is the keepalive_timeout of upstream
+	// blocks.
+	defaultUpstreamKeepaliveTimeout = 60 * time.Second
+)
+
+// ClientKeepalive is how long and for how many requests nginx keeps the
+// connections of clients on a listener open.
+type ClientKeepalive struct {
+	Server *Directive `json:"-"`
+	Listen string     `json:"listen"`
+	// Timeout is 0 when keep-alive is disabled.
+	Timeout       time.Duration `json:"timeout"`
+	HeaderTimeout time.Duration `json:"header_timeout,omitempty"`
+	Requests      int           `json:"requests"`
+	// ResetTimedout is set by reset_timedout_connection.
+	ResetTimedout bool `json:"reset_timedout,omitempty"`
+	Reuse         bool `json:"reuse"`
+}
+
+// UpstreamKeepalive is the cache of idle connections nginx keeps to the
+// servers of an upstream, and the *_pass directives that prevent their
+// reuse.
+type UpstreamKeepalive struct {
+	Upstream    *Directive    `json:"-"`
+	Name        string        `json:"name"`
+	Connections int           `json:"connections"`
+	Timeout     time.Duration `json:"timeout,omitempty"`
+	Requests    int           `json:"requests,omitempty"`
+	Reuse       bool          `json:"reuse"`
+	// Blocking lists the *_pass directives that send the upstream
+	// requests on connections that cannot be reused.
+	Blocking []*Directive `json:"-"`
+	// Reasons holds why each of Blocking prevents reuse.
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// ClientKeepalives reports the client connection reuse of every listener
+// of every http server.
+func ClientKeepalives(directives []*Directive) []*ClientKeepalive {
+	result := make([]*ClientKeepalive, 0)
+	for _, server := range servers(directives) {
+		parents := parentsOf(directives, server)
+		keepalive := &ClientKeepalive{Server: server, Timeout: defaultKeepaliveTimeout, Requests: defaultKeepaliveRequests}
+		if d := lookupInherited("keepalive_timeout", server.Block, parents); d != nil && len(d.Args) > 0 {
+			keepalive.Timeout, _ = ParseDuration(d.Args[0])
+			if len(d.Args) > 1 {
+				keepalive.HeaderTimeout, _ = ParseDuration(d.Args[1])
+			}
+		}
+		if d := lookupInherited("keepalive_requests", server.Block, parents); d != nil && len(d.Args) > 0 {
+			keepalive.Requests, _ = strconv.Atoi(d.Args[0])
+		}
+		if d := lookupInherited("reset_timedout_connection", server.Block, parents); d != nil && len(d.Args) > 0 {
+			keepalive.ResetTimedout = d.Args[0] == "on"
+		}
+		keepalive.Reuse = keepalive.Timeout > 0 && keepalive.Requests > 1
+
+		listens := serverListens(server)
+		if len(listens) == 0 {
+			listens = append(listens, &Listen{Port: "80"})
+		}
+		for _, listen := range listens {
+			l := *keepalive
+			l.Listen = listen.Port
+			if listen.Address != "" {
+				l.Listen = listen.Address + ":" + listen.Port
+			}
+			result = append(result, &l)
+		}
+	}
+	return result
+}
+
+// UpstreamKeepalives reports the connection reuse of every upstream with
+// a keepalive cache. Proxied connections are reused only over HTTP/1.1
+// with the Connection header cleared, FastCGI ones with fastcgi_keep_conn
+// on.
+func UpstreamKeepalives(directives []*Directive) []*UpstreamKeepalive {
+	result := make([]*UpstreamKeepalive, 0)
+	byName := make(map[string]*UpstreamKeepalive)
+	for _, upstream := range upstreams(directives) {
+		d := findFirst(upstream.Block, "keepalive")
+		if d == nil || len(d.Args) == 0 {
+			continue
+		}
+		keepalive := &UpstreamKeepalive{Upstream: upstream, Name: upstreamName(upstream), Timeout: defaultUpstreamKeepaliveTimeout, Requests: defaultKeepaliveRequests, Blocking: make([]*Directive, 0)}
+		keepalive.Connections, _ = strconv.Atoi(d.Args[0])
+		if d := findFirst(upstream.Block, "keepalive_timeout"); d != nil && len(d.Args) > 0 {
+			keepalive.Timeout, _ = ParseDuration(d.Args[0])
+		}
+		if d := findFirst(upstream.Block, "keepalive_requests"); d != nil && len(d.Args) > 0 {
+			keepalive.Requests, _ = strconv.Atoi(d.Args[0])
+		}
+		result = append(result, keepalive)
+		byName[keepalive.Name] = keepalive
+	}
+
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if !passDirectives[d.Directive] || len(d.Args) == 0 {
+			return true
+		}
+		_, host, _ := splitPassTarget(d.Args[0])
+		keepalive, ok := byName[host]
+		if !ok {
+			return true
+		}
+		reason := ""
+		switch d.Directive {
+		case "proxy_pass":
+			version := lookupInherited("proxy_http_version", nil, parents)
+			connection := "close"
+			for _, header := range lookupInheritedAll("proxy_set_header", nil, parents) {
+				if len(header.Args) == 2 && strings.EqualFold(header.Args[0], "Connection") {
+					connection = strings.ToLower(header.Args[1])
+				}
+			}
+			switch {
+			case version == nil || len(version.Args) == 0 || version.Args[0] == "1.0":
+				reason = "proxy_http_version is 1.0"
+			case connection != "" && connection != "keep-alive":
+				reason = "the Connection header sent is " + strconv.Quote(connection) + ", clear it with proxy_set_header Connection \"\""
+			}
+		case "fastcgi_pass":
+			if keepConn := lookupInherited("fastcgi_keep_conn", nil, parents); keepConn == nil || len(keepConn.Args) == 0 || keepConn.Args[0] != "on" {
+				reason = "fastcgi_keep_conn is off"
+			}
+		}
+		if reason != "" {
+			keepalive.Blocking = append(keepalive.Blocking, d)
+			keepalive.Reasons = append(keepalive.Reasons, reason)
+		}
+		return true
+	})
+	for _, keepalive := range result {
+		keepalive.Reuse = keepalive.Connections > 0 && keepalive.Requests > 1 && keepalive.Timeout > 0 && len(keepalive.Blocking) == 0
+	}
+	return result
+}
+
+func checkKeepalive(directives []*Directive) []*Issue {
+	issues := make([]*Issue, 0)
+	reported := make(map[*Directive]bool)
+	for _, keepalive := range ClientKeepalives(directives) {
+		if keepalive.Timeout > 0 && keepalive.Requests <= 1 && !reported[keepalive.Server] {
+			reported[keepalive.Server] = true
+			issues = append(issues, newIssue("keepalive", keepalive.Server, "keepalive_requests %d closes every client connection after one request although keepalive_timeout is %s", keepalive.Requests, keepalive.Timeout))
+		}
+	}
+	for _, keepalive := range UpstreamKeepalives(directives) {
+		for i, d := range keepalive.Blocking {
+			issues = append(issues, newIssue("keepalive", d, "connections to upstream %s are never reused although it sets keepalive %d: %s", keepalive.Name, keepalive.Connections, keepalive.Reasons[i]))
+		}
+		if keepalive.Requests <= 1 {
+			issues = append(issues, newIssue("keepalive", keepalive.Upstream, "keepalive_requests %d in upstream %s closes every connection after one request", keepalive.Requests, keepalive.Name))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,71 @@
+package nginxparser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeepalive(t *testing.T) {
+	directives, err := New(&ParseOptions{}).ParseString(`http {
+    keepalive_timeout 30s 25s;
+    reset_timedout_connection on;
+    upstream app {
+        server 10.0.0.1:8080;
+        keepalive 16;
+    }
+    upstream php {
+        server unix:/run/php.sock;
+        keepalive 8;
+    }
+    server {
+        listen 80;
+        listen 127.0.0.1:8080;
+        location / {
+            proxy_http_version 1.1;
+            proxy_set_header Connection "";
+            proxy_pass http://app;
+        }
+        location /legacy {
+            proxy_pass http://app;
+        }
+        location ~ \.php$ {
+            fastcgi_pass php;
+        }
+    }
+    server {
+        listen 81;
+        keepalive_requests 1;
+    }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients := ClientKeepalives(directives)
+	if len(clients) != 3 {
+		t.Fatalf("expected 3 listeners, got %d", len(clients))
+	}
+	if c := clients[1]; c.Listen != "127.0.0.1:8080" || c.Timeout != 30*time.Second || c.HeaderTimeout != 25*time.Second || !c.ResetTimedout || !c.Reuse {
+		t.Fatalf("unexpected listener %+v", c)
+	}
+	if c := clients[2]; c.Requests != 1 || c.Reuse {
+		t.Fatalf("expected reuse to be disabled, got %+v", c)
+	}
+
+	upstreams := UpstreamKeepalives(directives)
+	if len(upstreams) != 2 {
+		t.Fatalf("expected 2 upstreams, got %d", len(upstreams))
+	}
+	app, php := upstreams[0], upstreams[1]
+	if app.Connections != 16 || app.Timeout != 60*time.Second || app.Reuse || len(app.Blocking) != 1 || app.Blocking[0].Line != 21 {
+		t.Fatalf("unexpected upstream %+v", app)
+	}
+	if php.Reuse || len(php.Blocking) != 1 || php.Reasons[0] != "fastcgi_keep_conn is off" {
+		t.Fatalf("unexpected upstream %+v", php)
+	}
+
+	issues := checkKeepalive(directives)
+	if len(issues) != 3 || issues[0].Line != 27 || issues[1].Line != 21 || issues[2].Line != 24 {
+		t.Fatalf("unexpected issues %+v", issues)
+	}
+}
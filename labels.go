@@ -0,0 +1,109 @@
+package nginxparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SetLabel labels a directive, replacing the value of an existing label.
+// Labels set this way are not written by the emitter, use a
+// "# nginx-parser: label=key=value" comment to keep them in the file.
+func (d *Directive) SetLabel(key, value string) {
+	if d.Annotations == nil {
+		d.Annotations = &Annotations{}
+	}
+	if d.Annotations.Labels == nil {
+		d.Annotations.Labels = make(map[string]string)
+	}
+	d.Annotations.Labels[key] = value
+}
+
+// Label returns the value of a label and whether the directive has it.
+func (d *Directive) Label(key string) (string, bool) {
+	if d.Annotations == nil {
+		return "", false
+	}
+	value, ok := d.Annotations.Labels[key]
+	return value, ok
+}
+
+func (d *Directive) RemoveLabel(key string) {
+	if d.Annotations != nil {
+		delete(d.Annotations.Labels, key)
+	}
+}
+
+type requirement struct {
+	key    string
+	value  string
+	negate bool
+	// exists is set for requirements on the key alone.
+	exists bool
+}
+
+// Selector matches directives by their labels.
+type Selector []requirement
+
+// ParseSelector reads a comma separated list of requirements that must all
+// hold: "key" and "!key" test whether a label is set, "key=value" and
+// "key!=value" compare its value. An empty selector matches everything.
+func ParseSelector(s string) (Selector, error) {
+	selector := make(Selector, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var r requirement
+		switch {
+		case strings.Contains(part, "!="):
+			i := strings.Index(part, "!=")
+			r = requirement{key: part[:i], value: part[i+2:], negate: true}
+		case strings.Contains(part, "="):
+			i := strings.IndexByte(part, '=')
+			r = requirement{key: part[:i], value: strings.TrimPrefix(part[i+1:], "=")}
+		case strings.HasPrefix(part, "!"):
+			r = requirement{key: part[1:], negate: true, exists: true}
+		default:
+			r = requirement{key: part, exists: true}
+		}
+		r.key, r.value = strings.TrimSpace(r.key), strings.TrimSpace(r.value)
+		if r.key == "" || strings.ContainsAny(r.key, "!=") {
+			return nil, fmt.Errorf("invalid selector requirement %q", part)
+		}
+		selector = append(selector, r)
+	}
+	return selector, nil
+}
+
+// Matches reports whether the labels of d satisfy every requirement.
+func (s Selector) Matches(d *Directive) bool {
+	for _, r := range s {
+		value, ok := d.Label(r.key)
+		matched := ok
+		if !r.exists {
+			matched = ok && value == r.value
+		}
+		if matched == r.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// Select returns the directives matching a label selector, in the order
+// Walk visits them.
+func Select(directives []*Directive, selector string) ([]*Directive, error) {
+	s, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Directive, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive != "#" && s.Matches(d) {
+			result = append(result, d)
+		}
+		return true
+	})
+	return result, nil
+}
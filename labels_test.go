@@ -0,0 +1,59 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    # nginx-parser: label=generated labels=experiment=a,frozen
+    server {
+        location / {} # nginx-parser: label=experiment=b
+        location /static {}
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := directives[0].Block[1]
+	if value, ok := server.Label("experiment"); !ok || value != "a" {
+		t.Fatalf("unexpected label %q %v", value, ok)
+	}
+	server.Block[2].SetLabel("experiment", "c")
+
+	expected := map[string][]*Directive{
+		"generated":              {server},
+		"frozen, experiment=a":   {server},
+		"experiment":             {server, server.Block[0], server.Block[2]},
+		"experiment!=a":          {directives[0], server.Block[0], server.Block[2]},
+		"experiment,!frozen":     {server.Block[0], server.Block[2]},
+		"experiment==b":          {server.Block[0]},
+		"experiment=c,generated": {},
+		"":                       {directives[0], server, server.Block[0], server.Block[2]},
+	}
+	for selector, want := range expected {
+		got, err := Select(directives, selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%q: expected %d directives, got %d", selector, len(want), len(got))
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("%q: unexpected directive %s at %d", selector, got[i].Directive, got[i].Line)
+			}
+		}
+	}
+
+	server.RemoveLabel("frozen")
+	if _, ok := server.Label("frozen"); ok {
+		t.Fatal("expected the label to be removed")
+	}
+	for _, selector := range []string{"=a", "!", "a!b=c"} {
+		if _, err := ParseSelector(selector); err == nil {
+			t.Fatalf("expected an error for %q", selector)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package nginxparser
+
+import (
+	"fmt"
+	"sort"
+)
+
+type Issue struct {
+	Rule     string `json:"rule"`
+	FileName string `json:"filename"`
+	Line     int    `json:"line"`
+	// Column is set when the issue is about one argument.
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (i *Issue) String() string {
+	if i.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s (%s)", i.FileName, i.Line, i.Column, i.Message, i.Rule)
+	}
+	return fmt.Sprintf("%s:%d: %s (%s)", i.FileName, i.Line, i.Message, i.Rule)
+}
+
+func newIssue(rule string, d *Directive, format string, args ...interface{}) *Issue {
+	return &Issue{
+		Rule:     rule,
+		FileName: d.FileName,
+		Line:     d.Line,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// newArgIssue points the issue at the i-th argument of d when its position
+// is known.
+func newArgIssue(rule string, d *Directive, i int, format string, args ...interface{}) *Issue {
+	issue := newIssue(rule, d, format, args...)
+	if position, ok := d.ArgPosition(i); ok {
+		issue.Line, issue.Column = position.Line, position.Column
+	}
+	return issue
+}
+
+type Rule struct {
+	Name        string
+	Description string
+	Check       func(directives []*Directive) []*Issue
+}
+
+var rules = []*Rule{
+	{
+		Name:        "auth-request",
+		Description: "auth_request targets must exist and be internal",
+		Check:       checkAuthRequests,
+	},
+	{
+		Name:        "upstream-affinity",
+		Description: "upstreams must use a single balancing method and a hash key that is always set",
+		Check:       checkUpstreamAffinity,
+	},
+	{
+		Name:        "pass-resolver",
+		Description: "*_pass targets with variables need a resolver in scope",
+		Check:       checkPassResolver,
+	},
+	{
+		Name:        "pass-startup-dns",
+		Description: "*_pass host names without variables are resolved only at startup",
+		Check:       checkPassStartupDNS,
+	},
+	{
+		Name:        "http-versions",
+		Description: "HTTP/2 and HTTP/3 directives must match the nginx version and be advertised",
+		Check:       checkHTTPVersions,
+	},
+	{
+		Name:        "websocket",
+		Description: "WebSocket locations must upgrade the proxied connection",
+		Check:       checkWebSockets,
+	},
+	{
+		Name:        "grpc",
+		Description: "grpc_pass locations need HTTP/2 and grpc_ settings",
+		Check:       CheckGRPC,
+	},
+	{
+		Name:        "mirror",
+		Description: "mirror targets must exist and be internal",
+		Check:       checkMirrors,
+	},
+	{
+		Name:        "sub-filter",
+		Description: "sub_filter rules must be able to see the response body",
+		Check:       checkSubFilters,
+	},
+	{
+		Name:        "error-page",
+		Description: "error_page codes and targets must be valid",
+		Check:       checkErrorPages,
+	},
+	{
+		Name:        "internal-location",
+		Description: "internal redirect targets must be internal and not reachable by rewrites",
+		Check:       checkInternalLocations,
+	},
+	{
+		Name:        "if-is-evil",
+		Description: "only return and rewrite ... last are safe inside if in location context",
+		Check:       CheckIfIsEvil,
+	},
+	{
+		Name:        "regex-captures",
+		Description: "capture references must match a group of the regex in effect",
+		Check:       CheckCaptures,
+	},
+	{
+		Name:        "try-files",
+		Description: "try_files fallbacks must exist, not loop and keep access control",
+		Check:       checkTryFiles,
+	},
+	{
+		Name:        "open-redirect",
+		Description: "redirect destinations must not be controlled by the client",
+		Check:       checkOpenRedirects,
+	},
+	{
+		Name:        "real-ip",
+		Description: "set_real_ip_from must not trust every client",
+		Check:       checkRealIP,
+	},
+	{
+		Name:        "tracing-request-id",
+		Description: "proxied requests must carry X-Request-ID $request_id",
+		Check:       checkRequestID,
+	},
+	{
+		Name:        "tracing-context",
+		Description: "trace context must be propagated by every proxying location once used",
+		Check:       checkTraceContext,
+	},
+	{
+		Name:        "named-location",
+		Description: "named locations must be top-level in a server and reachable",
+		Check:       checkNamedLocations,
+	},
+	{
+		Name:        "alias-traversal",
+		Description: "alias must not let requests escape the aliased directory",
+		Check:       CheckAlias,
+	},
+	{
+		Name:        "ownership",
+		Description: "servers and locations must have an owner once any block is annotated with one",
+		Check:       checkOwnership,
+	},
+	{
+		Name:        "if-condition",
+		Description: "if conditions must be valid",
+		Check:       checkConditions,
+	},
+	{
+		Name:        "retry-policy",
+		Description: "retries must not repeat non-idempotent requests or amplify failures",
+		Check:       checkRetryPolicies,
+	},
+	{
+		Name:        "keepalive",
+		Description: "keep-alive settings must not silently prevent connection reuse",
+		Check:       checkKeepalive,
+	},
+	{
+		Name:        "otel",
+		Description: "OpenTelemetry directives must be valid and spans must have an exporter",
+		Check:       checkOtel,
+	},
+}
+
+// Rules returns all registered lint rules sorted by name.
+func Rules() []*Rule {
+	result := make([]*Rule, len(rules))
+	copy(result, rules)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Lint runs the named rules, or every rule when no names are given.
+func Lint(directives []*Directive, names ...string) ([]*Issue, error) {
+	selected, err := selectRules(names)
+	if err != nil {
+		return nil, err
+	}
+	return lintRules(directives, selected), nil
+}
+
+func selectRules(names []string) ([]*Rule, error) {
+	if len(names) == 0 {
+		return rules, nil
+	}
+	selected := make([]*Rule, 0, len(names))
+	for _, name := range names {
+		rule := findRule(name)
+		if rule == nil {
+			return nil, fmt.Errorf("unknown rule %s", name)
+		}
+		selected = append(selected, rule)
+	}
+	return selected, nil
+}
+
+// lintRules runs rules and returns the issues not suppressed by pragmas,
+// ordered by file and line.
+func lintRules(directives []*Directive, rules []*Rule) []*Issue {
+	issues := make([]*Issue, 0)
+	chains := suppressions(directives)
+	for _, rule := range rules {
+		for _, issue := range rule.Check(directives) {
+			if !suppressedIssue(issue, chains) {
+				issues = append(issues, issue)
+			}
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].FileName != issues[j].FileName {
+			return issues[i].FileName < issues[j].FileName
+		}
+		return issues[i].Line < issues[j].Line
+	})
+	return issues
+}
+
+func findRule(name string) *Rule {
+	for _, rule := range rules {
+		if rule.Name == name {
+			return rule
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package nginxparser
+
+import (
+	"strconv"
+	"strings"
+)
+
+type Listen struct {
+	Directive *Directive      `json:"-"`
+	Address   string          `json:"address"`
+	Port      string          `json:"port"`
+	Params    map[string]bool `json:"params,omitempty"`
+}
+
+func (l *Listen) Has(param string) bool {
+	return l.Params[param]
+}
+
+// ParseListen splits a listen directive into its address, port and the
+// parameters that follow. Parameters with values, such as backlog=511, are
+// recorded by name only.
+func ParseListen(d *Directive) *Listen {
+	listen := &Listen{
+		Directive: d,
+		Address:   "*",
+		Port:      "80",
+		Params:    make(map[string]bool),
+	}
+	if len(d.Args) == 0 {
+		return listen
+	}
+	addr := d.Args[0]
+	switch {
+	case strings.HasPrefix(addr, "unix:"):
+		listen.Address, listen.Port = addr, ""
+	case strings.HasPrefix(addr, "["):
+		end := strings.IndexByte(addr, ']')
+		if end < 0 {
+			listen.Address = addr
+			break
+		}
+		listen.Address = addr[:end+1]
+		if port := strings.TrimPrefix(addr[end+1:], ":"); port != "" {
+			listen.Port = port
+		}
+	default:
+		if _, err := strconv.Atoi(addr); err == nil {
+			listen.Port = addr
+		} else if i := strings.LastIndexByte(addr, ':'); i >= 0 {
+			listen.Address, listen.Port = addr[:i], addr[i+1:]
+		} else {
+			listen.Address = addr
+		}
+	}
+	for _, param := range d.Args[1:] {
+		if i := strings.IndexByte(param, '='); i >= 0 {
+			param = param[:i]
+		}
+		listen.Params[param] = true
+	}
+	return listen
+}
+
+func serverListens(server *Directive) []*Listen {
+	result := make([]*Listen, 0)
+	for _, d := range findAll(server.Block, "listen") {
+		result = append(result, ParseListen(d))
+	}
+	return result
+}
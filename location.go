@@ -0,0 +1,113 @@
+package nginxparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	locationPrefix       = ""
+	locationExact        = "="
+	locationPreferPrefix = "^~"
+	locationRegex        = "~"
+	locationRegexNoCase  = "~*"
+	locationNamed        = "@"
+)
+
+func locationPattern(d *Directive) (modifier string, pattern string) {
+	switch len(d.Args) {
+	case 0:
+		return "", ""
+	case 1:
+		arg := d.Args[0]
+		switch {
+		case strings.HasPrefix(arg, "@"):
+			return locationNamed, arg
+		case strings.HasPrefix(arg, "="):
+			return locationExact, arg[1:]
+		case strings.HasPrefix(arg, "^~"):
+			return locationPreferPrefix, arg[2:]
+		case strings.HasPrefix(arg, "~*"):
+			return locationRegexNoCase, arg[2:]
+		case strings.HasPrefix(arg, "~"):
+			return locationRegex, arg[1:]
+		}
+		return locationPrefix, arg
+	default:
+		return d.Args[0], d.Args[1]
+	}
+}
+
+func locationRegexp(d *Directive) (*regexp.Regexp, error) {
+	modifier, pattern := locationPattern(d)
+	return compileRegex(pattern, modifier == locationRegexNoCase)
+}
+
+// FindLocation returns the location block of a server that nginx would
+// select for the given normalized request URI, or nil if none matches.
+func FindLocation(server *Directive, uri string) *Directive {
+	location, _ := findLocation(server.Block, uri)
+	return location
+}
+
+func findLocation(block []*Directive, uri string) (*Directive, bool) {
+	locations := findAll(block, "location")
+
+	var prefix *Directive
+	prefixLen := -1
+	noregex := false
+	for _, location := range locations {
+		modifier, pattern := locationPattern(location)
+		switch modifier {
+		case locationExact:
+			if pattern == uri {
+				return location, true
+			}
+		case locationPrefix, locationPreferPrefix:
+			if strings.HasPrefix(uri, pattern) && len(pattern) > prefixLen {
+				prefix, prefixLen = location, len(pattern)
+				noregex = modifier == locationPreferPrefix
+			}
+		}
+	}
+
+	result := prefix
+	if prefix != nil {
+		if nested, final := findLocation(prefix.Block, uri); nested != nil {
+			if final {
+				return nested, true
+			}
+			result = nested
+		}
+	}
+	if noregex {
+		return result, false
+	}
+
+	for _, location := range locations {
+		modifier, _ := locationPattern(location)
+		if modifier != locationRegex && modifier != locationRegexNoCase {
+			continue
+		}
+		re, err := locationRegexp(location)
+		if err != nil || !re.MatchString(uri) {
+			continue
+		}
+		if nested, _ := findLocation(location.Block, uri); nested != nil {
+			return nested, true
+		}
+		return location, true
+	}
+	return result, false
+}
+
+// NamedLocation returns the server's location @name, named locations are
+// only reachable through internal redirects and never match request URIs.
+func NamedLocation(server *Directive, name string) *Directive {
+	for _, location := range findAll(server.Block, "location") {
+		if modifier, pattern := locationPattern(location); modifier == locationNamed && pattern == name {
+			return location
+		}
+	}
+	return nil
+}
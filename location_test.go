@@ -0,0 +1,51 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestFindLocation(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        location / { return 200 root; }
+        location = /exact { return 200 exact; }
+        location ^~ /static/ { return 200 static; }
+        location /api/ {
+            location /api/v1/ { return 200 v1; }
+            location ~ \.json$ { return 200 nested-json; }
+        }
+        location ~* \.(png|jpg)$ { return 200 image; }
+        location @fallback { return 200 named; }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := servers(directives)[0]
+
+	fixtures := map[string]string{
+		"/":              "root",
+		"/exact":         "exact",
+		"/exactly":       "root",
+		"/static/a.png":  "static",
+		"/images/a.PNG":  "image",
+		"/api/v1/users":  "v1",
+		"/api/v1/a.json": "nested-json",
+		"/api/other":     "",
+	}
+	for uri, expected := range fixtures {
+		location := FindLocation(server, uri)
+		if location == nil {
+			t.Fatalf("no location found for %s", uri)
+		}
+		ret := findFirst(location.Block, "return")
+		actual := ""
+		if ret != nil {
+			actual = ret.Args[1]
+		}
+		if actual != expected {
+			t.Errorf("%s: expected %q but got %q", uri, expected, actual)
+		}
+	}
+}
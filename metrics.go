@@ -0,0 +1,91 @@
+package nginxparser
+
+type Metrics struct {
+	// Directives counts every directive except comments and, in
+	// Frequency, by name.
+	Directives int            `json:"directives"`
+	Frequency  map[string]int `json:"frequency"`
+	// Files counts the directives read from each file.
+	Files map[string]int `json:"files"`
+	// MaxDepth is the largest number of blocks around a directive.
+	MaxDepth       int `json:"max_depth"`
+	Locations      int `json:"locations"`
+	RegexLocations int `json:"regex_locations"`
+	Ifs            int `json:"ifs"`
+	// RewriteChain is the longest sequence of rewrites where each one
+	// lands in a location that rewrites again.
+	RewriteChain []*Directive `json:"-"`
+}
+
+// Measure computes complexity metrics for a parsed tree, meant to track
+// how a configuration grows over time.
+func Measure(directives []*Directive) *Metrics {
+	metrics := &Metrics{
+		Frequency:    make(map[string]int),
+		Files:        make(map[string]int),
+		RewriteChain: make([]*Directive, 0),
+	}
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive == "#" {
+			return true
+		}
+		metrics.Directives++
+		metrics.Frequency[d.Directive]++
+		metrics.Files[d.FileName]++
+		if len(parents) > metrics.MaxDepth {
+			metrics.MaxDepth = len(parents)
+		}
+		switch d.Directive {
+		case "location":
+			metrics.Locations++
+			if modifier, _ := locationPattern(d); modifier == locationRegex || modifier == locationRegexNoCase {
+				metrics.RegexLocations++
+			}
+		case "if":
+			metrics.Ifs++
+		}
+		return true
+	})
+	metrics.RewriteChain = longestRewriteChain(directives)
+	return metrics
+}
+
+// longestRewriteChain follows rewrites that search for a location again
+// into the location their target selects. Loops end the chain.
+func longestRewriteChain(directives []*Directive) []*Directive {
+	redirects := internalRedirects(directives)
+	targets := make(map[*Directive]*internalRedirect)
+	for _, redirect := range redirects {
+		d := redirect.directive
+		if d.Directive == "rewrite" && (len(d.Args) < 3 || d.Args[2] != "break") {
+			targets[d] = redirect
+		}
+	}
+
+	var follow func(d *Directive, seen map[*Directive]bool) []*Directive
+	follow = func(d *Directive, seen map[*Directive]bool) []*Directive {
+		redirect := targets[d]
+		if redirect == nil || seen[d] {
+			return nil
+		}
+		seen[d] = true
+		defer delete(seen, d)
+		longest := make([]*Directive, 0)
+		if location := FindLocation(redirect.server, redirect.uri); location != nil {
+			for _, next := range findAll(location.Block, "rewrite") {
+				if chain := follow(next, seen); len(chain) > len(longest) {
+					longest = chain
+				}
+			}
+		}
+		return append([]*Directive{d}, longest...)
+	}
+
+	longest := make([]*Directive, 0)
+	for _, redirect := range redirects {
+		if chain := follow(redirect.directive, make(map[*Directive]bool)); len(chain) > len(longest) {
+			longest = chain
+		}
+	}
+	return longest
+}
@@ -0,0 +1,52 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestMeasure(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+# metrics
+http {
+    server {
+        rewrite ^/old$ /a last;
+        location /a {
+            rewrite ^ /b;
+        }
+        location /b {
+            if ($arg_c) {
+                rewrite ^ /c last;
+            }
+            rewrite ^ /c last;
+        }
+        location ~ ^/c {
+            rewrite ^ /a break;
+            rewrite ^ /loop last;
+        }
+        location /loop {
+            rewrite ^ /a last;
+        }
+        location ~* \.png$ {
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := Measure(directives)
+	if metrics.Directives != 15 || metrics.Frequency["rewrite"] != 7 || metrics.Files[""] != 15 {
+		t.Fatalf("unexpected counts %+v", metrics)
+	}
+	if metrics.MaxDepth != 4 || metrics.Locations != 5 || metrics.RegexLocations != 2 || metrics.Ifs != 1 {
+		t.Fatalf("unexpected metrics %+v", metrics)
+	}
+	// /old -> /a -> /b -> /c -> /loop, and back to /a ends the chain.
+	lines := make([]int, 0)
+	for _, d := range metrics.RewriteChain {
+		lines = append(lines, d.Line)
+	}
+	if len(lines) != 5 || lines[0] != 5 || lines[1] != 7 || lines[2] != 13 || lines[3] != 17 || lines[4] != 20 {
+		t.Fatalf("unexpected rewrite chain %v", lines)
+	}
+}
@@ -0,0 +1,91 @@
+package nginxparser
+
+type MirrorTarget struct {
+	URI      string     `json:"uri"`
+	Location *Directive `json:"-"`
+	Internal bool       `json:"internal"`
+}
+
+type Mirror struct {
+	Server   *Directive      `json:"-"`
+	Location *Directive      `json:"-"`
+	Targets  []*MirrorTarget `json:"targets"`
+	Body     bool            `json:"body"`
+}
+
+// AddedRequests is the number of extra backend requests nginx issues for
+// every request served by the mirrored location.
+func (m *Mirror) AddedRequests() int {
+	return len(m.Targets)
+}
+
+// Mirrors lists the locations whose traffic is shadowed by the mirror
+// directive, including mirrors inherited from the server or http level.
+// Internal locations are skipped as subrequests are never mirrored.
+func Mirrors(directives []*Directive) []*Mirror {
+	result := make([]*Mirror, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive != "location" || findFirst(d.Block, "internal") != nil {
+			return true
+		}
+		server := enclosing("server", parents)
+		mirror := &Mirror{
+			Server:   server,
+			Location: d,
+			Targets:  make([]*MirrorTarget, 0),
+			Body:     true,
+		}
+		for _, m := range lookupInheritedAll("mirror", d.Block, parents) {
+			if len(m.Args) == 0 || m.Args[0] == "off" {
+				continue
+			}
+			target := &MirrorTarget{URI: m.Args[0]}
+			if server != nil {
+				target.Location = FindLocation(server, target.URI)
+			}
+			if target.Location != nil {
+				target.Internal = findFirst(target.Location.Block, "internal") != nil
+			}
+			mirror.Targets = append(mirror.Targets, target)
+		}
+		if body := lookupInherited("mirror_request_body", d.Block, parents); body != nil && len(body.Args) > 0 {
+			mirror.Body = body.Args[0] != "off"
+		}
+		if len(mirror.Targets) > 0 {
+			result = append(result, mirror)
+		}
+		return true
+	})
+	return result
+}
+
+// MirrorLoad counts how many mirrored locations feed each mirror target,
+// i.e. by how many times the target's traffic is multiplied.
+func MirrorLoad(mirrors []*Mirror) map[*Directive]int {
+	load := make(map[*Directive]int)
+	for _, mirror := range mirrors {
+		for _, target := range mirror.Targets {
+			if target.Location != nil {
+				load[target.Location]++
+			}
+		}
+	}
+	return load
+}
+
+func checkMirrors(directives []*Directive) []*Issue {
+	issues := make([]*Issue, 0)
+	for _, mirror := range Mirrors(directives) {
+		for _, target := range mirror.Targets {
+			switch {
+			case target.Location == nil:
+				issues = append(issues, newIssue("mirror", mirror.Location, "mirror %s does not match any location", target.URI))
+			case target.Location == mirror.Location:
+				issues = append(issues, newIssue("mirror", mirror.Location, "mirror %s is routed back to the mirrored location", target.URI))
+			case !target.Internal:
+				issues = append(issues, newIssue("mirror", target.Location, "mirror target %s is not internal and can be requested directly", target.URI))
+			}
+		}
+	}
+	return issues
+}
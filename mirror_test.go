@@ -0,0 +1,51 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestMirrors(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        mirror /shadow;
+        location / {
+            proxy_pass http://backend;
+        }
+        location /upload {
+            mirror /shadow;
+            mirror /audit;
+            mirror_request_body off;
+            proxy_pass http://backend;
+        }
+        location = /shadow {
+            internal;
+            proxy_pass http://shadow$request_uri;
+        }
+        location = /audit {
+            proxy_pass http://audit;
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mirrors := Mirrors(directives)
+	if len(mirrors) != 3 {
+		t.Fatalf("expected 3 mirrored locations but got %d", len(mirrors))
+	}
+	if mirrors[1].AddedRequests() != 2 || mirrors[1].Body {
+		t.Fatalf("unexpected mirror %+v", mirrors[1])
+	}
+
+	load := MirrorLoad(mirrors)
+	if shadow := mirrors[0].Targets[0].Location; load[shadow] != 3 {
+		t.Fatalf("expected /shadow to receive 3 mirrors but got %d", load[shadow])
+	}
+
+	issues := checkMirrors(directives)
+	if len(issues) != 1 || issues[0].Line != 18 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+}
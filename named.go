@@ -0,0 +1,125 @@
+package nginxparser
+
+import (
+	"strings"
+)
+
+type Named struct {
+	Location   *Directive   `json:"-"`
+	Server     *Directive   `json:"-"`
+	Name       string       `json:"name"`
+	References []*Directive `json:"-"`
+}
+
+func isNamedLocation(d *Directive) bool {
+	modifier, _ := locationPattern(d)
+	return d.Directive == "location" && modifier == locationNamed
+}
+
+// InternalRedirect returns the location an internal redirect to target
+// ends up in: the named location for @name, the location matching the URI
+// otherwise.
+func InternalRedirect(server *Directive, target string) *Directive {
+	if strings.HasPrefix(target, "@") {
+		return NamedLocation(server, target)
+	}
+	return FindLocation(server, target)
+}
+
+// NamedLocations lists the named locations of every server with the
+// directives redirecting to them: try_files fallbacks, error_page,
+// post_action and X-Accel-Redirect responses naming them.
+func NamedLocations(directives []*Directive, options *InternalAuditOptions) []*Named {
+	if options == nil {
+		options = &InternalAuditOptions{}
+	}
+	result := make([]*Named, 0)
+	index := make(map[*Directive]*Named)
+	for _, server := range servers(directives) {
+		for _, location := range findAll(server.Block, "location") {
+			if !isNamedLocation(location) {
+				continue
+			}
+			_, name := locationPattern(location)
+			named := &Named{Location: location, Server: server, Name: name, References: make([]*Directive, 0)}
+			if _, ok := index[location]; !ok {
+				index[location] = named
+				result = append(result, named)
+			}
+		}
+	}
+	reference := func(location, d *Directive) {
+		if named := index[location]; named != nil && d != nil {
+			named.References = append(named.References, d)
+		} else if named != nil {
+			named.References = append(named.References, location)
+		}
+	}
+
+	for _, try := range TryFilesRoutes(directives) {
+		if try.FallbackKind == FallbackNamed {
+			reference(try.Target, try.Directive)
+		}
+	}
+	for _, page := range ErrorPages(directives) {
+		if page.Kind == ErrorPageNamed {
+			reference(page.Location, page.Directive)
+		}
+	}
+	for _, server := range servers(directives) {
+		Walk(server.Block, func(d *Directive, parents []*Directive) bool {
+			if d.Directive == "post_action" && len(d.Args) > 0 && strings.HasPrefix(d.Args[0], "@") {
+				reference(NamedLocation(server, d.Args[0]), d)
+			}
+			return true
+		})
+		for _, uri := range options.AccelRedirects {
+			if strings.HasPrefix(uri, "@") {
+				reference(NamedLocation(server, uri), nil)
+			}
+		}
+	}
+	return result
+}
+
+// CheckNamedLocations flags named locations nginx rejects, nested in
+// another location or containing locations, duplicated names and named
+// locations nothing redirects to.
+func CheckNamedLocations(directives []*Directive, options *InternalAuditOptions) []*Issue {
+	issues := make([]*Issue, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if !isNamedLocation(d) {
+			return true
+		}
+		if enclosing("location", parents) != nil {
+			issues = append(issues, newIssue("named-location", d, "named location %s cannot be nested in another location", d.Args[0]))
+		}
+		for _, nested := range findAll(d.Block, "location") {
+			issues = append(issues, newIssue("named-location", nested, "location cannot be nested in named location %s", d.Args[0]))
+		}
+		if internal := findFirst(d.Block, "internal"); internal != nil {
+			issues = append(issues, newIssue("named-location", internal, "named locations are always internal, internal is redundant"))
+		}
+		return true
+	})
+
+	seen := make(map[*Directive]map[string]bool)
+	for _, named := range NamedLocations(directives, options) {
+		if seen[named.Server] == nil {
+			seen[named.Server] = make(map[string]bool)
+		}
+		if seen[named.Server][named.Name] {
+			issues = append(issues, newIssue("named-location", named.Location, "duplicate named location %s", named.Name))
+			continue
+		}
+		seen[named.Server][named.Name] = true
+		if len(named.References) == 0 {
+			issues = append(issues, newIssue("named-location", named.Location, "named location %s is never used, only try_files, error_page, post_action and X-Accel-Redirect can reach it", named.Name))
+		}
+	}
+	return issues
+}
+
+func checkNamedLocations(directives []*Directive) []*Issue {
+	return CheckNamedLocations(directives, nil)
+}
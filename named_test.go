@@ -0,0 +1,59 @@
+package nginxparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNamedLocations(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        server_name example.com;
+        location / {
+            try_files $uri @app;
+            location @nested { return 204; }
+        }
+        location /api {
+            error_page 502 @maintenance;
+            proxy_pass http://api;
+        }
+        location @app { proxy_pass http://app; }
+        location @maintenance { internal; return 503; }
+        location @unused { return 404; }
+        location @accel { root /srv/protected; }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	named := NamedLocations(directives, nil)
+	names := make([]string, 0, len(named))
+	for _, n := range named {
+		names = append(names, n.Name)
+	}
+	if expected := []string{"@app", "@maintenance", "@unused", "@accel"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected %v but got %v", expected, names)
+	}
+	if len(named[0].References) != 1 || named[0].References[0].Directive != "try_files" {
+		t.Fatalf("unexpected references %+v", named[0].References)
+	}
+
+	route := RouteRequest(directives, "example.com", "80", "@app")
+	if route.Location != named[0].Location {
+		t.Fatalf("expected @app, got %+v", route.Location)
+	}
+	if route := RouteRequest(directives, "example.com", "80", "/@app"); route.Location == named[0].Location {
+		t.Fatal("named locations must not match request URIs")
+	}
+
+	issues := CheckNamedLocations(directives, &InternalAuditOptions{AccelRedirects: []string{"@accel"}})
+	lines := make([]int, 0, len(issues))
+	for _, issue := range issues {
+		lines = append(lines, issue.Line)
+	}
+	if expected := []int{7, 14, 15}; !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("expected issues on lines %v but got %v", expected, issues)
+	}
+}
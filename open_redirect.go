@@ -0,0 +1,158 @@
+package nginxparser
+
+import (
+	"strconv"
+	"strings"
+)
+
+var taintedPrefixes = []string{"http_", "arg_", "cookie_"}
+
+var taintedVariables = map[string]bool{
+	"args":         true,
+	"query_string": true,
+	"request_uri":  true,
+	"request":      true,
+	"request_body": true,
+}
+
+func isTaintedVariable(name string) bool {
+	if taintedVariables[name] {
+		return true
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return true
+	}
+	for _, prefix := range taintedPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// taintChains follows set and map assignments and returns, for every
+// variable derived from request-controlled input, the chain of variables
+// leading back to that input, e.g. [$dest $next $arg_next].
+func taintChains(directives []*Directive) map[string][]string {
+	sources := make(map[string][]string)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		switch d.Directive {
+		case "set":
+			if len(d.Args) == 2 && strings.HasPrefix(d.Args[0], "$") {
+				name := strings.TrimPrefix(d.Args[0], "$")
+				sources[name] = append(sources[name], Variables(d.Args[1])...)
+			}
+		case "map":
+			if len(d.Args) == 2 {
+				name := strings.TrimPrefix(d.Args[1], "$")
+				for _, entry := range children(d.Block) {
+					for _, arg := range entry.Args {
+						sources[name] = append(sources[name], Variables(arg)...)
+					}
+				}
+			}
+			return false
+		}
+		return true
+	})
+
+	chains := make(map[string][]string)
+	for changed := true; changed; {
+		changed = false
+		for name, vars := range sources {
+			if _, ok := chains[name]; ok {
+				continue
+			}
+			for _, v := range vars {
+				if chain, ok := chains[v]; ok {
+					chains[name] = append([]string{"$" + name}, chain...)
+					changed = true
+					break
+				}
+				if isTaintedVariable(v) {
+					chains[name] = []string{"$" + name, "$" + v}
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return chains
+}
+
+type OpenRedirect struct {
+	Directive *Directive `json:"-"`
+	Target    string     `json:"target"`
+	Chain     []string   `json:"chain"`
+}
+
+func redirectTarget(d *Directive) (string, bool) {
+	switch d.Directive {
+	case "return":
+		if len(d.Args) == 2 {
+			code, err := strconv.Atoi(d.Args[0])
+			return d.Args[1], err == nil && code >= 301 && code <= 308 && code != 304 && code != 305 && code != 306
+		}
+		return "", false
+	case "rewrite":
+		if len(d.Args) < 2 {
+			return "", false
+		}
+		target := d.Args[1]
+		external := strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") || strings.HasPrefix(target, "$scheme")
+		return target, external || (len(d.Args) > 2 && (d.Args[2] == "redirect" || d.Args[2] == "permanent"))
+	}
+	return "", false
+}
+
+// redirectAuthority returns the part of a redirect target that decides
+// where the client is sent: the leading variables of a relative target or
+// the host of an absolute one.
+func redirectAuthority(target string) string {
+	if strings.HasPrefix(target, "$") && !strings.HasPrefix(target, "$scheme") {
+		end := 1
+		for end < len(target) && (target[end] == '$' || target[end] == '{' || target[end] == '}' || isVariableByte(target[end])) {
+			end++
+		}
+		return target[:end]
+	}
+	if strings.Contains(target, "://") {
+		_, host, _ := splitPassTarget(target)
+		return host
+	}
+	return ""
+}
+
+// OpenRedirects lists redirects whose destination is controlled by the
+// client, either directly through request variables or through variables
+// assigned from them.
+func OpenRedirects(directives []*Directive) []*OpenRedirect {
+	chains := taintChains(directives)
+	result := make([]*OpenRedirect, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		target, ok := redirectTarget(d)
+		if !ok {
+			return true
+		}
+		for _, name := range Variables(redirectAuthority(target)) {
+			chain, tainted := chains[name]
+			if !tainted && isTaintedVariable(name) {
+				chain, tainted = []string{"$" + name}, true
+			}
+			if tainted {
+				result = append(result, &OpenRedirect{Directive: d, Target: target, Chain: chain})
+				break
+			}
+		}
+		return true
+	})
+	return result
+}
+
+func checkOpenRedirects(directives []*Directive) []*Issue {
+	issues := make([]*Issue, 0)
+	for _, redirect := range OpenRedirects(directives) {
+		issues = append(issues, newIssue("open-redirect", redirect.Directive, "redirect target %s is controlled by the client through %s", redirect.Target, strings.Join(redirect.Chain, " <- ")))
+	}
+	return issues
+}
@@ -0,0 +1,57 @@
+package nginxparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOpenRedirects(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    map $arg_next $destination {
+        default $arg_next;
+    }
+    map $arg_lang $language {
+        default en;
+        de de;
+    }
+    server {
+        location /login {
+            set $next $destination;
+            return 302 $next;
+        }
+        location /lang {
+            return 302 /$language/;
+        }
+        location /proxy {
+            return 301 https://$http_host$request_uri;
+        }
+        location /safe {
+            return 301 https://example.com$request_uri;
+        }
+        location /raw {
+            return 302 $request_uri;
+        }
+        location ~ ^/go/(.*)$ {
+            rewrite ^/go/(.*)$ $1 redirect;
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	redirects := OpenRedirects(directives)
+	if len(redirects) != 4 {
+		t.Fatalf("expected 4 open redirects but got %d", len(redirects))
+	}
+	if !reflect.DeepEqual(redirects[0].Chain, []string{"$next", "$destination", "$arg_next"}) {
+		t.Fatalf("unexpected chain %v", redirects[0].Chain)
+	}
+	lines := []int{13, 19, 25, 28}
+	for i, redirect := range redirects {
+		if redirect.Directive.Line != lines[i] {
+			t.Fatalf("unexpected redirect %+v", redirect)
+		}
+	}
+}
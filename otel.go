@@ -0,0 +1,149 @@
+package nginxparser
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+var otelTraceContexts = map[string]bool{
+	"extract":   true,
+	"inject":    true,
+	"propagate": true,
+	"ignore":    true,
+}
+
+type OtelExporter struct {
+	Directive  *Directive        `json:"-"`
+	Endpoint   string            `json:"endpoint"`
+	Interval   time.Duration     `json:"interval"`
+	BatchSize  int               `json:"batch_size"`
+	BatchCount int               `json:"batch_count"`
+	Headers    map[string]string `json:"headers,omitempty"`
+}
+
+// OtelSpan is the tracing in effect for a location.
+type OtelSpan struct {
+	Location *Directive `json:"-"`
+	Server   *Directive `json:"-"`
+	// Trace is on, off or the variable deciding per request.
+	Trace   string `json:"trace"`
+	Context string `json:"context"`
+	// Name is empty for the default, the name of the location.
+	Name       string            `json:"name,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+type Otel struct {
+	// Exporter is nil when no otel_exporter is configured.
+	Exporter           *OtelExporter     `json:"exporter,omitempty"`
+	ServiceName        string            `json:"service_name"`
+	ResourceAttributes map[string]string `json:"resource_attributes,omitempty"`
+	// Spans lists the locations of http servers where tracing is not off.
+	Spans []*OtelSpan `json:"spans"`
+}
+
+// OpenTelemetry reports the ngx_otel_module setup: where spans are
+// exported, and which locations are traced with what context propagation
+// and attributes.
+func OpenTelemetry(directives []*Directive) *Otel {
+	otel := &Otel{ServiceName: "unknown_service:nginx", ResourceAttributes: make(map[string]string), Spans: make([]*OtelSpan, 0)}
+	for _, http := range findAll(directives, "http") {
+		if d := findFirst(http.Block, "otel_exporter"); d != nil {
+			otel.Exporter = &OtelExporter{Directive: d, Interval: 5 * time.Second, BatchSize: 512, BatchCount: 4, Headers: make(map[string]string)}
+			for _, child := range children(d.Block) {
+				if len(child.Args) == 0 {
+					continue
+				}
+				switch child.Directive {
+				case "endpoint":
+					otel.Exporter.Endpoint = child.Args[0]
+				case "interval":
+					otel.Exporter.Interval, _ = ParseDuration(child.Args[0])
+				case "batch_size":
+					otel.Exporter.BatchSize, _ = strconv.Atoi(child.Args[0])
+				case "batch_count":
+					otel.Exporter.BatchCount, _ = strconv.Atoi(child.Args[0])
+				case "header":
+					if len(child.Args) == 2 {
+						otel.Exporter.Headers[child.Args[0]] = child.Args[1]
+					}
+				}
+			}
+		}
+		if d := findFirst(http.Block, "otel_service_name"); d != nil && len(d.Args) > 0 {
+			otel.ServiceName = d.Args[0]
+		}
+		for _, d := range findAll(http.Block, "otel_resource_attr") {
+			if len(d.Args) == 2 {
+				otel.ResourceAttributes[d.Args[0]] = d.Args[1]
+			}
+		}
+	}
+
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive != "location" || enclosing("http", parents) == nil {
+			return true
+		}
+		span := &OtelSpan{Location: d, Server: enclosing("server", parents), Trace: "off", Context: "ignore", Attributes: make(map[string]string)}
+		if trace := lookupInherited("otel_trace", d.Block, parents); trace != nil && len(trace.Args) > 0 {
+			span.Trace = trace.Args[0]
+		}
+		if span.Trace == "off" {
+			return true
+		}
+		if context := lookupInherited("otel_trace_context", d.Block, parents); context != nil && len(context.Args) > 0 {
+			span.Context = context.Args[0]
+		}
+		if name := lookupInherited("otel_span_name", d.Block, parents); name != nil && len(name.Args) > 0 {
+			span.Name = name.Args[0]
+		}
+		for _, attr := range lookupInheritedAll("otel_span_attr", d.Block, parents) {
+			if len(attr.Args) == 2 {
+				span.Attributes[attr.Args[0]] = attr.Args[1]
+			}
+		}
+		otel.Spans = append(otel.Spans, span)
+		return true
+	})
+	return otel
+}
+
+func checkOtel(directives []*Directive) []*Issue {
+	issues := make([]*Issue, 0)
+	var exporter *Directive
+	traces := make([]*Directive, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		switch d.Directive {
+		case "otel_exporter":
+			exporter = d
+			if endpoint := findFirst(d.Block, "endpoint"); endpoint == nil || len(endpoint.Args) == 0 {
+				issues = append(issues, newIssue("otel", d, "otel_exporter requires an endpoint"))
+			}
+		case "otel_trace":
+			if len(d.Args) != 1 || d.Args[0] != "on" && d.Args[0] != "off" && !strings.HasPrefix(d.Args[0], "$") {
+				issues = append(issues, newIssue("otel", d, "otel_trace takes on, off or a variable"))
+			} else if d.Args[0] != "off" {
+				traces = append(traces, d)
+			}
+		case "otel_trace_context":
+			if len(d.Args) != 1 || !otelTraceContexts[d.Args[0]] {
+				issues = append(issues, newIssue("otel", d, "otel_trace_context takes extract, inject, propagate or ignore"))
+			}
+		case "otel_span_attr", "otel_resource_attr", "header":
+			if d.Directive == "header" && enclosing("otel_exporter", parents) == nil {
+				return true
+			}
+			if len(d.Args) != 2 {
+				issues = append(issues, newIssue("otel", d, "%s takes a name and a value", d.Directive))
+			}
+		}
+		return true
+	})
+	if exporter == nil {
+		for _, d := range traces {
+			issues = append(issues, newIssue("otel", d, "otel_trace enables tracing but no otel_exporter sends the spans anywhere"))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,79 @@
+package nginxparser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOpenTelemetry(t *testing.T) {
+	directives, err := New(&ParseOptions{}).ParseString(`http {
+    otel_exporter {
+        endpoint collector:4317;
+        interval 10s;
+        header X-API-Key secret;
+    }
+    otel_service_name shop;
+    otel_resource_attr deployment.environment production;
+    otel_trace on;
+    otel_trace_context propagate;
+    otel_span_attr team web;
+    server {
+        location / {
+            proxy_pass http://app;
+        }
+        location /api {
+            otel_span_name api;
+            otel_span_attr team api;
+            otel_span_attr tier backend;
+            proxy_pass http://api;
+        }
+        location /health {
+            otel_trace off;
+            return 204;
+        }
+    }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otel := OpenTelemetry(directives)
+	if otel.Exporter == nil || otel.Exporter.Endpoint != "collector:4317" || otel.Exporter.Interval != 10*time.Second || otel.Exporter.BatchSize != 512 || otel.Exporter.Headers["X-API-Key"] != "secret" {
+		t.Fatalf("unexpected exporter %+v", otel.Exporter)
+	}
+	if otel.ServiceName != "shop" || otel.ResourceAttributes["deployment.environment"] != "production" {
+		t.Fatalf("unexpected resource %+v", otel)
+	}
+	if len(otel.Spans) != 2 {
+		t.Fatalf("expected 2 traced locations, got %d", len(otel.Spans))
+	}
+	root, api := otel.Spans[0], otel.Spans[1]
+	if root.Trace != "on" || root.Context != "propagate" || root.Name != "" || root.Attributes["team"] != "web" {
+		t.Fatalf("unexpected span %+v", root)
+	}
+	if api.Name != "api" || api.Attributes["team"] != "api" || api.Attributes["tier"] != "backend" || len(api.Attributes) != 2 {
+		t.Fatalf("unexpected span %+v", api)
+	}
+	if issues := checkOtel(directives); len(issues) != 0 {
+		t.Fatalf("unexpected issues %+v", issues)
+	}
+
+	directives, err = New(&ParseOptions{}).ParseString(`http {
+    otel_trace $sampled;
+    otel_trace_context always;
+    server {
+        otel_span_attr team;
+    }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues := checkOtel(directives)
+	if len(issues) != 3 || issues[0].Line != 3 || issues[1].Line != 5 || issues[2].Line != 2 {
+		t.Fatalf("unexpected issues %+v", issues)
+	}
+	if doc, ok := Lookup("otel_trace"); !ok || doc.Default != "off" {
+		t.Fatal("expected otel_trace to be documented")
+	}
+}
@@ -0,0 +1,77 @@
+package nginxparser
+
+import (
+	"strings"
+)
+
+type Ownership struct {
+	Block  *Directive `json:"-"`
+	Server *Directive `json:"-"`
+	// Path names the block and the blocks around it, outermost first.
+	Path     []string `json:"path"`
+	FileName string   `json:"filename"`
+	Line     int      `json:"line"`
+	Owner    string   `json:"owner,omitempty"`
+	// Inherited is set when the owner is annotated on an enclosing block.
+	Inherited bool `json:"inherited,omitempty"`
+}
+
+// Ownerships maps every server and location to the team or service owning
+// it, taken from "# owner: team" annotations on the block or the nearest
+// block around it. The owner is empty when none is annotated.
+func Ownerships(directives []*Directive) []*Ownership {
+	result := make([]*Ownership, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if !isBlock(d) || len(parents) == 0 {
+			return true
+		}
+		var server *Directive
+		switch {
+		case d.Directive == "server" && parents[len(parents)-1].Directive == "http":
+			server = d
+		case d.Directive == "location":
+			if server = enclosing("server", parents); server == nil {
+				return true
+			}
+		default:
+			return true
+		}
+		entry := &Ownership{Block: d, Server: server, Path: make([]string, 0), FileName: d.FileName, Line: d.Line}
+		for _, parent := range append(parents[:len(parents):len(parents)], d) {
+			if parent.Directive == "server" || parent.Directive == "location" {
+				entry.Path = append(entry.Path, diffLabel(parent))
+			}
+		}
+		if d.Annotations != nil && d.Annotations.Owner != "" {
+			entry.Owner = d.Annotations.Owner
+		}
+		for i := len(parents) - 1; i >= 0 && entry.Owner == ""; i-- {
+			if parents[i].Annotations != nil && parents[i].Annotations.Owner != "" {
+				entry.Owner, entry.Inherited = parents[i].Annotations.Owner, true
+			}
+		}
+		result = append(result, entry)
+		return true
+	})
+	return result
+}
+
+// checkOwnership only applies once the configuration annotates owners,
+// blocks left out are the ones audits cannot route.
+func checkOwnership(directives []*Directive) []*Issue {
+	ownerships := Ownerships(directives)
+	owned := false
+	for _, entry := range ownerships {
+		owned = owned || entry.Owner != ""
+	}
+	issues := make([]*Issue, 0)
+	if !owned {
+		return issues
+	}
+	for _, entry := range ownerships {
+		if entry.Owner == "" {
+			issues = append(issues, newIssue("ownership", entry.Block, "%s has no owner", strings.Join(entry.Path, " > ")))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,69 @@
+package nginxparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOwnerships(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    # owner: team-edge
+    server {
+        server_name example.com;
+        location / {
+        }
+        location /api/ { # owner: team-api
+            location /api/v1/ {
+            }
+        }
+    }
+    server {
+        server_name other.example.com;
+        location / {
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ownerships := Ownerships(directives)
+	expected := []struct {
+		path      string
+		owner     string
+		inherited bool
+	}{
+		{"server example.com", "team-edge", false},
+		{"server example.com > location /", "team-edge", true},
+		{"server example.com > location /api/", "team-api", false},
+		{"server example.com > location /api/ > location /api/v1/", "team-api", true},
+		{"server other.example.com", "", false},
+		{"server other.example.com > location /", "", false},
+	}
+	if len(ownerships) != len(expected) {
+		t.Fatalf("unexpected ownerships %+v", ownerships)
+	}
+	for i, entry := range ownerships {
+		path := strings.Join(entry.Path, " > ")
+		if path != expected[i].path || entry.Owner != expected[i].owner || entry.Inherited != expected[i].inherited {
+			t.Fatalf("expected %+v but got %s %+v", expected[i], path, entry)
+		}
+	}
+
+	issues, err := Lint(directives, "ownership")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 || issues[0].Line != 13 || issues[1].Line != 15 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+
+	directives, err = New(&ParseOptions{SingleFile: true}).ParseString(`http { server { location / {} } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues, _ := Lint(directives, "ownership"); len(issues) != 0 {
+		t.Fatalf("expected no issues without owner annotations, got %v", issues)
+	}
+}
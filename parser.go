@@ -1,14 +1,15 @@
 package nginxparser
 
 import (
-	"bufio"
 	"bytes"
-	"fmt"
+	"context"
 	"io"
+	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"unicode"
 )
 
@@ -19,44 +20,355 @@ type Directive struct {
 	Args      []string     `json:"args,omitempty"`
 	Block     []*Directive `json:"block,omitempty"`
 	Comment   string       `json:"comment,omitempty"`
+	Includes  []int        `json:"includes,omitempty"`
+	// IncludeFiles are the files an include directive matched, recorded
+	// instead of parsed with ParseOptions.RecordIncludes.
+	IncludeFiles []string `json:"include_files,omitempty"`
+	// Quotes holds how each of Args was quoted in the source.
+	Quotes []Quote `json:"-"`
+	// ArgPositions holds where each of Args starts in the source.
+	ArgPositions []Position `json:"-"`
+	// Inline marks a comment that follows a directive or an opening brace
+	// on the same line.
+	Inline bool `json:"-"`
+	// LeadingComments are the comments on the lines right above the
+	// directive and TrailingComment the one after it on its line, or after
+	// the opening brace of a block. The comments stay in the tree as #
+	// directives too, those are what the emitter writes.
+	LeadingComments []string `json:"-"`
+	TrailingComment string   `json:"-"`
+	// BlankLinesBefore is the number of blank lines between the directive
+	// and the one before it, or the opening brace of its block.
+	BlankLinesBefore int `json:"-"`
+
+	Annotations *Annotations `json:"annotations,omitempty"`
+	Provenance  *Provenance  `json:"provenance,omitempty"`
+	Trivia      *Trivia      `json:"-"`
+	Range       Range        `json:"-"`
+	// Raw is the directive as written, up to and including the semicolon,
+	// or the opening brace for blocks, when parsing with ParseOptions.Raw.
+	Raw string `json:"raw,omitempty"`
+	// Parent is the block directive around this one, nil at the top
+	// level. Directives pulled in by include point to the block around
+	// the include directive.
+	Parent *Directive `json:"-"`
+	// IncludedBy is the include directive that pulled the directive in
+	// from another file, nil for directives of the main file.
+	IncludedBy *Directive `json:"-"`
+	// pulled are the files an include directive holds the directives of
+	// in its Block, empty ones included, for WriteFiles.
+	pulled []string
 }
 
+// fsPath turns a file name into the form fs.FS expects: slash separated,
+// unrooted and clean.
+func fsPath(name string) string {
+	name = strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// DefaultMaxIncludeDepth is the include nesting allowed when
+// ParseOptions.MaxIncludeDepth is not set.
+const DefaultMaxIncludeDepth = 32
+
 func New(options *ParseOptions) *Parser {
 	if options == nil {
 		options = &ParseOptions{}
 	}
+	if options.MaxIncludeDepth == 0 {
+		options.MaxIncludeDepth = DefaultMaxIncludeDepth
+	}
+	if options.FS != nil {
+		fsys := options.FS
+		if options.Glob == nil {
+			options.Glob = func(pattern string) ([]string, error) {
+				matches, err := fs.Glob(fsys, fsPath(pattern))
+				if strings.HasPrefix(filepath.ToSlash(pattern), "/") {
+					for i := range matches {
+						matches[i] = "/" + matches[i]
+					}
+				}
+				return matches, err
+			}
+		}
+		if options.Open == nil {
+			options.Open = func(name string) (io.ReadCloser, error) {
+				return fsys.Open(fsPath(name))
+			}
+		}
+	}
 	if options.Glob == nil {
 		options.Glob = filepath.Glob
 	}
 	if options.Open == nil {
 		options.Open = func(name string) (io.ReadCloser, error) {
 			file, err := os.Open(name)
-			return io.NopCloser(file), err
+			if err != nil {
+				return nil, err
+			}
+			return file, nil
 		}
 	}
 	return &Parser{options: options}
 }
 
 type ParseOptions struct {
-	SingleFile bool
-	Root       string
-	Glob       func(pattern string) (matches []string, err error)
-	Open       func(name string) (io.ReadCloser, error)
+	SingleFile  bool
+	CatchErrors bool
+	// Lossless records the original layout of every directive in its
+	// Trivia so the emitter reproduces unmodified input byte for byte.
+	Lossless bool
+	// ShareIncludes parses every included file once and shares the
+	// resulting directives between all include directives naming it. Use
+	// Unshare before modifying a shared subtree.
+	ShareIncludes bool
+	// IndexIncludes leaves the Block of include directives empty and
+	// records the files they name in Includes instead, as positions in
+	// Parser.Configs. Every file is parsed once however often it is
+	// included, and walking the tree no longer descends into includes.
+	IndexIncludes bool
+	// RecordIncludes parses only the given file: every include directive
+	// keeps an empty Block and records the files its pattern matches in
+	// IncludeFiles, to be expanded later or not at all. Unlike SingleFile
+	// the patterns are still resolved. Use Directive.ResolveInclude to
+	// parse the files of an include when they are needed.
+	RecordIncludes bool
+	// MaxIncludeDepth limits how deeply includes nest. 0 means
+	// DefaultMaxIncludeDepth.
+	MaxIncludeDepth int
+	// IncludeConcurrency is the number of files parsed at once when
+	// includes match several files, such as the vhosts of a conf.d
+	// directory. The directives keep the order of the matches. Files are
+	// parsed one at a time when it is 0 or 1, otherwise Open and Glob must
+	// be safe for concurrent use. With ShareIncludes a file included from
+	// files parsed at the same time may be parsed more than once.
+	IncludeConcurrency int
+	// OnMissingInclude is what happens to an include matching no files.
+	OnMissingInclude MissingInclude
+	// Root is the directory relative includes are resolved against.
+	Root string
+	// IncludeRelativeTo picks between Root and the directory of the
+	// including file for relative includes.
+	IncludeRelativeTo RelativeTo
+	// Prefix and ConfPath resolve files the way nginx -p and -c do and
+	// replace Root when either is set. A relative ConfPath, or file given
+	// to ParseFile, is taken relative to Prefix, and relative includes at
+	// any depth resolve against the directory of ConfPath. ConfPath
+	// defaults to the file given to ParseFile, or conf/nginx.conf for
+	// ParseReader and for ParseFile(""), as in nginx.
+	Prefix   string
+	ConfPath string
+	Glob     func(pattern string) (matches []string, err error)
+	// Open reads a file, the parser closes it once parsed.
+	Open func(name string) (io.ReadCloser, error)
+	// FS reads the configuration and included files from a file system
+	// such as an embed.FS instead of the disk, when Glob and Open are not
+	// set. Absolute paths are taken relative to the root of FS and file
+	// names keep the form they were given in.
+	FS fs.FS
+	// CommentNodes keeps every comment written between the arguments of a
+	// directive as a comment directive of its own, with its own line,
+	// placed before the directive instead of joined into its Comment.
+	CommentNodes bool
+	// Raw records the text of every directive as written in Raw.
+	Raw bool
+	// CrossplaneEscapes reads backslashes the way crossplane does, so both
+	// produce the same Args: an escaped quote inside a string with the
+	// same quotes is unescaped, every other backslash sequence is kept as
+	// written.
+	CrossplaneEscapes bool
+	// RawEscapes keeps every backslash sequence in arguments as written,
+	// as nginx does for most of them, instead of resolving \n, \t and
+	// escaped quotes. Emit with EmitOptions.RawEscapes to write them back.
+	RawEscapes bool
+	// Watermark detects the watermark EmitOptions.Watermark writes at the
+	// top of a file, records it in the FileResult of the file and leaves
+	// its comments out of the tree.
+	Watermark bool
+	// Strict checks every directive against Catalog, the documented
+	// directives when nil, and fails on unknown directives and on those
+	// used in the wrong context or with the wrong number of arguments.
+	// The contents of map, types and similar blocks are not checked.
+	Strict  bool
+	Catalog []DirectiveDoc
+	// Version makes Strict also fail on directives the given nginx release
+	// does not have. The zero Version skips this check.
+	Version Version
 }
 
+// Parser parses configurations with the options given to New. It keeps no
+// state between calls, except for the results of the last parse returned
+// by Files, Warnings and Configs, so it can be reused and used from several
+// goroutines at once.
 type Parser struct {
-	options  *ParseOptions
+	options *ParseOptions
+	mu      sync.Mutex
+	// last is the parse that finished last.
+	last *parseState
+}
+
+// parseState is what the parsers of all files read by one call of ParseFile
+// or ParseReader share.
+type parseState struct {
+	options *ParseOptions
+	ctx     context.Context
+	// root is the directory relative includes resolve against.
+	root     string
+	files    map[string]*FileResult
+	warnings []*Issue
+	shared   map[string][]*Directive
+	index    *includeIndex
+	// mu guards files, warnings and shared, which the parsers of included
+	// files add to from several goroutines with IncludeConcurrency.
+	mu *sync.Mutex
+	// workers holds a token for every goroutine parsing included files.
+	workers chan struct{}
+}
+
+func (p *Parser) newParse(ctx context.Context) *parseState {
+	return &parseState{
+		options: p.options,
+		ctx:     ctx,
+		root:    p.options.Root,
+		files:   make(map[string]*FileResult),
+		shared:  make(map[string][]*Directive),
+		mu:      &sync.Mutex{},
+		workers: make(chan struct{}, p.options.includeWorkers()),
+	}
+}
+
+// finish makes state the last parse of p.
+func (p *Parser) finish(state *parseState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.last = state
+}
+
+func (p *Parser) lastParse() *parseState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.last
+}
+
+// parser reads one file of a parse.
+type parser struct {
+	*parseState
 	filename string
 	line     int
+	includes []string
+	closing  string
+	opened   int
+	// watermark is the one found in the file being parsed.
+	watermark *Watermark
+}
+
+func (s *parseState) newParser(filename string, includes []string) *parser {
+	return &parser{parseState: s, filename: filename, includes: includes}
 }
 
 func (p *Parser) ParseFile(filename string) ([]*Directive, error) {
-	p.filename = filename
+	return p.ParseFileContext(context.Background(), filename)
+}
+
+// ParseFileContext parses like ParseFile but gives up once ctx is done,
+// checked before every file and while reading them, with an error of kind
+// ErrCanceled.
+func (p *Parser) ParseFileContext(ctx context.Context, filename string) ([]*Directive, error) {
+	state := p.newParse(ctx)
+	defer p.finish(state)
+	if p.options.Prefix != "" || p.options.ConfPath != "" {
+		filename, state.root = p.options.nginxPaths(filename)
+	}
+	if p.options.IndexIncludes {
+		state.index = newIncludeIndex(filename)
+	}
+	parser := state.newParser(filename, nil)
+	directives, err := parser.parseFile()
+	if err == nil && state.index != nil {
+		err = parser.parseIndexed()
+	}
+	if err == nil && p.options.Strict {
+		err = parser.checkStrict(directives)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return directives, nil
+}
+
+// parseFile reads the file of p.
+func (p *parser) parseFile() ([]*Directive, error) {
+	if err := p.ctx.Err(); err != nil {
+		err = p.wrapError(nil, err)
+		p.record(p.filename, nil, err)
+		return nil, err
+	}
 	file, err := p.options.Open(p.filename)
 	if err != nil {
+		err = p.wrapError(nil, err)
+		p.record(p.filename, nil, err)
+		return nil, err
+	}
+	defer file.Close()
+	reader, err := newSourceReader(p.ctx, file, p.options.Lossless || p.options.Raw || p.options.Watermark)
+	if err != nil {
+		err = p.wrapError(nil, err)
+		p.record(p.filename, nil, err)
 		return nil, err
 	}
-	return p.ParseReader(file)
+	directives, err := p.parse(reader)
+	result := p.record(p.filename, directives, err)
+	result.LineEnding, result.Watermark = reader.lineEnding(), p.watermark
+	return directives, err
+}
+
+// includeWorkers is the number of goroutines parsing included files next
+// to the one that started the parse.
+func (o *ParseOptions) includeWorkers() int {
+	if o.IncludeConcurrency <= 1 {
+		return 0
+	}
+	return o.IncludeConcurrency - 1
+}
+
+// defaultConfPath is where nginx looks for its configuration file under
+// the prefix when -c is not given.
+const defaultConfPath = "conf/nginx.conf"
+
+// nginxPaths applies Prefix and ConfPath to the file given to ParseFile,
+// returning the file to read and the directory relative includes resolve
+// against.
+func (o *ParseOptions) nginxPaths(filename string) (file, root string) {
+	resolve := func(name string) string {
+		if o.Prefix != "" && !strings.HasPrefix(name, "/") {
+			return path.Join(o.Prefix, name)
+		}
+		return name
+	}
+	conf := o.ConfPath
+	if conf == "" {
+		conf = filename
+	}
+	if conf == "" {
+		conf = defaultConfPath
+	}
+	if filename == "" {
+		filename = conf
+	}
+	return resolve(filename), path.Dir(resolve(conf))
+}
+
+// ParseFS parses the configuration tree under the root directory of fsys,
+// such as one embedded with //go:embed, starting from the entry file.
+// Relative includes are resolved against root.
+func ParseFS(fsys fs.FS, root, entry string) ([]*Directive, error) {
+	if root == "" {
+		root = "."
+	}
+	return New(&ParseOptions{FS: fsys, Root: root}).ParseFile(path.Join(root, entry))
 }
 
 func (p *Parser) ParseString(s string) ([]*Directive, error) {
@@ -64,24 +376,79 @@ func (p *Parser) ParseString(s string) ([]*Directive, error) {
 }
 
 func (p *Parser) ParseReader(rd io.Reader) ([]*Directive, error) {
-	reader := bufio.NewReader(rd)
-	p.line = 1
-	directives, err := p.parseReader(reader)
+	return p.ParseReaderContext(context.Background(), rd)
+}
+
+// ParseReaderContext parses like ParseReader but gives up once ctx is
+// done, like ParseFileContext.
+func (p *Parser) ParseReaderContext(ctx context.Context, rd io.Reader) ([]*Directive, error) {
+	state := p.newParse(ctx)
+	defer p.finish(state)
+	parser := state.newParser("", nil)
+	if err := ctx.Err(); err != nil {
+		return nil, parser.wrapError(nil, err)
+	}
+	if p.options.IndexIncludes {
+		state.index = newIncludeIndex("")
+	}
+	if p.options.Prefix != "" || p.options.ConfPath != "" {
+		_, state.root = p.options.nginxPaths("")
+	}
+	reader, err := newSourceReader(ctx, rd, p.options.Lossless || p.options.Raw || p.options.Watermark)
+	if err != nil {
+		return nil, parser.wrapError(nil, err)
+	}
+	directives, err := parser.parse(reader)
+	if err == nil && (state.index != nil || parser.watermark != nil) {
+		result := parser.record("", directives, nil)
+		result.LineEnding, result.Watermark = reader.lineEnding(), parser.watermark
+	}
+	if err == nil && state.index != nil {
+		err = parser.parseIndexed()
+	}
+	if err == nil && p.options.Strict {
+		err = parser.checkStrict(directives)
+	}
 	if err != nil {
 		return nil, err
 	}
+	return directives, nil
+}
+
+func (p *parser) parse(reader *sourceReader) ([]*Directive, error) {
+	p.line, p.opened, p.watermark = 1, 0, nil
+	directives, err := p.parseReader(reader)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, p.syntaxError(`unexpected end of file %s line %d`, p.filename, p.line)
+	}
+	if err != nil {
+		return nil, p.wrapError(nil, err)
+	}
 	for {
 		b, err := reader.ReadByte()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return nil, err
+			return nil, p.wrapError(nil, err)
 		}
 		if unicode.IsSpace(rune(b)) {
 			continue
 		}
-		return nil, fmt.Errorf(`unexpected end in file %s line %d`, p.filename, p.line)
+		return nil, p.syntaxError(`unexpected end in file %s line %d`, p.filename, p.line)
+	}
+	if p.options.Watermark {
+		if _, watermark := splitWatermark(reader.src); watermark != nil {
+			p.watermark = watermark
+			directives = stripWatermark(directives)
+		}
+	}
+	annotate(directives, nil)
+	attachComments(directives, nil)
+	setParents(directives, nil)
+	if len(directives) > 0 && directives[len(directives)-1].Trivia != nil {
+		last := directives[len(directives)-1].Trivia
+		last.Trailing, last.End = p.closing, true
 	}
 	return directives, nil
 }
@@ -91,19 +458,49 @@ const (
 	stateScanArgs      = "ScanArgs"
 )
 
-func (p *Parser) parseReader(reader *bufio.Reader) ([]*Directive, error) {
+// blankLines counts the blank lines between a directive starting on line
+// and the one before it ending on previous.
+func blankLines(previous, line int) int {
+	if line-previous > 1 {
+		return line - previous - 1
+	}
+	return 0
+}
+
+func (p *parser) parseReader(reader *sourceReader) ([]*Directive, error) {
 	directives := make([]*Directive, 0)
 
 	var buf bytes.Buffer
 	var current *Directive
+	// comments holds the comments written between the arguments of
+	// current with ParseOptions.CommentNodes.
+	var comments []*Directive
+	var argStart Position
 	state := stateScanDirective
+	gap, start := reader.offset, -1
+	// endLine is the line the last directive, or the opening brace of
+	// this block, ended on, to tell trailing comments from standalone ones.
+	endLine := p.opened
+	// previous is the line the last directive or comment ended on, to
+	// count the blank lines above the next one.
+	previous := p.opened
 
 readConfBlock:
 	for {
 		b, err := reader.ReadByte()
 		if err == io.EOF {
+			p.closing = p.source(reader, gap, reader.offset)
 			return directives, nil
 		}
+		if err != nil {
+			return nil, err
+		}
+		if start < 0 && current == nil && state == stateScanDirective && buf.Len() == 0 && !unicode.IsSpace(rune(b)) && b != ';' && b != '}' {
+			start = reader.offset - 1
+		}
+		if state == stateScanArgs && buf.Len() == 0 && !unicode.IsSpace(rune(b)) && b != ';' && b != '{' && b != '}' && b != '#' {
+			argStart = Position{Line: p.line, Column: reader.offset - reader.lineStart, Offset: reader.offset - 1}
+		}
 
 		if buf.Len() == 0 {
 			switch b {
@@ -115,17 +512,24 @@ readConfBlock:
 						FileName:  p.filename,
 						Directive: "#",
 						Args:      make([]string, 0),
-						Block:     make([]*Directive, 0),
+						Inline:    endLine == p.line,
 					}
 				}
-				p.line++
-				if len(current.Comment) != 0 {
-					current.Comment += " "
+				if current.Directive != "#" && p.options.CommentNodes {
+					comments = append(comments, p.commentNode(reader, string(comment)))
+				} else {
+					if len(current.Comment) != 0 {
+						current.Comment += " "
+					}
+					current.Comment += string(comment)
 				}
-				current.Comment += string(comment)
+				p.line++
 				if current.Directive == "#" {
+					p.layout(reader, current, gap, start, reader.offset-reader.newline)
+					current.BlankLinesBefore, previous = blankLines(previous, current.Line), current.Line
 					directives = append(directives, current)
 					current = nil
+					gap, start = reader.offset-reader.newline, -1
 				}
 				continue
 			case '/':
@@ -142,17 +546,24 @@ readConfBlock:
 							FileName:  p.filename,
 							Directive: "#",
 							Args:      make([]string, 0),
-							Block:     make([]*Directive, 0),
+							Inline:    endLine == p.line,
 						}
 					}
-					p.line++
-					if len(current.Comment) != 0 {
-						current.Comment += " "
+					if current.Directive != "#" && p.options.CommentNodes {
+						comments = append(comments, p.commentNode(reader, string(comment)))
+					} else {
+						if len(current.Comment) != 0 {
+							current.Comment += " "
+						}
+						current.Comment += string(comment)
 					}
-					current.Comment += string(comment)
+					p.line++
 					if current.Directive == "#" {
+						p.layout(reader, current, gap, start, reader.offset-reader.newline)
+						current.BlankLinesBefore, previous = blankLines(previous, current.Line), current.Line
 						directives = append(directives, current)
 						current = nil
+						gap, start = reader.offset-reader.newline, -1
 					}
 				} else {
 					buf.WriteByte('/')
@@ -172,7 +583,6 @@ readConfBlock:
 							FileName:  p.filename,
 							Directive: buf.String(),
 							Args:      make([]string, 0),
-							Block:     make([]*Directive, 0),
 						}
 					}
 					buf.Reset()
@@ -180,7 +590,7 @@ readConfBlock:
 				}
 			case stateScanArgs:
 				if buf.Len() > 0 {
-					current.Args = append(current.Args, buf.String())
+					current.appendArg(buf.String(), QuoteNone, argStart)
 					buf.Reset()
 				}
 			}
@@ -194,7 +604,6 @@ readConfBlock:
 							FileName:  p.filename,
 							Directive: buf.String(),
 							Args:      make([]string, 0),
-							Block:     make([]*Directive, 0),
 						}
 					}
 					buf.Reset()
@@ -204,7 +613,7 @@ readConfBlock:
 			case stateScanArgs:
 				p.line++
 				if buf.Len() > 0 {
-					current.Args = append(current.Args, buf.String())
+					current.appendArg(buf.String(), QuoteNone, argStart)
 					buf.Reset()
 				}
 			}
@@ -213,6 +622,14 @@ readConfBlock:
 			if err != nil {
 				return nil, err
 			}
+			if p.options.CrossplaneEscapes || p.options.RawEscapes {
+				if nb == '\n' {
+					p.line++
+				}
+				buf.WriteByte(b)
+				buf.WriteByte(nb)
+				continue
+			}
 			switch nb {
 			case '"', '\'', '\\':
 				b = nb
@@ -254,6 +671,14 @@ readConfBlock:
 						if err != nil {
 							return nil, err
 						}
+						if p.options.RawEscapes || (p.options.CrossplaneEscapes && nnb != b) {
+							if nnb == '\n' {
+								p.line++
+							}
+							buf.WriteRune(nr)
+							buf.WriteByte(nnb)
+							continue
+						}
 						switch nnb {
 						case '"', '\'', '\\':
 							nr = rune(nnb)
@@ -278,7 +703,6 @@ readConfBlock:
 						FileName:  p.filename,
 						Directive: buf.String(),
 						Args:      make([]string, 0),
-						Block:     make([]*Directive, 0),
 					}
 					buf.Reset()
 					state = stateScanArgs
@@ -304,7 +728,7 @@ readConfBlock:
 						break
 					}
 
-					current.Args = append(current.Args, buf.String())
+					current.appendArg(buf.String(), Quote(b), argStart)
 					buf.Reset()
 					break readString
 				}
@@ -313,53 +737,43 @@ readConfBlock:
 			switch state {
 			case stateScanDirective:
 				if buf.Len() > 0 {
-					directives = append(directives, &Directive{
+					current = &Directive{
 						Line:      p.line,
 						FileName:  p.filename,
 						Directive: buf.String(),
 						Args:      make([]string, 0),
-						Block:     make([]*Directive, 0),
-					})
+					}
+					p.layout(reader, current, gap, start, reader.offset)
+					current.BlankLinesBefore, previous = blankLines(previous, current.Line), p.line
+					directives = append(directives, current)
 					current = nil
 					buf.Reset()
+					gap, start, endLine = reader.offset, -1, p.line
 				}
 			case stateScanArgs:
 				if buf.Len() > 0 {
-					current.Args = append(current.Args, buf.String())
+					current.appendArg(buf.String(), QuoteNone, argStart)
 				}
 
 				if !p.options.SingleFile && current.Directive == "include" {
-					for _, arg := range current.Args {
-						if !strings.HasPrefix(arg, "/") {
-							if p.options.Root == "" {
-								return nil, fmt.Errorf("not found `root` dir in options")
-							}
-							arg = path.Join(p.options.Root, arg)
-						}
-						filenames, err := p.options.Glob(arg)
-						if err != nil {
-							return nil, err
-						}
-						for _, filename := range filenames {
-							blockDirectives, err := New(p.options).ParseFile(filename)
-							if err != nil {
-								return nil, err
-							}
-							current.Block = append(current.Block, blockDirectives...)
-						}
+					if err := p.include(current); err != nil {
+						return nil, err
 					}
 				}
 
-				directives = append(directives, current)
-				current = nil
+				p.layout(reader, current, gap, start, reader.offset)
+				current.BlankLinesBefore, previous = blankLines(previous, current.Line), p.line
+				directives = append(append(directives, comments...), current)
+				current, comments = nil, nil
 				buf.Reset()
 				state = stateScanDirective
+				gap, start, endLine = reader.offset, -1, p.line
 			}
 		case '{':
 			switch state {
 			case stateScanDirective:
 				if buf.Len() == 0 {
-					return nil, fmt.Errorf(`unexpected '%c' in file %s line %d`, b, p.filename, p.line)
+					return nil, p.syntaxError(`unexpected '%c' in file %s line %d`, b, p.filename, p.line)
 				}
 
 				current = &Directive{
@@ -368,20 +782,29 @@ readConfBlock:
 					Directive: buf.String(),
 					Args:      make([]string, 0),
 				}
+				p.layout(reader, current, gap, start, reader.offset)
+				p.opened = p.line
 				current.Block, err = p.parseReader(reader)
 				if err != nil {
 					return nil, err
 				}
+				current.Range.End = reader.offset
+				if current.Trivia != nil {
+					current.Trivia.Close = p.closing
+				}
+				current.BlankLinesBefore, previous = blankLines(previous, current.Line), p.line
 				directives = append(directives, current)
 				current = nil
 				buf.Reset()
+				gap, start, endLine = reader.offset, -1, p.line
 			case stateScanArgs:
 				if buf.Len() > 0 {
-					current.Args = append(current.Args, buf.String())
+					current.appendArg(buf.String(), QuoteNone, argStart)
 				}
 
 				buf.Reset()
 				if strings.HasSuffix(current.Directive, "_by_lua_block") {
+					argStart = Position{Line: p.line, Column: reader.offset - reader.lineStart + 1, Offset: reader.offset}
 					depth := 0
 				readLuaBlock:
 					for {
@@ -438,7 +861,8 @@ readConfBlock:
 						}
 						buf.WriteByte(b)
 					}
-					current.Args = append(current.Args, strings.TrimRightFunc(buf.String(), unicode.IsSpace))
+					current.appendArg(strings.TrimRightFunc(buf.String(), unicode.IsSpace), QuoteNone, argStart)
+					p.layout(reader, current, gap, start, reader.offset)
 				} else {
 					if current.Directive == "if" {
 						lastArgIndex := len(current.Args) - 1
@@ -447,31 +871,44 @@ readConfBlock:
 							current.Args[lastArgIndex] = strings.TrimRightFunc(strings.TrimSuffix(current.Args[lastArgIndex], ")"), unicode.IsSpace)
 							if len(current.Args[0]) == 0 {
 								current.Args = current.Args[1:]
+								current.Quotes = current.Quotes[1:]
+								current.ArgPositions = current.ArgPositions[1:]
 								lastArgIndex -= 1
 							}
 							if len(current.Args[lastArgIndex]) == 0 {
 								current.Args = current.Args[:lastArgIndex]
+								current.Quotes = current.Quotes[:lastArgIndex]
+								current.ArgPositions = current.ArgPositions[:lastArgIndex]
 							}
 						}
 					}
 
+					p.layout(reader, current, gap, start, reader.offset)
+					p.opened = p.line
 					current.Block, err = p.parseReader(reader)
 					if err != nil {
 						return nil, err
 					}
+					current.Range.End = reader.offset
+					if current.Trivia != nil {
+						current.Trivia.Close = p.closing
+					}
 				}
 
-				directives = append(directives, current)
-				current = nil
+				current.BlankLinesBefore, previous = blankLines(previous, current.Line), p.line
+				directives = append(append(directives, comments...), current)
+				current, comments = nil, nil
 				buf.Reset()
 				state = stateScanDirective
+				gap, start, endLine = reader.offset, -1, p.line
 			}
 		case '}':
 			switch state {
 			case stateScanDirective:
+				p.closing = p.source(reader, gap, reader.offset)
 				break readConfBlock
 			case stateScanArgs:
-				return nil, fmt.Errorf(`unexpected '%c' in file %s line %d`, b, p.filename, p.line)
+				return nil, p.syntaxError(`unexpected '%c' in file %s line %d`, b, p.filename, p.line)
 			}
 		case '$':
 			buf.WriteByte(b)
@@ -2,10 +2,19 @@ package nginxparser
 
 import (
 	"bytes"
+	"context"
+	"embed"
 	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
 	"path/filepath"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/fstest"
 	"text/template"
 )
 
@@ -18,7 +27,7 @@ const (
 		FileName:  {{ quote $directive.FileName }},
 		Directive: {{ quote $directive.Directive }},
 {{ if ne (len $directive.Args) 0 }} Args: []string{ {{- range $arg := $directive.Args }} {{ quote $arg }}, {{- end }} }, {{ end }} 
-{{ if ne (len $directive.Block) 0 }} Block: {{ template "Directives" $directive.Block }} {{ end }} 
+{{ if ne (len $directive.Block) 0 }} Block: {{ template "Directives" $directive.Block }} {{ else if isBlock $directive }} Block: []*Directive{}, {{ end }} 
 {{ if ne (len $directive.Comment) 0 }} Comment: {{ quote $directive.Comment }},  {{ end }} 
 	},
 	{{- end }}
@@ -30,7 +39,8 @@ const (
 func buildFixture(directives []*Directive) (string, error) {
 	var buf bytes.Buffer
 	err := template.Must(template.New("fixtureTemplate").Funcs(map[string]interface{}{
-		"quote": strconv.Quote,
+		"quote":   strconv.Quote,
+		"isBlock": isBlock,
 	}).Parse(fixtureTemplate)).Execute(&buf, directives)
 	return buf.String(), err
 }
@@ -55,11 +65,13 @@ func TestParse(t *testing.T) {
 					Line:      2,
 					FileName:  "testdata/bad-args/nginx.conf",
 					Directive: "events",
+					Block:     []*Directive{},
 				},
 				{
 					Line:      3,
 					FileName:  "testdata/bad-args/nginx.conf",
 					Directive: "http",
+					Block:     []*Directive{},
 				},
 			},
 		},
@@ -95,6 +107,7 @@ func TestParse(t *testing.T) {
 					Line:      1,
 					FileName:  "testdata/directive-with-space/nginx.conf",
 					Directive: "events",
+					Block:     []*Directive{},
 				},
 				{
 					Line:      3,
@@ -132,6 +145,7 @@ func TestParse(t *testing.T) {
 					Line:      1,
 					FileName:  "testdata/empty-value-map/nginx.conf",
 					Directive: "events",
+					Block:     []*Directive{},
 				},
 				{
 					Line:      3,
@@ -172,6 +186,7 @@ func TestParse(t *testing.T) {
 					Line:      1,
 					FileName:  "testdata/includes-globbed/nginx.conf",
 					Directive: "events",
+					Block:     []*Directive{},
 				},
 				{
 					Line:      2,
@@ -306,6 +321,7 @@ func TestParse(t *testing.T) {
 					Line:      1,
 					FileName:  "testdata/includes-regular/nginx.conf",
 					Directive: "events",
+					Block:     []*Directive{},
 				},
 				{
 					Line:      2,
@@ -699,18 +715,21 @@ func TestParse(t *testing.T) {
 									FileName:  "testdata/messy/nginx.conf",
 									Directive: "location",
 									Args:      []string{"/foo"},
+									Block:     []*Directive{},
 								},
 								{
 									Line:      15,
 									FileName:  "testdata/messy/nginx.conf",
 									Directive: "location",
 									Args:      []string{"/bar"},
+									Block:     []*Directive{},
 								},
 								{
 									Line:      16,
 									FileName:  "testdata/messy/nginx.conf",
 									Directive: "location",
 									Args:      []string{"/{;} # ab"},
+									Block:     []*Directive{},
 								},
 								{
 									Line:      16,
@@ -723,6 +742,7 @@ func TestParse(t *testing.T) {
 									FileName:  "testdata/messy/nginx.conf",
 									Directive: "if",
 									Args:      []string{"$request_method", "=", "P{O)###;ST"},
+									Block:     []*Directive{},
 								},
 								{
 									Line:      18,
@@ -772,6 +792,7 @@ func TestParse(t *testing.T) {
 							Line:      24,
 							FileName:  "testdata/messy/nginx.conf",
 							Directive: "server",
+							Block:     []*Directive{},
 						},
 					},
 				},
@@ -807,6 +828,7 @@ func TestParse(t *testing.T) {
 					Line:      1,
 					FileName:  "testdata/quoted-right-brace/nginx.conf",
 					Directive: "events",
+					Block:     []*Directive{},
 				},
 				{
 					Line:      2,
@@ -836,6 +858,7 @@ func TestParse(t *testing.T) {
 					Line:      2,
 					FileName:  "testdata/russian-text/nginx.conf",
 					Directive: "events",
+					Block:     []*Directive{},
 				},
 			},
 		},
@@ -976,6 +999,7 @@ func TestParse(t *testing.T) {
 					Line:      1,
 					FileName:  "testdata/spelling-mistake/nginx.conf",
 					Directive: "events",
+					Block:     []*Directive{},
 				},
 				{
 					Line:      3,
@@ -1123,3 +1147,199 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"etc/nginx/nginx.conf":         {Data: []byte("http {\n    include conf.d/*.conf;\n    include /etc/nginx/mime.types;\n}\n")},
+		"etc/nginx/conf.d/a.conf":      {Data: []byte("server { listen 80; }\n")},
+		"etc/nginx/conf.d/b.conf":      {Data: []byte("server { listen 81; }\n")},
+		"etc/nginx/mime.types":         {Data: []byte("types { text/html html; }\n")},
+		"etc/nginx/conf.d/ignored.txt": {Data: []byte("broken {")},
+	}
+	directives, err := New(&ParseOptions{FS: fsys, Root: "/etc/nginx"}).ParseFile("/etc/nginx/nginx.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := directives[0].Children("server")
+	if len(servers) != 2 || servers[1].FirstChild("listen").ArgAt(0) != "81" || servers[1].FileName != "/etc/nginx/conf.d/b.conf" {
+		t.Fatalf("unexpected servers %+v", servers)
+	}
+	if directives[0].FirstChild("types") == nil {
+		t.Fatal("expected the absolute include to be read from the file system")
+	}
+	if _, err := New(&ParseOptions{FS: fsys}).ParseFile("missing.conf"); err == nil {
+		t.Fatal("expected a missing file to fail")
+	}
+}
+
+//go:embed testdata/includes-globbed
+var includesGlobbed embed.FS
+
+func TestParseEmbedFS(t *testing.T) {
+	directives, err := ParseFS(includesGlobbed, "testdata/includes-globbed", "nginx.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	http := findFirst(directives, "http")
+	if http == nil {
+		t.Fatal("expected http.conf to be included")
+	}
+	servers := findAll(http.Block, "server")
+	if len(servers) != 2 || servers[0].FileName != "testdata/includes-globbed/servers/server1.conf" {
+		t.Fatalf("unexpected servers %+v", servers)
+	}
+	if len(findAll(servers[0].Block, "location")) != 2 {
+		t.Fatalf("expected the globbed locations to be included, got %+v", servers[0].Block)
+	}
+	if _, err := ParseFS(includesGlobbed, "testdata/includes-globbed", "missing.conf"); err == nil {
+		t.Fatal("expected a missing entry to fail")
+	}
+}
+
+// endlessConfig yields "events {}" blocks forever and cancels its context
+// after the given number of reads.
+type endlessConfig struct {
+	cancel context.CancelFunc
+	reads  int
+}
+
+func (r *endlessConfig) Read(b []byte) (int, error) {
+	r.reads--
+	if r.reads == 0 {
+		r.cancel()
+	}
+	n := 0
+	for n+10 <= len(b) {
+		n += copy(b[n:], "events {}\n")
+	}
+	return n, nil
+}
+
+func TestParseContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := New(nil).ParseFileContext(ctx, "testdata/simple/nginx.conf")
+	if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected ErrCanceled but got %v", err)
+	}
+
+	for _, lossless := range []bool{false, true} {
+		ctx, cancel = context.WithCancel(context.Background())
+		_, err = New(&ParseOptions{SingleFile: true, Lossless: lossless}).ParseReaderContext(ctx, &endlessConfig{cancel: cancel, reads: 3})
+		if !errors.Is(err, ErrCanceled) {
+			t.Fatalf("expected an endless config to be canceled but got %v", err)
+		}
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	opened := make([]string, 0)
+	parser := New(&ParseOptions{
+		Root:        "testdata/includes-globbed",
+		CatchErrors: true,
+		Open: func(name string) (io.ReadCloser, error) {
+			opened = append(opened, name)
+			if len(opened) == 2 {
+				cancel()
+			}
+			return os.Open(name)
+		},
+	})
+	_, err = parser.ParseFileContext(ctx, "testdata/includes-globbed/nginx.conf")
+	if !errors.Is(err, context.Canceled) || len(opened) != 2 {
+		t.Fatalf("expected the parse to stop after the first include even with CatchErrors, got %v after %v", err, opened)
+	}
+}
+
+func TestParserConcurrentUse(t *testing.T) {
+	fsys := fstest.MapFS{
+		"nginx.conf":    {Data: []byte("http {\n    include conf.d/*.conf;\n}\n")},
+		"conf.d/a.conf": {Data: []byte("server {\n    listen 80;\n}\n")},
+		"broken.conf":   {Data: []byte("}\nevents {}\n")},
+	}
+	parser := New(&ParseOptions{FS: fsys, Root: "."})
+	if _, err := parser.ParseFile("broken.conf"); err == nil {
+		t.Fatal("expected broken.conf to fail")
+	}
+	var parseErr *ParseError
+	if _, err := parser.ParseString("}\nevents {}\n"); !errors.As(err, &parseErr) || parseErr.FileName != "" {
+		t.Fatalf("expected the error of a reader without the file of the last parse, got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			directives, err := parser.ParseFile("nginx.conf")
+			if err == nil && GetString(directives, "http.server.listen") != "80" {
+				err = errors.New("expected the included server")
+			}
+			errs <- err
+		}()
+		go func(i int) {
+			defer wg.Done()
+			directives, err := parser.ParseString("worker_processes " + strconv.Itoa(i) + ";")
+			if err == nil && GetString(directives, "worker_processes") != strconv.Itoa(i) {
+				err = errors.New("expected the directives of the string")
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// closeCountingFS counts the files opened from it that are still open.
+type closeCountingFS struct {
+	fs.FS
+	open *int32
+}
+
+type closeCountingFile struct {
+	fs.File
+	open *int32
+}
+
+func (c closeCountingFS) Open(name string) (fs.File, error) {
+	file, err := c.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt32(c.open, 1)
+	return closeCountingFile{File: file, open: c.open}, nil
+}
+
+func (f closeCountingFile) Close() error {
+	atomic.AddInt32(f.open, -1)
+	return f.File.Close()
+}
+
+func TestParseClosesFiles(t *testing.T) {
+	var open int32
+	fsys := closeCountingFS{FS: os.DirFS("testdata/includes-globbed"), open: &open}
+	for _, concurrency := range []int{0, 4} {
+		directives, err := New(&ParseOptions{FS: fsys, Root: ".", IncludeConcurrency: concurrency}).ParseFile("nginx.conf")
+		if err != nil || len(directives) == 0 {
+			t.Fatal(err)
+		}
+		if open != 0 {
+			t.Fatalf("expected every file to be closed, %d are open", open)
+		}
+	}
+
+	file, err := New(nil).options.Open(filepath.Join("testdata", "includes-globbed", "nginx.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, ok := file.(*os.File); !ok {
+		t.Fatalf("expected the default Open to return the file, got %T", file)
+	}
+}
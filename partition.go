@@ -0,0 +1,83 @@
+package nginxparser
+
+import (
+	"strings"
+)
+
+type PartitionOptions struct {
+	// Tenant names the tenant a server belongs to. By default servers are
+	// grouped by the last two labels of their first server_name, servers
+	// without a name go to the tenant "".
+	Tenant func(server *Directive) string
+}
+
+func serverNameTenant(server *Directive) string {
+	names := serverNames(server)
+	if len(names) == 0 {
+		return ""
+	}
+	name := strings.Trim(strings.TrimPrefix(names[0], "~"), ".*")
+	labels := strings.Split(name, ".")
+	if len(labels) > 2 {
+		labels = labels[len(labels)-2:]
+	}
+	return strings.Join(labels, ".")
+}
+
+// Partition splits a configuration into one configuration per tenant.
+// Each holds a copy of everything outside the http server blocks, so the
+// shared http settings, upstreams and maps carry over, and only the
+// servers of its tenant. Include directives are kept, emit with
+// EmitOptions.Flatten for self-contained files. The copies record where
+// they were copied from in their Provenance.
+func Partition(directives []*Directive, options *PartitionOptions) map[string][]*Directive {
+	if options == nil {
+		options = &PartitionOptions{}
+	}
+	if options.Tenant == nil {
+		options.Tenant = serverNameTenant
+	}
+
+	tenants := make(map[string]bool)
+	labels := make(map[*Directive]string)
+	for _, server := range servers(directives) {
+		labels[server] = options.Tenant(server)
+		tenants[labels[server]] = true
+	}
+
+	result := make(map[string][]*Directive)
+	for tenant := range tenants {
+		result[tenant] = partitionBlock(directives, func(server *Directive) bool {
+			return labels[server] == tenant
+		}, false)
+		for _, d := range result[tenant] {
+			MarkMoved(d)
+		}
+		SetParents(result[tenant])
+	}
+	return result
+}
+
+// partitionBlock copies a block, dropping the http servers keep rejects.
+func partitionBlock(directives []*Directive, keep func(server *Directive) bool, http bool) []*Directive {
+	if directives == nil {
+		return nil
+	}
+	result := make([]*Directive, 0, len(directives))
+	for _, d := range directives {
+		if http && d.Directive == "server" && !keep(d) {
+			continue
+		}
+		clone := cloneDirectives([]*Directive{d})[0]
+		switch {
+		case d.Directive == "http":
+			clone.Block = partitionBlock(d.Block, keep, true)
+		case d.Directive == "include":
+			clone.Block = partitionBlock(d.Block, keep, http)
+		case d.Directive != "server":
+			clone.Block = partitionBlock(d.Block, keep, false)
+		}
+		result = append(result, clone)
+	}
+	return result
+}
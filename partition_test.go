@@ -0,0 +1,65 @@
+package nginxparser
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPartition(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+user nginx;
+http {
+    gzip on;
+    upstream app { server 10.0.0.1; }
+    server { server_name a.example.com; location / { proxy_pass http://app; } }
+    server { server_name www.example.org example.org; }
+    server { server_name b.example.com; }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenants := Partition(directives, nil)
+	if len(tenants) != 2 {
+		t.Fatalf("unexpected tenants %v", tenants)
+	}
+	var buf bytes.Buffer
+	if err := Dump(tenants["example.com"], &buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `user nginx;
+http {
+    gzip on;
+    upstream app {
+        server 10.0.0.1;
+    }
+    server {
+        server_name a.example.com;
+        location / {
+            proxy_pass http://app;
+        }
+    }
+    server {
+        server_name b.example.com;
+    }
+}
+`
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+	if servers := servers(tenants["example.org"]); len(servers) != 1 || serverNames(servers[0])[0] != "www.example.org" {
+		t.Fatalf("unexpected servers %+v", servers)
+	}
+
+	tenants["example.com"][1].Block[0].Args[0] = "off"
+	if directives[1].Block[0].Args[0] != "on" {
+		t.Fatal("partitions must not share directives with the original")
+	}
+
+	tenants = Partition(directives, &PartitionOptions{Tenant: func(server *Directive) string {
+		return "all"
+	}})
+	if len(tenants) != 1 || len(servers(tenants["all"])) != 3 {
+		t.Fatalf("unexpected tenants %v", tenants)
+	}
+}
@@ -0,0 +1,134 @@
+package nginxparser
+
+import (
+	"net"
+	"strings"
+)
+
+var passDirectives = map[string]bool{
+	"proxy_pass":     true,
+	"fastcgi_pass":   true,
+	"uwsgi_pass":     true,
+	"scgi_pass":      true,
+	"grpc_pass":      true,
+	"memcached_pass": true,
+}
+
+const (
+	ResolveUpstream   = "upstream"
+	ResolveAddress    = "address"
+	ResolveUnix       = "unix"
+	ResolveStartup    = "startup"
+	ResolveRuntime    = "runtime"
+	ResolveUnresolved = "unresolved"
+)
+
+type PassTarget struct {
+	Directive  *Directive `json:"-"`
+	Target     string     `json:"target"`
+	Scheme     string     `json:"scheme,omitempty"`
+	Host       string     `json:"host"`
+	Port       string     `json:"port,omitempty"`
+	Variables  bool       `json:"variables"`
+	Resolver   *Directive `json:"-"`
+	Resolution string     `json:"resolution"`
+}
+
+// uriVariables always start with a slash, so they terminate the host part.
+var uriVariables = []string{"$request_uri", "$document_uri", "$uri"}
+
+// splitPassTarget breaks a *_pass argument such as http://backend:8080/api
+// into its scheme, host and port. Unix sockets are returned with the unix:
+// prefix as host.
+func splitPassTarget(target string) (scheme, host, port string) {
+	if i := strings.Index(target, "://"); i >= 0 {
+		scheme, target = target[:i], target[i+3:]
+	}
+	if strings.HasPrefix(target, "unix:") {
+		if i := strings.LastIndexByte(target, ':'); i > len("unix") {
+			target = target[:i]
+		}
+		return scheme, target, ""
+	}
+	if i := strings.IndexByte(target, '/'); i >= 0 {
+		target = target[:i]
+	}
+	for _, name := range uriVariables {
+		if i := strings.Index(target, name); i >= 0 && (i+len(name) == len(target) || !isVariableByte(target[i+len(name)])) {
+			target = target[:i]
+		}
+	}
+	if strings.HasPrefix(target, "[") {
+		if i := strings.IndexByte(target, ']'); i >= 0 {
+			host, target = target[1:i], target[i+1:]
+			return scheme, host, strings.TrimPrefix(target, ":")
+		}
+	}
+	if i := strings.LastIndexByte(target, ':'); i >= 0 {
+		return scheme, target[:i], target[i+1:]
+	}
+	return scheme, target, ""
+}
+
+// PassTargets lists every proxy_pass style directive together with how
+// nginx resolves its host: through an upstream group, as a literal address,
+// once at startup through the system resolver, or per request through the
+// resolver configured in scope.
+func PassTargets(directives []*Directive) []*PassTarget {
+	groups := make(map[string]bool)
+	for _, upstream := range upstreams(directives) {
+		groups[upstreamName(upstream)] = true
+	}
+
+	result := make([]*PassTarget, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if !passDirectives[d.Directive] || len(d.Args) == 0 {
+			return true
+		}
+		pass := &PassTarget{
+			Directive: d,
+			Target:    d.Args[0],
+			Variables: len(Variables(d.Args[0])) > 0,
+		}
+		pass.Scheme, pass.Host, pass.Port = splitPassTarget(pass.Target)
+		pass.Resolver = lookupInherited("resolver", nil, parents)
+
+		switch {
+		case strings.HasPrefix(pass.Host, "unix:"):
+			pass.Resolution = ResolveUnix
+		case len(Variables(pass.Host)) == 0 && net.ParseIP(pass.Host) != nil:
+			pass.Resolution = ResolveAddress
+		case len(Variables(pass.Host)) == 0 && groups[pass.Host]:
+			pass.Resolution = ResolveUpstream
+		case !pass.Variables:
+			pass.Resolution = ResolveStartup
+		case pass.Resolver != nil:
+			pass.Resolution = ResolveRuntime
+		default:
+			pass.Resolution = ResolveUnresolved
+		}
+		result = append(result, pass)
+		return true
+	})
+	return result
+}
+
+func checkPassResolver(directives []*Directive) []*Issue {
+	issues := make([]*Issue, 0)
+	for _, pass := range PassTargets(directives) {
+		if pass.Resolution == ResolveUnresolved {
+			issues = append(issues, newIssue("pass-resolver", pass.Directive, "%s %s contains variables but no resolver is configured, requests fail with 502 unless the host names an upstream", pass.Directive.Directive, pass.Target))
+		}
+	}
+	return issues
+}
+
+func checkPassStartupDNS(directives []*Directive) []*Issue {
+	issues := make([]*Issue, 0)
+	for _, pass := range PassTargets(directives) {
+		if pass.Resolution == ResolveStartup {
+			issues = append(issues, newIssue("pass-startup-dns", pass.Directive, "%s host %s is resolved once at startup, DNS changes are ignored until reload", pass.Directive.Directive, pass.Host))
+		}
+	}
+	return issues
+}
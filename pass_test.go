@@ -0,0 +1,63 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestSplitPassTarget(t *testing.T) {
+	fixtures := map[string][3]string{
+		"http://backend":                      {"http", "backend", ""},
+		"https://api.example.com:8443/v1/":    {"https", "api.example.com", "8443"},
+		"127.0.0.1:9000":                      {"", "127.0.0.1", "9000"},
+		"http://[::1]:8080/":                  {"http", "::1", "8080"},
+		"http://unix:/tmp/backend.socket:/a/": {"http", "unix:/tmp/backend.socket", ""},
+		"unix:/run/php-fpm.sock":              {"", "unix:/run/php-fpm.sock", ""},
+	}
+	for target, expected := range fixtures {
+		scheme, host, port := splitPassTarget(target)
+		if actual := [3]string{scheme, host, port}; actual != expected {
+			t.Errorf("%s: expected %v but got %v", target, expected, actual)
+		}
+	}
+}
+
+func TestPassTargets(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    upstream backend {
+        server 10.0.0.1;
+    }
+    server {
+        location /a { proxy_pass http://backend$request_uri; }
+        location /b { proxy_pass http://$host; }
+        location /c { proxy_pass http://api.example.com; }
+        location /d { fastcgi_pass 127.0.0.1:9000; }
+        location /e {
+            resolver 1.1.1.1;
+            proxy_pass http://$host;
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{ResolveUpstream, ResolveUnresolved, ResolveStartup, ResolveAddress, ResolveRuntime}
+	targets := PassTargets(directives)
+	if len(targets) != len(expected) {
+		t.Fatalf("expected %d targets but got %d", len(expected), len(targets))
+	}
+	for i, target := range targets {
+		if target.Resolution != expected[i] {
+			t.Errorf("%s: expected %s but got %s", target.Target, expected[i], target.Resolution)
+		}
+	}
+
+	issues, err := Lint(directives, "pass-resolver", "pass-startup-dns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 || issues[0].Rule != "pass-resolver" || issues[1].Rule != "pass-startup-dns" {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+}
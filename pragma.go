@@ -0,0 +1,189 @@
+package nginxparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+const pragmaPrefix = "nginx-parser:"
+
+// Annotations are the structured comments attached to a directive:
+// "# nginx-parser: disable=rule,rule enable=rule label=key=value" and
+// "# owner: team", and the source map comments written with
+// EmitOptions.SourceMap.
+// A standalone comment annotates the directive after it, a trailing one
+// the directive or the block opening it follows.
+type Annotations struct {
+	Disable []string `json:"disable,omitempty"`
+	Enable  []string `json:"enable,omitempty"`
+	Owner   string   `json:"owner,omitempty"`
+	// Labels are free-form marks for tools, a label without a value maps
+	// to "".
+	Labels map[string]string `json:"labels,omitempty"`
+	// Source is the origin of a generated directive read from a
+	// "# source: ..." comment.
+	Source *Provenance `json:"source,omitempty"`
+}
+
+// parsePragma reads the annotations in a comment into annotations and
+// reports whether it was one.
+func parsePragma(comment string, annotations *Annotations) bool {
+	text := strings.TrimSpace(comment)
+	switch {
+	case strings.HasPrefix(text, sourcePrefix):
+		if source := parseSource(text); source != nil {
+			annotations.Source = source
+			return true
+		}
+		return false
+	case strings.HasPrefix(text, "owner:"):
+		annotations.Owner = strings.TrimSpace(strings.TrimPrefix(text, "owner:"))
+		return true
+	case strings.HasPrefix(text, pragmaPrefix):
+		for _, field := range strings.Fields(strings.TrimPrefix(text, pragmaPrefix)) {
+			i := strings.IndexByte(field, '=')
+			if i < 0 {
+				continue
+			}
+			values := strings.Split(field[i+1:], ",")
+			switch field[:i] {
+			case "disable":
+				annotations.Disable = append(annotations.Disable, values...)
+			case "enable":
+				annotations.Enable = append(annotations.Enable, values...)
+			case "owner":
+				annotations.Owner = field[i+1:]
+			case "label", "labels":
+				for _, label := range values {
+					key, value := label, ""
+					if j := strings.IndexByte(label, '='); j >= 0 {
+						key, value = label[:j], label[j+1:]
+					}
+					if key == "" {
+						continue
+					}
+					if annotations.Labels == nil {
+						annotations.Labels = make(map[string]string)
+					}
+					annotations.Labels[key] = value
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// annotate attaches pragma comments to the directives they refer to. A
+// trailing comment after the opening brace annotates the block.
+func annotate(directives []*Directive, block *Directive) {
+	previous := block
+	pending := &Annotations{}
+	found := false
+	for _, d := range directives {
+		if d.Directive != "#" {
+			if found {
+				d.Annotations = mergeAnnotations(d.Annotations, pending)
+				pending, found = &Annotations{}, false
+			}
+			if d.Directive != "include" {
+				annotate(d.Block, d)
+			}
+			previous = d
+			continue
+		}
+		if d.Inline && previous != nil {
+			trailing := &Annotations{}
+			if parsePragma(d.Comment, trailing) {
+				previous.Annotations = mergeAnnotations(previous.Annotations, trailing)
+			}
+			continue
+		}
+		if parsePragma(d.Comment, pending) {
+			found = true
+		}
+	}
+}
+
+func mergeAnnotations(a, b *Annotations) *Annotations {
+	if a == nil {
+		return b
+	}
+	a.Disable = append(a.Disable, b.Disable...)
+	a.Enable = append(a.Enable, b.Enable...)
+	if b.Owner != "" {
+		a.Owner = b.Owner
+	}
+	if b.Source != nil {
+		a.Source = b.Source
+	}
+	for key, value := range b.Labels {
+		if a.Labels == nil {
+			a.Labels = make(map[string]string)
+		}
+		a.Labels[key] = value
+	}
+	return a
+}
+
+// Disabled reports whether the annotations turn off a lint rule, the name
+// all standing for every rule.
+func (a *Annotations) Disabled(rule string) bool {
+	disabled, _ := a.decide(rule)
+	return disabled
+}
+
+func (a *Annotations) decide(rule string) (disabled, decided bool) {
+	if a == nil {
+		return false, false
+	}
+	for _, name := range a.Enable {
+		if name == rule || name == "all" {
+			return false, true
+		}
+	}
+	for _, name := range a.Disable {
+		if name == rule || name == "all" {
+			return true, true
+		}
+	}
+	return false, false
+}
+
+// suppressions maps file positions to the annotations in effect there,
+// outermost first.
+func suppressions(directives []*Directive) map[string][]*Annotations {
+	result := make(map[string][]*Annotations)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		chain := make([]*Annotations, 0)
+		for _, parent := range parents {
+			if parent.Annotations != nil {
+				chain = append(chain, parent.Annotations)
+			}
+		}
+		if d.Annotations != nil {
+			chain = append(chain, d.Annotations)
+		}
+		if len(chain) > 0 {
+			result[fmt.Sprintf("%s:%d", d.FileName, d.Line)] = chain
+			// Issues about an argument carry the line of the argument.
+			for _, position := range d.ArgPositions {
+				if position.Line != d.Line {
+					result[fmt.Sprintf("%s:%d", d.FileName, position.Line)] = chain
+				}
+			}
+		}
+		return true
+	})
+	return result
+}
+
+func suppressedIssue(issue *Issue, chains map[string][]*Annotations) bool {
+	chain := chains[fmt.Sprintf("%s:%d", issue.FileName, issue.Line)]
+	for i := len(chain) - 1; i >= 0; i-- {
+		if disabled, decided := chain[i].decide(issue.Rule); decided {
+			return disabled
+		}
+	}
+	return false
+}
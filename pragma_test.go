@@ -0,0 +1,52 @@
+package nginxparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPragmas(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    # owner: team-edge
+    # nginx-parser: disable=auth-request
+    server {
+        location / { auth_request /missing; }
+        # nginx-parser: enable=auth-request
+        location /strict { auth_request /missing; }
+        location /other {
+            auth_request /missing; # nginx-parser: disable=all
+        }
+    }
+    server {
+        # just a comment
+        location / { auth_request /missing; }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := directives[0].Block[2]
+	expected := &Annotations{Disable: []string{"auth-request"}, Owner: "team-edge"}
+	if !reflect.DeepEqual(server.Annotations, expected) {
+		t.Fatalf("expected %+v but got %+v", expected, server.Annotations)
+	}
+	if server.Block[2].Annotations.Disabled("auth-request") || !server.Block[3].Block[0].Annotations.Disabled("mirror") {
+		t.Fatalf("unexpected annotations %+v", server.Block[3].Block[0].Annotations)
+	}
+	if directives[0].Block[3].Block[1].Annotations != nil {
+		t.Fatal("plain comments must not annotate")
+	}
+
+	issues, err := Lint(directives, "auth-request")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := make([]int, 0)
+	for _, issue := range issues {
+		lines = append(lines, issue.Line)
+	}
+	if expected := []int{8, 15}; !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("expected issues on lines %v but got %v", expected, issues)
+	}
+}
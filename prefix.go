@@ -0,0 +1,280 @@
+package nginxparser
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PrefixFile is how RenderPrefix materializes a file a directive reads.
+type PrefixFile int
+
+const (
+	// PrefixStub writes a placeholder nginx accepts: a self-signed
+	// certificate and its key for certificate directives, an empty file
+	// otherwise.
+	PrefixStub PrefixFile = iota
+	// PrefixCopy copies the original file.
+	PrefixCopy
+	// PrefixKeep leaves the directive pointing at the original file.
+	PrefixKeep
+)
+
+const (
+	fileCertificate = "certificate"
+	fileKey         = "key"
+	fileTicketKey   = "ticket-key"
+	filePassword    = "password"
+	fileText        = "text"
+	// fileParsed files have a format that cannot be stubbed easily, such
+	// as DH parameters, and are copied by default.
+	fileParsed = "parsed"
+)
+
+// prefixInputs maps the directives naming a file nginx reads to the kind
+// of file.
+var prefixInputs = map[string]string{
+	"ssl_certificate":               fileCertificate,
+	"ssl_certificate_key":           fileKey,
+	"ssl_trusted_certificate":       fileCertificate,
+	"ssl_client_certificate":        fileCertificate,
+	"proxy_ssl_certificate":         fileCertificate,
+	"proxy_ssl_certificate_key":     fileKey,
+	"proxy_ssl_trusted_certificate": fileCertificate,
+	"grpc_ssl_certificate":          fileCertificate,
+	"grpc_ssl_certificate_key":      fileKey,
+	"grpc_ssl_trusted_certificate":  fileCertificate,
+	"uwsgi_ssl_certificate":         fileCertificate,
+	"uwsgi_ssl_certificate_key":     fileKey,
+	"uwsgi_ssl_trusted_certificate": fileCertificate,
+	"ssl_session_ticket_key":        fileTicketKey,
+	"ssl_password_file":             filePassword,
+	"proxy_ssl_password_file":       filePassword,
+	"grpc_ssl_password_file":        filePassword,
+	"uwsgi_ssl_password_file":       filePassword,
+	"auth_basic_user_file":          fileText,
+	"ssl_dhparam":                   fileParsed,
+	"ssl_crl":                       fileParsed,
+	"proxy_ssl_crl":                 fileParsed,
+	"grpc_ssl_crl":                  fileParsed,
+	"uwsgi_ssl_crl":                 fileParsed,
+	"ssl_stapling_file":             fileParsed,
+}
+
+// prefixOutputs are the directives naming a file or directory nginx
+// writes to.
+var prefixOutputs = map[string]bool{
+	"error_log":             true,
+	"access_log":            true,
+	"pid":                   true,
+	"lock_file":             true,
+	"client_body_temp_path": true,
+	"proxy_temp_path":       true,
+	"fastcgi_temp_path":     true,
+	"uwsgi_temp_path":       true,
+	"scgi_temp_path":        true,
+	"proxy_cache_path":      true,
+	"fastcgi_cache_path":    true,
+	"uwsgi_cache_path":      true,
+	"scgi_cache_path":       true,
+}
+
+type PrefixOptions struct {
+	Emit *EmitOptions
+	// Files decides how the file a directive reads is materialized. It
+	// defaults to PrefixStub, or PrefixCopy for files such as DH
+	// parameters that have no usable stub.
+	Files func(d *Directive, name string) PrefixFile
+	// ReadFile reads the files to copy, it defaults to os.ReadFile.
+	ReadFile func(name string) ([]byte, error)
+}
+
+// RenderPrefix writes a parsed tree into dir so "nginx -t -p dir -c conf"
+// can check it without touching the original files, and returns conf. The
+// files of the tree are written under dir at their original paths,
+// absolute includes, certificates, keys and other files nginx reads are
+// rewritten to point into dir, and so are logs, pid files and temporary
+// paths nginx writes to. The directives passed in are not modified.
+func RenderPrefix(directives []*Directive, dir string, options *PrefixOptions) (string, error) {
+	if options == nil {
+		options = &PrefixOptions{}
+	}
+	files := options.Files
+	if files == nil {
+		files = func(d *Directive, name string) PrefixFile {
+			if prefixInputs[d.Directive] == fileParsed {
+				return PrefixCopy
+			}
+			return PrefixStub
+		}
+	}
+	readFile := options.ReadFile
+	if readFile == nil {
+		readFile = os.ReadFile
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	target := func(name string) string {
+		return filepath.Join(dir, filepath.FromSlash(path.Clean("/"+filepath.ToSlash(name))))
+	}
+	write := func(name string, data []byte) error {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(name, data, 0644)
+	}
+
+	tree := cloneDirectives(directives)
+	main := ""
+	for _, d := range tree {
+		if d.FileName != "" {
+			main = d.FileName
+			break
+		}
+	}
+	confDir := filepath.Dir(main)
+	conf := target(main)
+	if main == "" {
+		conf = filepath.Join(dir, "nginx.conf")
+	}
+
+	stubs := &prefixStubs{}
+	written := make(map[string]bool)
+	Walk(tree, func(d *Directive, parents []*Directive) bool {
+		if err != nil || len(d.Args) == 0 {
+			return err == nil
+		}
+		name := d.Args[0]
+		switch {
+		case d.Directive == "include":
+			for i, pattern := range d.Args {
+				if filepath.IsAbs(pattern) {
+					d.Args[i] = target(pattern)
+				}
+			}
+		case prefixOutputs[d.Directive]:
+			if name == "off" || name == "stderr" || strings.HasPrefix(name, "syslog:") || strings.HasPrefix(name, "memory:") || strings.HasPrefix(name, "/dev/") || strings.Contains(name, "$") {
+				return true
+			}
+			if filepath.IsAbs(name) {
+				d.Args[0] = target(name)
+				err = os.MkdirAll(filepath.Dir(d.Args[0]), 0755)
+			}
+		case prefixInputs[d.Directive] != "":
+			if strings.Contains(name, "$") || strings.HasPrefix(name, "data:") || strings.HasPrefix(name, "engine:") {
+				return true
+			}
+			original := name
+			if !filepath.IsAbs(original) {
+				original = filepath.Join(confDir, original)
+			}
+			mode := files(d, original)
+			if mode == PrefixKeep {
+				return true
+			}
+			d.Args[0] = target(original)
+			if written[d.Args[0]] {
+				return true
+			}
+			written[d.Args[0]] = true
+			var data []byte
+			if mode == PrefixCopy {
+				data, err = readFile(original)
+			} else {
+				data, err = stubs.stub(prefixInputs[d.Directive])
+			}
+			if err == nil {
+				err = write(d.Args[0], data)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "logs"), 0755); err != nil {
+		return "", err
+	}
+
+	err = WriteFiles(tree, &WriteOptions{
+		Emit: options.Emit,
+		Write: func(name string, data []byte) error {
+			if name == main {
+				return write(conf, data)
+			}
+			return write(target(name), data)
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return conf, nil
+}
+
+// prefixStubs generates one self-signed certificate and key, so every
+// certificate stub matches every key stub.
+type prefixStubs struct {
+	certificate []byte
+	key         []byte
+}
+
+func (s *prefixStubs) stub(kind string) ([]byte, error) {
+	switch kind {
+	case fileCertificate, fileKey:
+		if s.certificate == nil {
+			if err := s.generate(); err != nil {
+				return nil, err
+			}
+		}
+		if kind == fileKey {
+			return s.key, nil
+		}
+		return s.certificate, nil
+	case fileTicketKey:
+		data := make([]byte, 80)
+		_, err := rand.Read(data)
+		return data, err
+	case filePassword:
+		return []byte("stub\n"), nil
+	}
+	return []byte{}, nil
+}
+
+func (s *prefixStubs) generate() error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "nginx-parser stub"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	s.certificate = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})
+	s.key = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	return nil
+}
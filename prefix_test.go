@@ -0,0 +1,102 @@
+package nginxparser
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderPrefix(t *testing.T) {
+	dir := t.TempDir()
+	dhparam := filepath.Join(t.TempDir(), "dhparam.pem")
+	if err := os.WriteFile(dhparam, []byte("dh"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+pid /run/nginx.pid;
+error_log stderr;
+http {
+    include /etc/nginx/mime.types;
+    access_log /var/log/nginx/access.log;
+    server {
+        ssl_certificate /etc/ssl/example.pem;
+        ssl_certificate_key /etc/ssl/example.key;
+        ssl_certificate $ssl_server_name.pem;
+        ssl_dhparam ` + dhparam + `;
+        auth_basic_user_file /etc/nginx/htpasswd;
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := RenderPrefix(directives, dir, &PrefixOptions{
+		Files: func(d *Directive, name string) PrefixFile {
+			if d.Directive == "auth_basic_user_file" {
+				return PrefixKeep
+			}
+			if d.Directive == "ssl_dhparam" {
+				return PrefixCopy
+			}
+			return PrefixStub
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf != filepath.Join(dir, "nginx.conf") {
+		t.Fatalf("unexpected config path %s", conf)
+	}
+	data, err := os.ReadFile(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"pid " + filepath.Join(dir, "run", "nginx.pid") + ";",
+		"error_log stderr;",
+		"include " + filepath.Join(dir, "etc", "nginx", "mime.types") + ";",
+		"access_log " + filepath.Join(dir, "var", "log", "nginx", "access.log") + ";",
+		"ssl_certificate $ssl_server_name.pem;",
+		"ssl_dhparam " + filepath.Join(dir, dhparam) + ";",
+		"auth_basic_user_file /etc/nginx/htpasswd;",
+	} {
+		if !strings.Contains(string(data), line) {
+			t.Fatalf("expected %q in\n%s", line, data)
+		}
+	}
+	if directives[0].Args[0] != "/run/nginx.pid" {
+		t.Fatalf("expected the parsed tree to be left alone, got %v", directives[0].Args)
+	}
+	if _, err := tls.LoadX509KeyPair(filepath.Join(dir, "etc", "ssl", "example.pem"), filepath.Join(dir, "etc", "ssl", "example.key")); err != nil {
+		t.Fatalf("expected a matching certificate and key stub: %s", err)
+	}
+	if copied, err := os.ReadFile(filepath.Join(dir, dhparam)); err != nil || string(copied) != "dh" {
+		t.Fatalf("expected a copy of the DH parameters, got %q %v", copied, err)
+	}
+	for _, name := range []string{"logs", filepath.Join("var", "log", "nginx")} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || !info.IsDir() {
+			t.Fatalf("expected directory %s: %v", name, err)
+		}
+	}
+
+	root := filepath.Join("testdata", "includes-regular")
+	directives, err = New(&ParseOptions{Root: root}).ParseFile(filepath.Join(root, "nginx.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir = t.TempDir()
+	conf, err = RenderPrefix(directives, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reparsed, err := New(&ParseOptions{Root: filepath.Dir(conf)}).ParseFile(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(stripPositions(directives), stripPositions(reparsed)) {
+		t.Fatal("expected the rendered files to parse back to the same tree")
+	}
+}
@@ -0,0 +1,75 @@
+package nginxparser
+
+import (
+	"fmt"
+)
+
+// Provenance records where a directive came from once a transform has
+// created or moved it: either its original position or the rule that
+// synthesized it. Generators set Object to the higher-level source, such
+// as an Ingress, with FileName and Line pointing into it.
+type Provenance struct {
+	Object   string `json:"object,omitempty"`
+	FileName string `json:"filename,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Rule     string `json:"rule,omitempty"`
+}
+
+func (p *Provenance) String() string {
+	switch {
+	case p.Rule != "":
+		return "synthesized by " + p.Rule
+	case p.Object != "" && p.FileName == "" && p.Line == 0:
+		return "from " + p.Object
+	case p.Object != "":
+		return fmt.Sprintf("from %s (%s:%d)", p.Object, p.FileName, p.Line)
+	}
+	return fmt.Sprintf("from %s:%d", p.FileName, p.Line)
+}
+
+// Origin returns the recorded provenance of d, then the source map comment
+// annotating it, or its parse position when it has neither.
+func (d *Directive) Origin() *Provenance {
+	if d.Provenance != nil {
+		return d.Provenance
+	}
+	if d.Annotations != nil && d.Annotations.Source != nil {
+		return d.Annotations.Source
+	}
+	return &Provenance{FileName: d.FileName, Line: d.Line}
+}
+
+// MarkMoved records the current position of d and its descendants as
+// their provenance before a transform relocates them. Positions recorded
+// by an earlier transform are kept.
+func MarkMoved(d *Directive) {
+	if d.Provenance == nil {
+		d.Provenance = &Provenance{FileName: d.FileName, Line: d.Line}
+	}
+	for _, child := range d.Block {
+		MarkMoved(child)
+	}
+}
+
+// markSynthesized attributes the directives, and their descendants, that
+// have no provenance yet to the given transform rule.
+func markSynthesized(rule string, directives []*Directive) {
+	for _, d := range directives {
+		if d.Provenance == nil {
+			d.Provenance = &Provenance{Rule: rule}
+		}
+		markSynthesized(rule, d.Block)
+	}
+}
+
+// Synthesize creates a directive attributed to the given transform rule.
+func Synthesize(rule string, name string, args ...string) *Directive {
+	if args == nil {
+		args = make([]string, 0)
+	}
+	return &Directive{
+		Directive:  name,
+		Args:       args,
+		Provenance: &Provenance{Rule: rule},
+	}
+}
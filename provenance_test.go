@@ -0,0 +1,71 @@
+package nginxparser
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestProvenance(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString("server {\n    listen 80;\n}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := directives[0]
+	MarkMoved(server)
+	server.Line = 10
+	server.Block[0].FileName = "other.conf"
+	server.Block = append(server.Block, Synthesize("example", "server_name", "example.com"))
+
+	if origin := server.Block[0].Origin(); origin.Line != 2 || origin.FileName != "" {
+		t.Fatalf("unexpected origin %+v", origin)
+	}
+
+	var buf bytes.Buffer
+	if err := NewEmitter(&EmitOptions{Provenance: true}).Emit(&buf, directives); err != nil {
+		t.Fatal(err)
+	}
+	expected := "# from :1\nserver {\n    # from :2\n    listen 80;\n    # synthesized by example\n    server_name example.com;\n}\n"
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
+
+func TestTransformProvenance(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString("http {\n    server {\n        server_name a.example.com;\n        listen 80;\n    }\n}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	directives[0].FileName = "nginx.conf"
+	directives[0].Block[0].Block[1].FileName = "nginx.conf"
+
+	partitioned := Partition(directives, nil)["example.com"]
+	sliced := SliceByHost(directives, "a.example.com")
+	for _, result := range [][]*Directive{partitioned, sliced} {
+		listen := result[0].Block[0].Block[1]
+		if listen == directives[0].Block[0].Block[1] || listen.Provenance == nil || listen.Provenance.String() != "from nginx.conf:4" {
+			t.Fatalf("expected the copy to record its origin, got %+v", listen.Provenance)
+		}
+	}
+	if directives[0].Provenance != nil {
+		t.Fatal("expected the original to be left alone")
+	}
+
+	sites, err := ExpandTemplate(directives[0].Block[0], []map[string]string{{}}, &TemplateOptions{FileName: "site.conf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if include := sites[0]; include.Provenance.String() != "synthesized by template" || include.Block[0].Block[1].Provenance.String() != "from nginx.conf:4" {
+		t.Fatalf("unexpected provenance %v %v", include.Provenance, include.Block[0].Block[1].Provenance)
+	}
+
+	scaffolded, err := Scaffold(ScaffoldStaticSite, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Walk(scaffolded, func(d *Directive, parents []*Directive) bool {
+		if d.Provenance == nil || d.Provenance.Rule != "scaffold static-site" {
+			t.Fatalf("expected %s to be synthesized by the scaffold, got %v", d.Directive, d.Provenance)
+		}
+		return true
+	})
+}
@@ -0,0 +1,42 @@
+package nginxparser
+
+// Quote is the quoting style of an argument.
+type Quote byte
+
+const (
+	QuoteNone   Quote = 0
+	QuoteSingle Quote = '\''
+	QuoteDouble Quote = '"'
+)
+
+// Position is where an argument starts in its file, with the line and
+// the column of its first byte counted from 1.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+func (d *Directive) appendArg(arg string, quote Quote, position Position) {
+	d.Args = append(d.Args, arg)
+	d.Quotes = append(d.Quotes, quote)
+	d.ArgPositions = append(d.ArgPositions, position)
+}
+
+// QuoteAt returns the quoting style of the i-th argument, QuoteNone when
+// unknown.
+func (d *Directive) QuoteAt(i int) Quote {
+	if i < 0 || i >= len(d.Quotes) || len(d.Quotes) != len(d.Args) {
+		return QuoteNone
+	}
+	return d.Quotes[i]
+}
+
+// ArgPosition returns where the i-th argument starts in the source, false
+// for directives that were not parsed or arguments added since.
+func (d *Directive) ArgPosition(i int) (Position, bool) {
+	if i < 0 || i >= len(d.ArgPositions) || len(d.ArgPositions) != len(d.Args) {
+		return Position{}, false
+	}
+	return d.ArgPositions[i], true
+}
@@ -0,0 +1,129 @@
+package nginxparser
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQuotes(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+add_header X-Frame-Options 'DENY' always;
+log_format main "$remote_addr" '"$request"';
+if ($host = "example.com") {
+    return 404;
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]Quote{
+		{QuoteNone, QuoteSingle, QuoteNone},
+		{QuoteNone, QuoteDouble, QuoteSingle},
+		{QuoteNone, QuoteNone, QuoteDouble},
+	}
+	for i, quotes := range expected {
+		if !reflect.DeepEqual(directives[i].Quotes, quotes) {
+			t.Fatalf("expected %v but got %v for %s", quotes, directives[i].Quotes, directives[i].Directive)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Dump(directives, &buf); err != nil {
+		t.Fatal(err)
+	}
+	output := `add_header X-Frame-Options 'DENY' always;
+log_format main "$remote_addr" '"$request"';
+if ($host = "example.com") {
+    return 404;
+}
+`
+	if buf.String() != output {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", output, buf.String())
+	}
+
+	directives[0].Args = append(directives[0].Args, "x y")
+	if directives[0].QuoteAt(3) != QuoteNone {
+		t.Fatalf("expected no quote for an added argument")
+	}
+}
+
+func TestArgPositions(t *testing.T) {
+	src := "server {\n    error_page 404\n\t\t'/missing' ;\n    if ( $host = a ) { return 404; }\n    content_by_lua_block { ngx.say(1) }\n}\n"
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := directives[0].Block
+	expected := map[*Directive][]Position{
+		block[0]: {{Line: 2, Column: 16, Offset: 24}, {Line: 3, Column: 3, Offset: 30}},
+		block[1]: {{Line: 4, Column: 10, Offset: 52}, {Line: 4, Column: 16, Offset: 58}, {Line: 4, Column: 18, Offset: 60}},
+		block[2]: {{Line: 5, Column: 27, Offset: 106}},
+	}
+	for d, positions := range expected {
+		if !reflect.DeepEqual(d.ArgPositions, positions) {
+			t.Fatalf("expected %+v for %s but got %+v", positions, d.Directive, d.ArgPositions)
+		}
+	}
+	if _, ok := NewDirective("listen", "80").ArgPosition(0); ok {
+		t.Fatal("expected no position for a built directive")
+	}
+
+	issues, err := Lint(directives, "error-page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Line != 3 || issues[0].Column != 3 || !strings.HasPrefix(issues[0].String(), ":3:3: ") {
+		t.Fatalf("expected the issue to point at the target, got %v", issues)
+	}
+}
+
+func TestCrossplaneEscapes(t *testing.T) {
+	src := `log_format main "a\"b\n\t" 'c\'d\"' e\ f \\w;`
+	expected := map[bool][]string{
+		false: {"main", "a\"b\n\t", "c'd\"", "e f", "\\w"},
+		true:  {"main", `a"b\n\t`, `c'd\"`, `e\ f`, `\\w`},
+	}
+	for crossplane, args := range expected {
+		directives, err := New(&ParseOptions{SingleFile: true, CrossplaneEscapes: crossplane}).ParseString(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(directives[0].Args, args) {
+			t.Fatalf("expected %q but got %q", args, directives[0].Args)
+		}
+	}
+}
+
+func TestRawEscapes(t *testing.T) {
+	src := `log_format main '{"uri":"$uri\t"}\n' "x\"y" \d+ 'it\'s' "a\\";` + "\n"
+	directives, err := New(&ParseOptions{SingleFile: true, RawEscapes: true}).ParseString(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"main", `{"uri":"$uri\t"}\n`, `x\"y`, `\d+`, `it\'s`, `a\\`}
+	if !reflect.DeepEqual(directives[0].Args, expected) {
+		t.Fatalf("expected %q but got %q", expected, directives[0].Args)
+	}
+
+	var buf bytes.Buffer
+	if err := NewEmitter(&EmitOptions{RawEscapes: true}).Emit(&buf, directives); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Fatalf("expected %q but got %q", src, buf.String())
+	}
+
+	directives[0].Quotes = nil
+	buf.Reset()
+	if err := NewEmitter(&EmitOptions{RawEscapes: true}).Emit(&buf, directives); err != nil {
+		t.Fatal(err)
+	}
+	reparsed, err := New(&ParseOptions{SingleFile: true, RawEscapes: true}).ParseString(buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reparsed[0].Args, expected) {
+		t.Fatalf("expected %q after requoting %q, got %q", expected, buf.String(), reparsed[0].Args)
+	}
+}
@@ -0,0 +1,124 @@
+package nginxparser
+
+import (
+	"net"
+	"strings"
+)
+
+type RealIP struct {
+	Server    *Directive   `json:"-"`
+	Trusted   []string     `json:"trusted"`
+	Networks  []*net.IPNet `json:"-"`
+	Header    string       `json:"header"`
+	Recursive bool         `json:"recursive"`
+	Sources   []*Directive `json:"-"`
+}
+
+type RealIPOptions struct {
+	// CDNRanges are the CIDRs of a CDN or load balancer in front of nginx.
+	// Servers that do not trust all of them are reported.
+	CDNRanges []string
+}
+
+func parseCIDR(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, &net.ParseError{Type: "IP address", Text: s}
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, network, err := net.ParseCIDR(s)
+	return network, err
+}
+
+func containsNetwork(outer, inner *net.IPNet) bool {
+	outerOnes, outerBits := outer.Mask.Size()
+	innerOnes, innerBits := inner.Mask.Size()
+	return outerBits == innerBits && outerOnes <= innerOnes && outer.Contains(inner.IP)
+}
+
+// RealIPs reports the effective realip configuration of every server: the
+// proxies trusted to supply the client address and the header it is read
+// from.
+func RealIPs(directives []*Directive) []*RealIP {
+	result := make([]*RealIP, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive != "server" || enclosing("http", parents) == nil {
+			return true
+		}
+		realIP := &RealIP{
+			Server:   d,
+			Trusted:  make([]string, 0),
+			Networks: make([]*net.IPNet, 0),
+			Header:   "X-Real-IP",
+			Sources:  lookupInheritedAll("set_real_ip_from", d.Block, parents),
+		}
+		for _, from := range realIP.Sources {
+			if len(from.Args) == 0 {
+				continue
+			}
+			realIP.Trusted = append(realIP.Trusted, from.Args[0])
+			if network, err := parseCIDR(from.Args[0]); err == nil {
+				realIP.Networks = append(realIP.Networks, network)
+			}
+		}
+		if header := lookupInherited("real_ip_header", d.Block, parents); header != nil && len(header.Args) > 0 {
+			realIP.Header = header.Args[0]
+		}
+		if recursive := lookupInherited("real_ip_recursive", d.Block, parents); recursive != nil && len(recursive.Args) > 0 {
+			realIP.Recursive = recursive.Args[0] == "on"
+		}
+		result = append(result, realIP)
+		return false
+	})
+	return result
+}
+
+func CheckRealIP(directives []*Directive, options *RealIPOptions) []*Issue {
+	if options == nil {
+		options = &RealIPOptions{}
+	}
+	cdn := make([]*net.IPNet, 0)
+	for _, r := range options.CDNRanges {
+		if network, err := parseCIDR(r); err == nil {
+			cdn = append(cdn, network)
+		}
+	}
+
+	issues := make([]*Issue, 0)
+	reported := make(map[*Directive]bool)
+	for _, realIP := range RealIPs(directives) {
+		for i, network := range realIP.Networks {
+			if ones, _ := network.Mask.Size(); ones == 0 && !reported[realIP.Sources[i]] {
+				reported[realIP.Sources[i]] = true
+				issues = append(issues, newIssue("real-ip", realIP.Sources[i], "set_real_ip_from %s trusts every client to set its own address", realIP.Trusted[i]))
+			}
+		}
+		if len(cdn) == 0 {
+			continue
+		}
+		missing := make([]string, 0)
+		for i, r := range cdn {
+			covered := false
+			for _, network := range realIP.Networks {
+				covered = covered || containsNetwork(network, r)
+			}
+			if !covered {
+				missing = append(missing, options.CDNRanges[i])
+			}
+		}
+		if len(missing) > 0 {
+			issues = append(issues, newIssue("real-ip", realIP.Server, "server does not trust CDN ranges %s, client addresses are logged as the CDN's", strings.Join(missing, ", ")))
+		}
+	}
+	return issues
+}
+
+func checkRealIP(directives []*Directive) []*Issue {
+	return CheckRealIP(directives, nil)
+}
@@ -0,0 +1,48 @@
+package nginxparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRealIPs(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    set_real_ip_from 173.245.48.0/20;
+    real_ip_header CF-Connecting-IP;
+    server {
+        server_name a.example.com;
+    }
+    server {
+        server_name b.example.com;
+        set_real_ip_from 0.0.0.0/0;
+        real_ip_header X-Forwarded-For;
+        real_ip_recursive on;
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	realIPs := RealIPs(directives)
+	if len(realIPs) != 2 {
+		t.Fatalf("expected 2 servers but got %d", len(realIPs))
+	}
+	if !reflect.DeepEqual(realIPs[0].Trusted, []string{"173.245.48.0/20"}) || realIPs[0].Header != "CF-Connecting-IP" || realIPs[0].Recursive {
+		t.Fatalf("unexpected real ip %+v", realIPs[0])
+	}
+	if realIPs[1].Header != "X-Forwarded-For" || !realIPs[1].Recursive {
+		t.Fatalf("unexpected real ip %+v", realIPs[1])
+	}
+
+	issues := CheckRealIP(directives, &RealIPOptions{CDNRanges: []string{"173.245.48.0/24", "103.21.244.0/22"}})
+	lines := []int{5, 10}
+	if len(issues) != len(lines) {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	for i, issue := range issues {
+		if issue.Line != lines[i] {
+			t.Fatalf("unexpected issues %v", issues)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package nginxparser
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+var ErrRejected = errors.New("candidate rejected")
+
+// Report is the outcome of proposing a candidate configuration to a
+// Reconciler.
+type Report struct {
+	Changes []*Change `json:"changes"`
+	// Issues are the lint issues of the candidate, NewIssues those not
+	// already present in the current configuration.
+	Issues    []*Issue `json:"issues"`
+	NewIssues []*Issue `json:"new_issues"`
+	// Servers are the names of the servers affected by the changes. A
+	// change outside any server affects all of them.
+	Servers  []string `json:"servers"`
+	Promoted bool     `json:"promoted"`
+}
+
+type ReconcilerOptions struct {
+	// Rules limits validation to the named lint rules, all by default.
+	Rules []string
+	// Gate decides whether a candidate is promoted, for example after
+	// writing it out and running nginx -t. By default candidates that
+	// introduce lint issues are rejected.
+	Gate func(candidate []*Directive, report *Report) error
+}
+
+// Reconciler holds the last known good configuration and promotes
+// candidates that pass validation and the gate. It is safe for
+// concurrent use.
+type Reconciler struct {
+	options *ReconcilerOptions
+	mu      sync.Mutex
+	current []*Directive
+}
+
+func NewReconciler(current []*Directive, options *ReconcilerOptions) *Reconciler {
+	if options == nil {
+		options = &ReconcilerOptions{}
+	}
+	if options.Gate == nil {
+		options.Gate = func(candidate []*Directive, report *Report) error {
+			if len(report.NewIssues) > 0 {
+				return ErrRejected
+			}
+			return nil
+		}
+	}
+	return &Reconciler{options: options, current: current}
+}
+
+func (r *Reconciler) Current() []*Directive {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Propose validates a candidate against the current configuration and
+// promotes it when the gate allows. The report is returned even when the
+// candidate is rejected, together with the gate's error.
+func (r *Reconciler) Propose(candidate []*Directive) (*Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &Report{Changes: Diff(r.current, candidate)}
+	issues, err := Lint(candidate, r.options.Rules...)
+	if err != nil {
+		return nil, err
+	}
+	baseline, err := Lint(r.current, r.options.Rules...)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]int)
+	for _, issue := range baseline {
+		known[issue.Rule+"\x00"+issue.Message]++
+	}
+	report.Issues, report.NewIssues = issues, make([]*Issue, 0)
+	for _, issue := range issues {
+		key := issue.Rule + "\x00" + issue.Message
+		if known[key] > 0 {
+			known[key]--
+			continue
+		}
+		report.NewIssues = append(report.NewIssues, issue)
+	}
+	report.Servers = impact(report.Changes, r.current, candidate)
+
+	if err := r.options.Gate(candidate, report); err != nil {
+		return report, err
+	}
+	r.current, report.Promoted = candidate, true
+	return report, nil
+}
+
+func impact(changes []*Change, old, new []*Directive) []string {
+	affected := make(map[string]bool)
+	for _, change := range changes {
+		if change.Kind == ChangeMoved && equalStrings(change.OldPath, change.Path) {
+			// Moving a directive between files at the same place in the
+			// effective configuration changes nothing.
+			continue
+		}
+		if change.Kind == ChangeMoved && change.OldServer != nil {
+			for _, name := range serverNames(change.OldServer) {
+				affected[name] = true
+			}
+		}
+		if change.Server == nil {
+			for _, server := range append(servers(old), servers(new)...) {
+				for _, name := range serverNames(server) {
+					affected[name] = true
+				}
+			}
+			break
+		}
+		for _, name := range serverNames(change.Server) {
+			affected[name] = true
+		}
+	}
+	result := make([]string, 0, len(affected))
+	for name := range affected {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
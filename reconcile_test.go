@@ -0,0 +1,114 @@
+package nginxparser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReconciler(t *testing.T) {
+	parse := func(s string) []*Directive {
+		directives, err := New(&ParseOptions{SingleFile: true}).ParseString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return directives
+	}
+	current := parse(`
+http {
+    server {
+        server_name a.example.com;
+        location / { return 200; }
+    }
+    server {
+        server_name b.example.com;
+        location / { return 200; }
+    }
+}`)
+	reconciler := NewReconciler(current, &ReconcilerOptions{Rules: []string{"auth-request"}})
+
+	broken := parse(`
+http {
+    server {
+        server_name a.example.com;
+        location / { auth_request /auth; return 200; }
+    }
+    server {
+        server_name b.example.com;
+        location / { return 200; }
+    }
+}`)
+	report, err := reconciler.Propose(broken)
+	if !errors.Is(err, ErrRejected) || report.Promoted || len(report.NewIssues) != 1 {
+		t.Fatalf("expected rejection, got %v %+v", err, report)
+	}
+	if !reflect.DeepEqual(report.Servers, []string{"a.example.com"}) {
+		t.Fatalf("unexpected impact %v", report.Servers)
+	}
+	if reconciler.Current()[0] != current[0] {
+		t.Fatal("rejected candidate must not replace the current configuration")
+	}
+
+	good := parse(`
+http {
+    gzip on;
+    server {
+        server_name a.example.com;
+        location / { return 200; }
+    }
+    server {
+        server_name b.example.com;
+        location / { return 200; }
+    }
+}`)
+	gated := 0
+	reconciler = NewReconciler(current, &ReconcilerOptions{
+		Gate: func(candidate []*Directive, report *Report) error {
+			gated++
+			return nil
+		},
+	})
+	report, err = reconciler.Propose(good)
+	if err != nil || !report.Promoted || gated != 1 {
+		t.Fatalf("expected promotion, got %v %+v", err, report)
+	}
+	if !reflect.DeepEqual(report.Servers, []string{"a.example.com", "b.example.com"}) {
+		t.Fatalf("unexpected impact %v", report.Servers)
+	}
+	if reconciler.Current()[0] != good[0] {
+		t.Fatal("expected the candidate to become current")
+	}
+}
+
+func TestReconcilerReorder(t *testing.T) {
+	parse := func(s string) []*Directive {
+		directives, err := New(&ParseOptions{SingleFile: true}).ParseString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return directives
+	}
+	current := parse(`
+http {
+    server {
+        server_name a.example.com;
+        location ~ /admin { return 403; }
+        location ~ \.php$ { proxy_pass http://php; }
+    }
+}`)
+	candidate := parse(`
+http {
+    server {
+        server_name a.example.com;
+        location ~ \.php$ { proxy_pass http://php; }
+        location ~ /admin { return 403; }
+    }
+}`)
+	report, err := NewReconciler(current, nil).Propose(candidate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Changes) != 1 || !reflect.DeepEqual(report.Servers, []string{"a.example.com"}) {
+		t.Fatalf("expected the reordered locations to affect the server, got %v %v", report.Changes, report.Servers)
+	}
+}
@@ -0,0 +1,29 @@
+package nginxparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// compileRegex compiles a PCRE pattern as used by nginx. Named groups in
+// the (?<name>...) form are rewritten to the syntax RE2 understands.
+func compileRegex(pattern string, caseless bool) (*regexp.Regexp, error) {
+	var buf strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '\\' && i+1 < len(pattern) {
+			buf.WriteString(pattern[i : i+2])
+			i++
+			continue
+		}
+		if strings.HasPrefix(pattern[i:], "(?<") && !strings.HasPrefix(pattern[i:], "(?<=") && !strings.HasPrefix(pattern[i:], "(?<!") {
+			buf.WriteString("(?P<")
+			i += 2
+			continue
+		}
+		buf.WriteByte(pattern[i])
+	}
+	if caseless {
+		return regexp.Compile("(?i)" + buf.String())
+	}
+	return regexp.Compile(buf.String())
+}
@@ -0,0 +1,147 @@
+package nginxparser
+
+import (
+	"strconv"
+	"strings"
+)
+
+type RetryPolicy struct {
+	Pass *Directive `json:"-"`
+	// Module is the prefix of the retry directives, proxy for proxy_pass.
+	Module   string `json:"module"`
+	Upstream string `json:"upstream,omitempty"`
+	// Conditions are the cases of *_next_upstream, empty when retries are
+	// off.
+	Conditions    []string `json:"conditions"`
+	NonIdempotent bool     `json:"non_idempotent,omitempty"`
+	// Tries and Timeout limit the attempts, 0 and "" mean no limit.
+	Tries   int    `json:"tries,omitempty"`
+	Timeout string `json:"timeout,omitempty"`
+	// Servers is the number of usable servers in the upstream, 1 for a
+	// pass to a single address.
+	Servers int `json:"servers"`
+	// Attempts is the most attempts a request gets, nginx tries every
+	// server at most once.
+	Attempts int `json:"attempts"`
+	// MaxFails is the lowest max_fails of the servers of the upstream.
+	MaxFails int `json:"max_fails"`
+}
+
+// RetryPolicies reports the effective retry behavior of every *_pass
+// directive from the *_next_upstream, *_next_upstream_tries and
+// *_next_upstream_timeout directives in scope and the upstream it
+// targets.
+func RetryPolicies(directives []*Directive) []*RetryPolicy {
+	groups := make(map[string]*Directive)
+	for _, upstream := range upstreams(directives) {
+		groups[upstreamName(upstream)] = upstream
+	}
+
+	result := make([]*RetryPolicy, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if !passDirectives[d.Directive] || len(d.Args) == 0 {
+			return true
+		}
+		module := strings.TrimSuffix(d.Directive, "_pass")
+		policy := &RetryPolicy{Pass: d, Module: module, Conditions: []string{"error", "timeout"}, Servers: 1, MaxFails: 1}
+		if next := lookupInherited(module+"_next_upstream", nil, parents); next != nil {
+			policy.Conditions = make([]string, 0, len(next.Args))
+			for _, arg := range next.Args {
+				switch arg {
+				case "off":
+					policy.Conditions = policy.Conditions[:0]
+				case "non_idempotent":
+					policy.NonIdempotent = true
+				default:
+					policy.Conditions = append(policy.Conditions, arg)
+				}
+			}
+			if len(policy.Conditions) == 0 {
+				policy.NonIdempotent = false
+			}
+		}
+		if tries := lookupInherited(module+"_next_upstream_tries", nil, parents); tries != nil && len(tries.Args) > 0 {
+			policy.Tries, _ = strconv.Atoi(tries.Args[0])
+		}
+		if timeout := lookupInherited(module+"_next_upstream_timeout", nil, parents); timeout != nil && len(timeout.Args) > 0 && timeout.Args[0] != "0" {
+			policy.Timeout = timeout.Args[0]
+		}
+
+		_, host, _ := splitPassTarget(d.Args[0])
+		if upstream, ok := groups[host]; ok {
+			policy.Upstream = host
+			policy.Servers, policy.MaxFails = 0, -1
+			for _, server := range findAll(upstream.Block, "server") {
+				if hasParam(server.Args, "down") {
+					continue
+				}
+				policy.Servers++
+				maxFails := 1
+				for _, arg := range server.Args {
+					if strings.HasPrefix(arg, "max_fails=") {
+						maxFails, _ = strconv.Atoi(strings.TrimPrefix(arg, "max_fails="))
+					}
+				}
+				if policy.MaxFails < 0 || maxFails < policy.MaxFails {
+					policy.MaxFails = maxFails
+				}
+			}
+			if policy.MaxFails < 0 {
+				policy.MaxFails = 1
+			}
+		}
+
+		policy.Attempts = 1
+		if len(policy.Conditions) > 0 {
+			policy.Attempts = policy.Servers
+			if policy.Tries > 0 && policy.Tries < policy.Attempts {
+				policy.Attempts = policy.Tries
+			}
+		}
+		result = append(result, policy)
+		return true
+	})
+	return result
+}
+
+func hasParam(args []string, param string) bool {
+	for _, arg := range args {
+		if arg == param {
+			return true
+		}
+	}
+	return false
+}
+
+// retriesResponses reports whether the policy retries on error responses,
+// which also count as failures for max_fails, except http_403 and
+// http_404.
+func (p *RetryPolicy) retriesResponses() bool {
+	for _, condition := range p.Conditions {
+		if strings.HasPrefix(condition, "http_") && condition != "http_403" && condition != "http_404" {
+			return true
+		}
+	}
+	return false
+}
+
+func checkRetryPolicies(directives []*Directive) []*Issue {
+	issues := make([]*Issue, 0)
+	for _, policy := range RetryPolicies(directives) {
+		d := policy.Pass
+		next := policy.Module + "_next_upstream"
+		if policy.NonIdempotent && policy.Attempts > 1 {
+			issues = append(issues, newIssue("retry-policy", d, "%s non_idempotent retries POST and other non-idempotent requests up to %d times against %s", next, policy.Attempts, d.Args[0]))
+		}
+		if policy.retriesResponses() && policy.Tries == 0 && policy.Timeout == "" && policy.Attempts > 2 {
+			issues = append(issues, newIssue("retry-policy", d, "%s retries error responses on all %d servers of %s without %s_tries or %s_timeout, a failing backend multiplies the load on the others", next, policy.Servers, policy.Upstream, next, next))
+		}
+		if policy.Upstream != "" && policy.Tries > policy.Servers {
+			issues = append(issues, newIssue("retry-policy", d, "%s_tries %d exceeds the %d servers of upstream %s, nginx tries each server once", next, policy.Tries, policy.Servers, policy.Upstream))
+		}
+		if policy.Upstream != "" && policy.Servers > 1 && policy.MaxFails == 1 && policy.retriesResponses() {
+			issues = append(issues, newIssue("retry-policy", d, "%s counts error responses as failures and upstream %s marks a server down after max_fails=1, a single error takes it out of rotation", next, policy.Upstream))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,65 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestRetryPolicies(t *testing.T) {
+	directives, err := New(&ParseOptions{}).ParseString(`http {
+    proxy_next_upstream error timeout http_502 http_503;
+    upstream app {
+        server 10.0.0.1;
+        server 10.0.0.2 max_fails=3;
+        server 10.0.0.3 max_fails=2;
+        server 10.0.0.4 down;
+    }
+    server {
+        location / {
+            proxy_pass http://app;
+        }
+        location /api {
+            proxy_next_upstream error non_idempotent;
+            proxy_next_upstream_tries 5;
+            proxy_pass http://app;
+        }
+        location /once {
+            proxy_next_upstream off;
+            proxy_pass http://app;
+        }
+        location /direct {
+            proxy_next_upstream error;
+            grpc_pass grpc://127.0.0.1:9000;
+        }
+    }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies := RetryPolicies(directives)
+	if len(policies) != 4 {
+		t.Fatalf("expected 4 policies, got %d", len(policies))
+	}
+	root, api, once, direct := policies[0], policies[1], policies[2], policies[3]
+	if root.Upstream != "app" || root.Servers != 3 || root.MaxFails != 1 || root.Attempts != 3 || len(root.Conditions) != 4 {
+		t.Fatalf("unexpected policy %+v", root)
+	}
+	if !api.NonIdempotent || api.Tries != 5 || api.Attempts != 3 {
+		t.Fatalf("unexpected policy %+v", api)
+	}
+	if len(once.Conditions) != 0 || once.Attempts != 1 {
+		t.Fatalf("unexpected policy %+v", once)
+	}
+	if direct.Module != "grpc" || len(direct.Conditions) != 2 || direct.Servers != 1 || direct.Attempts != 1 {
+		t.Fatalf("expected grpc_pass to keep the default conditions, got %+v", direct)
+	}
+
+	issues := checkRetryPolicies(directives)
+	lines := make(map[int]int)
+	for _, issue := range issues {
+		lines[issue.Line]++
+	}
+	if len(issues) != 4 || lines[11] != 2 || lines[16] != 2 {
+		t.Fatalf("unexpected issues %+v", issues)
+	}
+}
@@ -0,0 +1,122 @@
+package nginxparser
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// CheckRoundTrip parses src, emits it, parses the output again and reports
+// whether both parses produced the same directives, arguments and
+// comments. diff shows the lines of the first output that emitting the
+// second parse changes, or the first directive that differs when the
+// outputs are the same. err is set when src or the output fails to parse.
+func CheckRoundTrip(src []byte) (stable bool, diff string, err error) {
+	first, err := New(&ParseOptions{SingleFile: true}).ParseReader(bytes.NewReader(src))
+	if err != nil {
+		return false, "", err
+	}
+	var emitted bytes.Buffer
+	if err := NewEmitter(nil).Emit(&emitted, first); err != nil {
+		return false, "", err
+	}
+	second, err := New(&ParseOptions{SingleFile: true}).ParseReader(bytes.NewReader(emitted.Bytes()))
+	if err != nil {
+		return false, "", fmt.Errorf("parsing the emitted configuration: %w", err)
+	}
+	if Equal(first, second, IgnoreLines()) {
+		return true, "", nil
+	}
+	var reemitted bytes.Buffer
+	if err := NewEmitter(nil).Emit(&reemitted, second); err != nil {
+		return false, "", err
+	}
+	if diff = lineDiff(emitted.String(), reemitted.String()); diff == "" {
+		diff = firstDifference(first, second)
+	}
+	return false, diff, nil
+}
+
+// lineDiff lists the lines removed from a with "-" and added in b with
+// "+", each group of changes headed by its line number in a.
+func lineDiff(a, b string) string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+	x, y = x[prefix:len(x)-suffix], y[prefix:len(y)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var buf strings.Builder
+	changing := false
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		if i < len(x) && j < len(y) && x[i] == y[j] {
+			i, j, changing = i+1, j+1, false
+			continue
+		}
+		if !changing {
+			fmt.Fprintf(&buf, "@@ line %d @@\n", prefix+i+1)
+			changing = true
+		}
+		if j == len(y) || i < len(x) && lcs[i+1][j] >= lcs[i][j+1] {
+			fmt.Fprintf(&buf, "-%s\n", x[i])
+			i++
+		} else {
+			fmt.Fprintf(&buf, "+%s\n", y[j])
+			j++
+		}
+	}
+	return buf.String()
+}
+
+// firstDifference describes the first directive of a that differs in b.
+func firstDifference(a, b []*Directive) string {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		switch {
+		case i >= len(b):
+			return fmt.Sprintf("line %d: %s removed", a[i].Line, describeDirective(a[i]))
+		case i >= len(a):
+			return fmt.Sprintf("line %d: %s added", b[i].Line, describeDirective(b[i]))
+		case a[i].Directive != b[i].Directive || !equalStrings(a[i].Args, b[i].Args) || a[i].Comment != b[i].Comment || isBlock(a[i]) != isBlock(b[i]):
+			return fmt.Sprintf("line %d: %s became %s", a[i].Line, describeDirective(a[i]), describeDirective(b[i]))
+		}
+		if diff := firstDifference(a[i].Block, b[i].Block); diff != "" {
+			return diff
+		}
+	}
+	return ""
+}
+
+func describeDirective(d *Directive) string {
+	s := fmt.Sprintf("%s %q", d.Directive, d.Args)
+	if isBlock(d) {
+		s += " {}"
+	}
+	if d.Comment != "" {
+		s += " #" + d.Comment
+	}
+	return s
+}
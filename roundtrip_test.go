@@ -0,0 +1,45 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestCheckRoundTrip(t *testing.T) {
+	stable, diff, err := CheckRoundTrip([]byte(`
+http {
+    map $host $backend { "~^x" 1; default ""; }
+    server {
+        listen 80; # plain
+        location / {
+            # comment
+            return 200 "a;b 'c'";
+        }
+    }
+}`))
+	if err != nil || !stable || diff != "" {
+		t.Fatalf("expected a stable round trip, got %v %q %v", stable, diff, err)
+	}
+
+	stable, diff, err = CheckRoundTrip([]byte("server_name a # between\n    b;\n"))
+	if err != nil || stable {
+		t.Fatalf("expected a comment between arguments to be unstable, got %v %v", stable, err)
+	}
+	if diff != `line 1: server_name ["a" "b"] # between became server_name ["a" "b"]` {
+		t.Fatalf("unexpected diff %q", diff)
+	}
+
+	if _, _, err := CheckRoundTrip([]byte(`return 200 "unterminated;`)); err == nil {
+		t.Fatal("expected a parse error")
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	diff := lineDiff("a\nb\nc\nd\ne\n", "a\nB\nc\nd\ne\nf\n")
+	expected := "@@ line 2 @@\n-b\n+B\n@@ line 6 @@\n+f\n"
+	if diff != expected {
+		t.Fatalf("expected %q but got %q", expected, diff)
+	}
+	if diff := lineDiff("a\n", "a\n"); diff != "" {
+		t.Fatalf("expected no diff, got %q", diff)
+	}
+}
@@ -0,0 +1,108 @@
+package nginxparser
+
+import (
+	"strings"
+)
+
+type Route struct {
+	Server   *Directive `json:"-"`
+	Location *Directive `json:"-"`
+	Host     string     `json:"host"`
+	Port     string     `json:"port,omitempty"`
+	URI      string     `json:"uri"`
+	// Ifs are the if blocks whose conditions hold, set by Env.Route.
+	Ifs []*Directive `json:"-"`
+}
+
+// FindServer selects the server block nginx uses for a request to host on
+// port, following the server_name precedence: exact name, longest leading
+// wildcard, longest trailing wildcard, first matching regex and finally the
+// default server of the port. An empty port matches every listener.
+func FindServer(directives []*Directive, host, port string) *Directive {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	candidates := make([]*Directive, 0)
+	var fallback *Directive
+	for _, server := range servers(directives) {
+		listens := serverListens(server)
+		if len(listens) == 0 {
+			listens = append(listens, &Listen{Port: "80", Params: make(map[string]bool)})
+		}
+		for _, listen := range listens {
+			if port != "" && listen.Port != port {
+				continue
+			}
+			candidates = append(candidates, server)
+			if fallback == nil || (listen.Has("default_server") || listen.Has("default")) && !isDefaultServer(fallback, port) {
+				fallback = server
+			}
+			break
+		}
+	}
+
+	var leading, trailing, regex *Directive
+	leadingLen, trailingLen := 0, 0
+	for _, server := range candidates {
+		for _, name := range serverNames(server) {
+			name = strings.ToLower(name)
+			switch {
+			case name == host:
+				return server
+			case strings.HasPrefix(name, "~"):
+				if regex != nil {
+					continue
+				}
+				if re, err := compileRegex(name[1:], true); err == nil && re.MatchString(host) {
+					regex = server
+				}
+			case strings.HasPrefix(name, "*.") || strings.HasPrefix(name, "."):
+				suffix := strings.TrimPrefix(name, "*")
+				if (strings.HasSuffix(host, suffix) || (name[0] == '.' && host == name[1:])) && len(suffix) > leadingLen {
+					leading, leadingLen = server, len(suffix)
+				}
+			case strings.HasSuffix(name, ".*"):
+				prefix := strings.TrimSuffix(name, "*")
+				if strings.HasPrefix(host, prefix) && len(prefix) > trailingLen {
+					trailing, trailingLen = server, len(prefix)
+				}
+			}
+		}
+	}
+	switch {
+	case leading != nil:
+		return leading
+	case trailing != nil:
+		return trailing
+	case regex != nil:
+		return regex
+	}
+	return fallback
+}
+
+func isDefaultServer(server *Directive, port string) bool {
+	for _, listen := range serverListens(server) {
+		if (port == "" || listen.Port == port) && (listen.Has("default_server") || listen.Has("default")) {
+			return true
+		}
+	}
+	return false
+}
+
+func serverNames(server *Directive) []string {
+	names := make([]string, 0)
+	for _, d := range findAll(server.Block, "server_name") {
+		names = append(names, d.Args...)
+	}
+	return names
+}
+
+// RouteRequest simulates how nginx selects the server and location for a
+// request. The location is nil when no location matches. A URI naming a
+// location, such as @fallback, simulates an internal redirect to it.
+func RouteRequest(directives []*Directive, host, port, uri string) *Route {
+	route := &Route{Host: host, Port: port, URI: uri}
+	route.Server = FindServer(directives, host, port)
+	if route.Server != nil {
+		route.Location = InternalRedirect(route.Server, uri)
+	}
+	return route
+}
@@ -0,0 +1,66 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestRouteRequest(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        listen 80;
+        server_name example.com www.example.com;
+        location / { return 200 main; }
+    }
+    server {
+        listen 80 default_server;
+        server_name _;
+        location / { return 444; }
+    }
+    server {
+        listen 80;
+        server_name *.example.com;
+        location / { return 200 wildcard; }
+    }
+    server {
+        listen 80;
+        server_name *.api.example.com;
+        location / { return 200 api; }
+    }
+    server {
+        listen 80;
+        server_name ~^(?<user>.+)\.users\.example\.org$;
+        location / { return 200 regex; }
+    }
+    server {
+        listen 8080;
+        server_name example.com;
+        location / { return 200 alt; }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures := []struct {
+		host, port, expected string
+	}{
+		{"example.com", "80", "main"},
+		{"WWW.example.com.", "80", "main"},
+		{"foo.example.com", "80", "wildcard"},
+		{"v1.api.example.com", "80", "api"},
+		{"alice.users.example.org", "80", "regex"},
+		{"unknown.org", "80", "444"},
+		{"example.com", "8080", "alt"},
+	}
+	for _, fixture := range fixtures {
+		route := RouteRequest(directives, fixture.host, fixture.port, "/")
+		if route.Location == nil {
+			t.Fatalf("%s:%s: no location found", fixture.host, fixture.port)
+		}
+		ret := findFirst(route.Location.Block, "return")
+		if actual := ret.Args[len(ret.Args)-1]; actual != fixture.expected {
+			t.Errorf("%s:%s: expected %s but got %s", fixture.host, fixture.port, fixture.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package nginxparser
+
+import (
+	"fmt"
+)
+
+const (
+	ScaffoldStaticSite   = "static-site"
+	ScaffoldReverseProxy = "reverse-proxy"
+	ScaffoldPHPFPM       = "php-fpm"
+	ScaffoldWebSocket    = "websocket-proxy"
+)
+
+type ScaffoldOptions struct {
+	ServerName string
+	Root       string
+	// Backend is the address proxied to, or the FastCGI socket for
+	// php-fpm.
+	Backend        string
+	Certificate    string
+	CertificateKey string
+	// WebSocketPath is the location upgraded to WebSocket.
+	WebSocketPath string
+}
+
+var scaffolds = map[string]func(options *ScaffoldOptions) []*Directive{
+	ScaffoldStaticSite:   scaffoldStaticSite,
+	ScaffoldReverseProxy: scaffoldReverseProxy,
+	ScaffoldPHPFPM:       scaffoldPHPFPM,
+	ScaffoldWebSocket:    scaffoldWebSocket,
+}
+
+// Scaffolds lists the templates Scaffold knows.
+func Scaffolds() []string {
+	return []string{ScaffoldStaticSite, ScaffoldReverseProxy, ScaffoldPHPFPM, ScaffoldWebSocket}
+}
+
+// Scaffold generates a complete nginx.conf for a common setup. Unset
+// options get placeholder values. The directives are attributed to the
+// rule "scaffold <template>".
+func Scaffold(template string, options *ScaffoldOptions) ([]*Directive, error) {
+	build, ok := scaffolds[template]
+	if !ok {
+		return nil, fmt.Errorf("unknown template %q", template)
+	}
+	o := ScaffoldOptions{}
+	if options != nil {
+		o = *options
+	}
+	if o.ServerName == "" {
+		o.ServerName = "example.com"
+	}
+	if o.Root == "" {
+		o.Root = "/var/www/html"
+	}
+	if o.Backend == "" {
+		o.Backend = "127.0.0.1:8080"
+		if template == ScaffoldPHPFPM {
+			o.Backend = "unix:/run/php/php-fpm.sock"
+		}
+	}
+	if o.Certificate == "" {
+		o.Certificate = "/etc/nginx/ssl/" + o.ServerName + ".crt"
+	}
+	if o.CertificateKey == "" {
+		o.CertificateKey = "/etc/nginx/ssl/" + o.ServerName + ".key"
+	}
+	if o.WebSocketPath == "" {
+		o.WebSocketPath = "/ws/"
+	}
+	directives := build(&o)
+	markSynthesized("scaffold "+template, directives)
+	return directives, nil
+}
+
+func scaffoldMain(http ...*Directive) []*Directive {
+	return []*Directive{
+		NewDirective("worker_processes", "auto"),
+		NewBlock("events", nil, NewDirective("worker_connections", "1024")),
+		NewBlock("http", nil, append([]*Directive{
+			NewDirective("include", "mime.types"),
+			NewDirective("default_type", "application/octet-stream"),
+			NewDirective("sendfile", "on"),
+			NewDirective("keepalive_timeout", "65"),
+		}, http...)...),
+	}
+}
+
+func scaffoldTLS(options *ScaffoldOptions, locations ...*Directive) []*Directive {
+	return []*Directive{
+		NewBlock("server", nil,
+			NewDirective("listen", "80"),
+			NewDirective("server_name", options.ServerName),
+			NewDirective("return", "301", "https://$host$request_uri"),
+		),
+		NewBlock("server", nil, append([]*Directive{
+			NewDirective("listen", "443", "ssl"),
+			NewDirective("http2", "on"),
+			NewDirective("server_name", options.ServerName),
+			NewDirective("ssl_certificate", options.Certificate),
+			NewDirective("ssl_certificate_key", options.CertificateKey),
+			NewDirective("ssl_protocols", "TLSv1.2", "TLSv1.3"),
+		}, locations...)...),
+	}
+}
+
+func scaffoldProxyHeaders() []*Directive {
+	return []*Directive{
+		NewDirective("proxy_set_header", "Host", "$host"),
+		NewDirective("proxy_set_header", "X-Real-IP", "$remote_addr"),
+		NewDirective("proxy_set_header", "X-Forwarded-For", "$proxy_add_x_forwarded_for"),
+		NewDirective("proxy_set_header", "X-Forwarded-Proto", "$scheme"),
+		NewDirective("proxy_set_header", "X-Request-ID", "$request_id"),
+	}
+}
+
+func scaffoldStaticSite(options *ScaffoldOptions) []*Directive {
+	return scaffoldMain(NewBlock("server", nil,
+		NewDirective("listen", "80"),
+		NewDirective("server_name", options.ServerName),
+		NewDirective("root", options.Root),
+		NewDirective("index", "index.html"),
+		NewBlock("location", []string{"/"},
+			NewDirective("try_files", "$uri", "$uri/", "=404"),
+		),
+	))
+}
+
+func scaffoldReverseProxy(options *ScaffoldOptions) []*Directive {
+	return scaffoldMain(append([]*Directive{
+		NewBlock("upstream", []string{"backend"},
+			NewDirective("server", options.Backend),
+			NewDirective("keepalive", "32"),
+		),
+	}, scaffoldTLS(options,
+		NewBlock("location", []string{"/"}, append([]*Directive{
+			NewDirective("proxy_pass", "http://backend"),
+			NewDirective("proxy_http_version", "1.1"),
+			NewDirective("proxy_set_header", "Connection", ""),
+		}, scaffoldProxyHeaders()...)...),
+	)...)...)
+}
+
+func scaffoldPHPFPM(options *ScaffoldOptions) []*Directive {
+	return scaffoldMain(NewBlock("server", nil,
+		NewDirective("listen", "80"),
+		NewDirective("server_name", options.ServerName),
+		NewDirective("root", options.Root),
+		NewDirective("index", "index.php", "index.html"),
+		NewBlock("location", []string{"/"},
+			NewDirective("try_files", "$uri", "$uri/", "/index.php?$query_string"),
+		),
+		NewBlock("location", []string{"~", `\.php$`},
+			NewDirective("try_files", "$uri", "=404"),
+			NewDirective("include", "fastcgi_params"),
+			NewDirective("fastcgi_param", "SCRIPT_FILENAME", "$document_root$fastcgi_script_name"),
+			NewDirective("fastcgi_param", "HTTP_X_REQUEST_ID", "$request_id"),
+			NewDirective("fastcgi_pass", options.Backend),
+		),
+		NewBlock("location", []string{"~", `/\.`},
+			NewDirective("deny", "all"),
+		),
+	))
+}
+
+func scaffoldWebSocket(options *ScaffoldOptions) []*Directive {
+	return scaffoldMain(append([]*Directive{
+		NewBlock("map", []string{"$http_upgrade", "$connection_upgrade"},
+			NewDirective("default", "upgrade"),
+			NewDirective("", "close"),
+		),
+		NewBlock("upstream", []string{"backend"},
+			NewDirective("server", options.Backend),
+		),
+	}, scaffoldTLS(options,
+		NewBlock("location", []string{options.WebSocketPath}, append([]*Directive{
+			NewDirective("proxy_pass", "http://backend"),
+			NewDirective("proxy_http_version", "1.1"),
+			NewDirective("proxy_set_header", "Upgrade", "$http_upgrade"),
+			NewDirective("proxy_set_header", "Connection", "$connection_upgrade"),
+			NewDirective("proxy_read_timeout", "3600s"),
+		}, scaffoldProxyHeaders()[1:]...)...),
+		NewBlock("location", []string{"/"}, append([]*Directive{
+			NewDirective("proxy_pass", "http://backend"),
+		}, scaffoldProxyHeaders()...)...),
+	)...)...)
+}
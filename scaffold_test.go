@@ -0,0 +1,40 @@
+package nginxparser
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestScaffold(t *testing.T) {
+	for _, template := range Scaffolds() {
+		t.Run(template, func(t *testing.T) {
+			directives, err := Scaffold(template, &ScaffoldOptions{ServerName: "app.example.com"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			issues, err := Lint(directives)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(issues) != 0 {
+				t.Fatalf("unexpected issues %v", issues)
+			}
+
+			var buf bytes.Buffer
+			if err := Dump(directives, &buf); err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := New(&ParseOptions{SingleFile: true}).ParseString(buf.String())
+			if err != nil {
+				t.Fatalf("unexpected error %s in:\n%s", err, buf.String())
+			}
+			if !reflect.DeepEqual(stripPositions(parsed), stripPositions(directives)) {
+				t.Fatalf("scaffold does not parse back:\n%s", buf.String())
+			}
+		})
+	}
+	if _, err := Scaffold("nope", nil); err == nil {
+		t.Fatal("expected error for an unknown template")
+	}
+}
@@ -0,0 +1,203 @@
+package nginxparser
+
+import (
+	"strings"
+)
+
+// SliceByHost extracts the part of a configuration that serves requests
+// for host: the http servers nginx selects for it on each port, with the
+// upstreams, maps, geo and split_clients variables and log formats they
+// use, and the rest of the http and main settings. stream and mail blocks
+// are left out. Include directives are kept with the part of their
+// content that remains, emit with EmitOptions.Flatten for a
+// self-contained file. The copies record where they were copied from in
+// their Provenance.
+func SliceByHost(directives []*Directive, host string) []*Directive {
+	selected := make(map[*Directive]bool)
+	ports := make(map[string]bool)
+	for _, server := range servers(directives) {
+		for _, listen := range serverListens(server) {
+			ports[listen.Port] = true
+		}
+	}
+	if len(ports) == 0 {
+		ports["80"] = true
+	}
+	for port := range ports {
+		if server := FindServer(directives, host, port); server != nil {
+			selected[server] = true
+		}
+	}
+	return slice(directives, func(server *Directive) bool {
+		return selected[server]
+	})
+}
+
+// SliceByPort extracts the part of a configuration reachable on a port:
+// the http servers listening on it and what they depend on, as for
+// SliceByHost.
+func SliceByPort(directives []*Directive, port string) []*Directive {
+	return slice(directives, func(server *Directive) bool {
+		listens := serverListens(server)
+		if len(listens) == 0 {
+			return port == "80"
+		}
+		for _, listen := range listens {
+			if listen.Port == port {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// slice copies a configuration keeping the http servers keep selects and
+// the definitions they depend on.
+func slice(directives []*Directive, keep func(server *Directive) bool) []*Directive {
+	definitions := make(map[string][]*Directive)
+	defined := make(map[*Directive]bool)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if key := definitionKey(d); key != "" && enclosing("http", parents) != nil {
+			definitions[key] = append(definitions[key], d)
+			defined[d] = true
+			return false
+		}
+		return d.Directive != "server"
+	})
+
+	used := make(map[*Directive]bool)
+	queue := make([]string, 0)
+	seen := make(map[string]bool)
+	scan := func(block []*Directive) {
+		Walk(block, func(d *Directive, parents []*Directive) bool {
+			switch {
+			case d.Directive == "stream" || d.Directive == "mail":
+				return false
+			case defined[d] && !used[d]:
+				return false
+			case d.Directive == "server" && len(parents) > 0 && parents[len(parents)-1].Directive == "http" && !keep(d):
+				return false
+			}
+			for _, key := range references(d) {
+				if !seen[key] {
+					seen[key] = true
+					queue = append(queue, key)
+				}
+			}
+			return true
+		})
+	}
+	scan(directives)
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		for _, d := range definitions[key] {
+			used[d] = true
+			scan([]*Directive{d})
+		}
+	}
+
+	result := sliceBlock(directives, func(d *Directive, http bool) bool {
+		switch {
+		case d.Directive == "stream" || d.Directive == "mail":
+			return false
+		case defined[d]:
+			return used[d]
+		case http && d.Directive == "server":
+			return keep(d)
+		}
+		return true
+	}, false)
+	for _, d := range result {
+		MarkMoved(d)
+	}
+	SetParents(result)
+	return result
+}
+
+// sliceBlock copies a block without the directives keep rejects and the
+// comments attached to them.
+func sliceBlock(directives []*Directive, keep func(d *Directive, http bool) bool, http bool) []*Directive {
+	if directives == nil {
+		return nil
+	}
+	result := make([]*Directive, 0, len(directives))
+	pending := make([]*Directive, 0)
+	dropped := false
+	for _, d := range directives {
+		if d.Directive == "#" {
+			if d.Inline {
+				if !dropped {
+					result = append(result, cloneDirectives([]*Directive{d})[0])
+				}
+			} else {
+				pending = append(pending, d)
+			}
+			continue
+		}
+		if dropped = !keep(d, http); dropped {
+			pending = pending[:0]
+			continue
+		}
+		clone := cloneDirectives([]*Directive{d})[0]
+		switch {
+		case d.Directive == "http":
+			clone.Block = sliceBlock(d.Block, keep, true)
+		case d.Directive == "include":
+			clone.Block = sliceBlock(d.Block, keep, http)
+			if len(d.Block) > 0 && len(children(clone.Block)) == 0 {
+				pending, dropped = pending[:0], true
+				continue
+			}
+		case d.Directive != "server" && d.Directive != "upstream" && !isLuaBlock(d):
+			clone.Block = sliceBlock(d.Block, keep, false)
+		}
+		result = append(result, cloneDirectives(pending)...)
+		result = append(result, clone)
+		pending = pending[:0]
+	}
+	return append(result, cloneDirectives(pending)...)
+}
+
+// definitionKey names what an http level directive defines for others to
+// reference: an upstream, a variable or a log format.
+func definitionKey(d *Directive) string {
+	switch d.Directive {
+	case "upstream":
+		if len(d.Args) > 0 {
+			return "upstream " + d.Args[0]
+		}
+	case "map", "split_clients":
+		if len(d.Args) == 2 {
+			return "$" + strings.TrimPrefix(d.Args[1], "$")
+		}
+	case "geo":
+		if len(d.Args) > 0 {
+			return "$" + strings.TrimPrefix(d.Args[len(d.Args)-1], "$")
+		}
+	case "log_format":
+		if len(d.Args) > 0 {
+			return "log_format " + d.Args[0]
+		}
+	}
+	return ""
+}
+
+// references lists the definitions a directive uses, named as by
+// definitionKey.
+func references(d *Directive) []string {
+	result := make([]string, 0)
+	for _, arg := range d.Args {
+		for _, name := range Variables(arg) {
+			result = append(result, "$"+name)
+		}
+	}
+	switch {
+	case passDirectives[d.Directive] && len(d.Args) > 0:
+		_, host, _ := splitPassTarget(d.Args[0])
+		result = append(result, "upstream "+host)
+	case d.Directive == "access_log" && len(d.Args) > 1 && !strings.Contains(d.Args[1], "="):
+		result = append(result, "log_format "+d.Args[1])
+	}
+	return result
+}
@@ -0,0 +1,164 @@
+package nginxparser
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSliceByHost(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+user nginx;
+events {
+}
+http {
+    log_format main $remote_addr;
+    log_format other $remote_addr;
+    geo $internal {
+        default 0;
+    }
+    map $internal $backend {
+        default app;
+    }
+    map $host $unused {
+        default 0;
+    }
+    upstream app {
+        server 10.0.0.1;
+    }
+    upstream other {
+        server 10.0.0.2;
+    }
+    # the site
+    server {
+        listen 80;
+        server_name example.com;
+        access_log /var/log/example.log main;
+        location / {
+            proxy_pass http://$backend;
+        }
+    }
+    # another site
+    server {
+        listen 80;
+        server_name other.com;
+        access_log /var/log/other.log other;
+        location / {
+            proxy_pass http://other;
+        }
+    }
+    server {
+        listen 443 ssl;
+        server_name example.com;
+        location / {
+            proxy_pass http://app;
+        }
+    } # tls
+}
+stream {
+    server {
+        listen 53;
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sliced := SliceByHost(directives, "example.com")
+	var buf bytes.Buffer
+	if err := Dump(sliced, &buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `user nginx;
+events {
+}
+http {
+    log_format main $remote_addr;
+    geo $internal {
+        default 0;
+    }
+    map $internal $backend {
+        default app;
+    }
+    upstream app {
+        server 10.0.0.1;
+    }
+    # the site
+    server {
+        listen 80;
+        server_name example.com;
+        access_log /var/log/example.log main;
+        location / {
+            proxy_pass http://$backend;
+        }
+    }
+    server {
+        listen 443 ssl;
+        server_name example.com;
+        location / {
+            proxy_pass http://app;
+        }
+    } # tls
+}
+`
+	if buf.String() != expected {
+		t.Fatalf("expected\n%s\nbut got\n%s", expected, buf.String())
+	}
+	if sliced[2].Block[0].Parent != sliced[2] {
+		t.Fatal("expected the slice to have its own parents")
+	}
+	if len(directives[2].Block) != 13 {
+		t.Fatal("expected the original tree to be left alone")
+	}
+}
+
+func TestSliceByPort(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    upstream app {
+        server 10.0.0.1;
+    }
+    upstream static {
+        server 10.0.0.2;
+    }
+    server {
+        listen 443 ssl;
+        listen [::]:443 ssl;
+        proxy_pass http://app;
+    }
+    server {
+        proxy_pass http://static;
+    }
+    server {
+        listen 127.0.0.1:8080;
+        proxy_pass http://static;
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sliced := SliceByPort(directives, "443")
+	var buf bytes.Buffer
+	if err := Dump(sliced, &buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `http {
+    upstream app {
+        server 10.0.0.1;
+    }
+    server {
+        listen 443 ssl;
+        listen [::]:443 ssl;
+        proxy_pass http://app;
+    }
+}
+`
+	if buf.String() != expected {
+		t.Fatalf("expected\n%s\nbut got\n%s", expected, buf.String())
+	}
+
+	sliced = SliceByPort(directives, "80")
+	if http := sliced[0]; len(http.Block) != 2 || http.Block[1].Block[0].Args[0] != "http://static" {
+		t.Fatalf("expected the server without listen on port 80, got %+v", http.Block)
+	}
+}
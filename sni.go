@@ -0,0 +1,154 @@
+package nginxparser
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
+
+// CheckCertificateNames matches the server names of every server listening
+// with ssl against the names its certificates cover. It reports the host
+// names clients would get a certificate warning for and the certificates
+// that cover none of the names of the servers using them. Regular
+// expression names, names ending in .* and servers with certificates that
+// cannot be read are skipped, CheckTLS reports the latter.
+func CheckCertificateNames(directives []*Directive, options *TLSPreflightOptions) []*Issue {
+	if options == nil {
+		options = &TLSPreflightOptions{}
+	}
+	certificates := make(map[*Directive][]*Certificate)
+	for _, certificate := range Certificates(directives) {
+		certificates[certificate.Server] = append(certificates[certificate.Server], certificate)
+	}
+	leaves := make(map[string]*x509.Certificate)
+	leaf := func(name string) *x509.Certificate {
+		if cached, ok := leaves[name]; ok {
+			return cached
+		}
+		leaves[name] = nil
+		if skipTLSFile(name) {
+			return nil
+		}
+		data, err := options.read(name)
+		if err != nil {
+			return nil
+		}
+		if chain, err := parseCertificates(data); err == nil && len(chain) > 0 {
+			leaves[name] = chain[0]
+		}
+		return leaves[name]
+	}
+
+	issues := make([]*Issue, 0)
+	// serving records for each ssl_certificate whether it covers a name of
+	// any server using it, in the order they were found.
+	serving := make(map[*Directive]bool)
+	order := make([]*Certificate, 0)
+	for _, server := range servers(directives) {
+		listeners := sslListeners(directives, server)
+		if len(listeners) == 0 {
+			continue
+		}
+		if len(certificates[server]) == 0 {
+			issues = append(issues, newIssue("tls-names", server, "server listening with ssl on %s has no ssl_certificate", strings.Join(listeners, ", ")))
+			continue
+		}
+		var leafs []*x509.Certificate
+		var files []string
+		for _, certificate := range certificates[server] {
+			l := leaf(certificate.CertificateFile)
+			if l == nil {
+				leafs = nil
+				break
+			}
+			leafs, files = append(leafs, l), append(files, certificate.CertificateFile)
+		}
+		if leafs == nil {
+			continue
+		}
+
+		checked := false
+		covered := make([]bool, len(leafs))
+		for _, d := range findAll(server.Block, "server_name") {
+			for i, name := range d.Args {
+				if !checkableServerName(name) {
+					continue
+				}
+				checked = true
+				coveredBy := false
+				for j, l := range leafs {
+					if coversServerName(l, strings.ToLower(name)) {
+						covered[j], coveredBy = true, true
+					}
+				}
+				if !coveredBy {
+					issues = append(issues, newArgIssue("tls-names", d, i, "server name %s on %s is not covered by certificate %s", name, strings.Join(listeners, ", "), strings.Join(files, ", ")))
+				}
+			}
+		}
+		if !checked {
+			continue
+		}
+		for j, certificate := range certificates[server] {
+			if _, ok := serving[certificate.Certificate]; !ok {
+				order = append(order, certificate)
+			}
+			serving[certificate.Certificate] = serving[certificate.Certificate] || covered[j]
+		}
+	}
+	for _, certificate := range order {
+		if !serving[certificate.Certificate] {
+			l := leaf(certificate.CertificateFile)
+			issues = append(issues, newIssue("tls-names", certificate.Certificate, "certificate %s for %s covers none of the server names using it", certificate.CertificateFile, strings.Join(certificateNames(l), ", ")))
+		}
+	}
+	return issues
+}
+
+// sslListeners returns the addresses a server accepts TLS connections on.
+func sslListeners(directives []*Directive, server *Directive) []string {
+	legacy := false
+	if d := lookupInherited("ssl", server.Block, parentsOf(directives, server)); d != nil && len(d.Args) > 0 {
+		legacy = d.Args[0] == "on"
+	}
+	result := make([]string, 0)
+	for _, listen := range serverListens(server) {
+		if legacy || listen.Has("ssl") || listen.Has("quic") {
+			result = append(result, fmt.Sprintf("%s:%s", listen.Address, listen.Port))
+		}
+	}
+	return result
+}
+
+func checkableServerName(name string) bool {
+	return name != "" && name != "_" && !strings.HasPrefix(name, "~") && !strings.HasSuffix(name, ".*") && !strings.Contains(name, "$")
+}
+
+// coversServerName reports whether leaf is valid for every host the server
+// name matches: a wildcard server name needs the same wildcard in the
+// certificate, and .example.com needs both example.com and *.example.com.
+func coversServerName(leaf *x509.Certificate, name string) bool {
+	switch {
+	case strings.HasPrefix(name, "*."):
+		for _, san := range leaf.DNSNames {
+			if strings.ToLower(san) == name {
+				return true
+			}
+		}
+		return false
+	case strings.HasPrefix(name, "."):
+		return leaf.VerifyHostname(name[1:]) == nil && coversServerName(leaf, "*"+name)
+	}
+	return leaf.VerifyHostname(name) == nil
+}
+
+func certificateNames(leaf *x509.Certificate) []string {
+	names := append([]string{}, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		names = append(names, ip.String())
+	}
+	if len(names) == 0 {
+		names = append(names, leaf.Subject.CommonName)
+	}
+	return names
+}
@@ -0,0 +1,87 @@
+package nginxparser
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCheckCertificateNames(t *testing.T) {
+	root := newTestCertificate(t, "root", nil, true)
+	files := map[string][]byte{
+		"example.pem":  newTestCertificate(t, "example.com", root, false).pem,
+		"wildcard.pem": newTestCertificate(t, "*.example.com", root, false).pem,
+		"stale.pem":    newTestCertificate(t, "old.example.org", root, false).pem,
+	}
+	options := &TLSPreflightOptions{ReadFile: func(name string) ([]byte, error) {
+		if data, ok := files[name]; ok {
+			return data, nil
+		}
+		return nil, os.ErrNotExist
+	}}
+
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    ssl_certificate wildcard.pem;
+    server {
+        listen 443 ssl;
+        server_name www.example.com *.example.com ~^api\d+\.example\.com$;
+    }
+    server {
+        listen 443 ssl;
+        listen 8443 ssl;
+        server_name example.com .example.com;
+        ssl_certificate example.pem;
+    }
+    server {
+        listen 443 ssl;
+        server_name shop.example.net;
+        ssl_certificate stale.pem;
+    }
+    server {
+        listen 80;
+        server_name plain.example.net;
+        ssl_certificate stale.pem;
+    }
+    server {
+        listen 443 ssl;
+        server_name missing.example.com;
+        ssl_certificate missing.pem;
+    }
+    server {
+        listen 443 ssl default_server;
+        server_name _;
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues := CheckCertificateNames(directives, options)
+	expected := []string{
+		"server name .example.com on *:443, *:8443 is not covered by certificate example.pem",
+		"server name shop.example.net on *:443 is not covered by certificate stale.pem",
+		"certificate stale.pem for old.example.org covers none of the server names using it",
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	for i, issue := range issues {
+		if issue.Rule != "tls-names" || issue.Message != expected[i] {
+			t.Fatalf("expected %q, got %s", expected[i], issue)
+		}
+	}
+
+	directives, err = New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        listen 443 ssl;
+        server_name example.com;
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := CheckCertificateNames(directives, options); len(issues) != 1 || !strings.Contains(issues[0].Message, "has no ssl_certificate") {
+		t.Fatalf("expected a missing certificate, got %v", issues)
+	}
+}
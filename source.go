@@ -0,0 +1,216 @@
+package nginxparser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+)
+
+// sourceReader tracks the offset of the next byte to be read so directives
+// can be mapped back to the source text.
+type sourceReader struct {
+	*bufio.Reader
+	src     []byte
+	offset  int
+	newline int
+	// lineStart is the offset of the first byte of the current line.
+	lineStart int
+	// lf and crlf count the line endings read so far.
+	lf, crlf int
+	last     byte
+}
+
+// newSourceReader reads the whole source up front when keep is set, so
+// the text of directives can be recorded. Reading fails with the error of
+// ctx once it is done, checked every time the buffer is refilled.
+func newSourceReader(ctx context.Context, rd io.Reader, keep bool) (*sourceReader, error) {
+	if !keep {
+		return &sourceReader{Reader: bufio.NewReader(withContext(ctx, rd))}, nil
+	}
+	src, err := io.ReadAll(withContext(ctx, rd))
+	if err != nil {
+		return nil, err
+	}
+	return &sourceReader{Reader: bufio.NewReader(withContext(ctx, bytes.NewReader(src))), src: src}, nil
+}
+
+type contextReader struct {
+	ctx context.Context
+	rd  io.Reader
+}
+
+func withContext(ctx context.Context, rd io.Reader) io.Reader {
+	if ctx.Done() == nil {
+		return rd
+	}
+	return &contextReader{ctx: ctx, rd: rd}
+}
+
+func (r *contextReader) Read(b []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.rd.Read(b)
+}
+
+func (r *sourceReader) ReadByte() (byte, error) {
+	b, err := r.Reader.ReadByte()
+	if err == nil {
+		r.offset++
+		r.count(b)
+	}
+	return b, err
+}
+
+func (r *sourceReader) ReadRune() (rune, int, error) {
+	c, size, err := r.Reader.ReadRune()
+	r.offset += size
+	if size == 1 {
+		r.count(byte(c))
+	} else if size > 1 {
+		r.last = 0
+	}
+	return c, size, err
+}
+
+func (r *sourceReader) count(b byte) {
+	if b == '\n' {
+		r.lineStart = r.offset
+		if r.last == '\r' {
+			r.crlf++
+		} else {
+			r.lf++
+		}
+	}
+	r.last = b
+}
+
+// lineEnding returns the line ending used by most lines read.
+func (r *sourceReader) lineEnding() string {
+	if r.crlf > r.lf {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// ReadLine reads up to and including the next newline and returns the line
+// without it. The length of the newline consumed is kept in r.newline.
+func (r *sourceReader) ReadLine() ([]byte, bool, error) {
+	line, err := r.Reader.ReadBytes('\n')
+	r.offset += len(line)
+	for _, b := range line {
+		r.count(b)
+	}
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	r.newline = 0
+	if bytes.HasSuffix(line, []byte("\n")) {
+		r.newline = 1
+		if bytes.HasSuffix(line, []byte("\r\n")) {
+			r.newline = 2
+		}
+	}
+	return line[:len(line)-r.newline], false, err
+}
+
+// Trivia is the original text of a directive, recorded when parsing with
+// ParseOptions.Lossless so an unmodified tree is emitted byte for byte.
+type Trivia struct {
+	// Before is the whitespace between the previous directive, or the
+	// opening brace, and this directive.
+	Before string
+	// Text is the directive as written, up to and including the
+	// semicolon, or up to and including the opening brace for blocks.
+	Text string
+	// Close is the text after the last child of a block up to and
+	// including its closing brace.
+	Close string
+	// Trailing is the text after the last directive of a file, which End
+	// marks, empty when the file ends right after it.
+	Trailing string
+	End      bool
+
+	fingerprint string
+}
+
+func fingerprint(d *Directive) string {
+	var buf bytes.Buffer
+	buf.WriteString(d.Directive)
+	for i, arg := range d.Args {
+		buf.WriteByte(0)
+		buf.WriteByte(byte(d.QuoteAt(i)))
+		buf.WriteString(arg)
+	}
+	buf.WriteByte(0)
+	buf.WriteString(d.Comment)
+	return buf.String()
+}
+
+// Modified reports whether the name, arguments or comment of a directive
+// changed since it was parsed, in which case its Text is stale.
+func (t *Trivia) Modified(d *Directive) bool {
+	return t.fingerprint != fingerprint(d)
+}
+
+// Range is the [Start, End) byte range a directive covers in its file,
+// through the closing brace for blocks.
+type Range struct {
+	Start int
+	End   int
+}
+
+// Text returns the source text of a directive from the contents of the
+// file it was parsed from.
+func (r Range) Text(src []byte) []byte {
+	if r.Start < 0 || r.End > len(src) || r.Start > r.End {
+		return nil
+	}
+	return src[r.Start:r.End]
+}
+
+func (p *parser) layout(reader *sourceReader, d *Directive, gap, start, end int) {
+	if start < 0 {
+		return
+	}
+	d.Range = Range{Start: start, End: end}
+	if reader.src == nil {
+		return
+	}
+	if p.options.Raw {
+		d.Raw = string(reader.src[start:end])
+	}
+	if !p.options.Lossless {
+		return
+	}
+	d.Trivia = &Trivia{
+		Before:      string(reader.src[gap:start]),
+		Text:        string(reader.src[start:end]),
+		fingerprint: fingerprint(d),
+	}
+}
+
+// commentNode creates the node for a comment between the arguments of a
+// directive. Its text is part of the text of the directive, so a lossless
+// parse records nothing for it.
+func (p *parser) commentNode(reader *sourceReader, comment string) *Directive {
+	d := &Directive{
+		Line:      p.line,
+		FileName:  p.filename,
+		Directive: "#",
+		Args:      make([]string, 0),
+		Comment:   comment,
+	}
+	if reader.src != nil {
+		d.Trivia = &Trivia{fingerprint: fingerprint(d)}
+	}
+	return d
+}
+
+func (p *parser) source(reader *sourceReader, start, end int) string {
+	if reader.src == nil {
+		return ""
+	}
+	return string(reader.src[start:end])
+}
@@ -0,0 +1,80 @@
+package nginxparser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRange(t *testing.T) {
+	src := "user nginx;\nhttp {\n    # main\n    if ($host = a) { return 404; }\n    content_by_lua_block {\n        ngx.say(\"}\")\n    }\n}\n"
+	for _, lossless := range []bool{false, true} {
+		directives, err := New(&ParseOptions{SingleFile: true, Lossless: lossless}).ParseString(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		http := directives[1]
+		expected := map[*Directive]string{
+			directives[0]:          "user nginx;",
+			http:                   src[12 : len(src)-1],
+			http.Block[0]:          "# main",
+			http.Block[1]:          "if ($host = a) { return 404; }",
+			http.Block[1].Block[0]: "return 404;",
+			http.Block[2]:          "content_by_lua_block {\n        ngx.say(\"}\")\n    }",
+		}
+		for d, text := range expected {
+			if got := string(d.Range.Text([]byte(src))); got != text {
+				t.Fatalf("expected %q for %s but got %q", text, d.Directive, got)
+			}
+		}
+	}
+
+	root := filepath.Join("testdata", "includes-regular")
+	directives, err := New(&ParseOptions{Root: root}).ParseFile(filepath.Join(root, "nginx.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		src, err := os.ReadFile(d.FileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := d.Range.Text(src)
+		if len(text) == 0 || (d.Directive != "#" && string(text[:len(d.Directive)]) != d.Directive) {
+			t.Fatalf("unexpected text %q for %s at %s:%d", text, d.Directive, d.FileName, d.Line)
+		}
+		return true
+	})
+
+	if text := (Range{Start: 4, End: 100}).Text([]byte(src[:50])); text != nil {
+		t.Fatalf("expected no text outside of the source, got %q", text)
+	}
+}
+
+func TestRaw(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true, Raw: true}).ParseString("http {\n    return 200 \"a\\\"b\"  'c' ;  # done\n    location\n        / {\n    }\n    content_by_lua_block { ngx.say(1) }\n}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	http := directives[0]
+	expected := []string{"http {", "return 200 \"a\\\"b\"  'c' ;", "# done", "location\n        / {", "content_by_lua_block { ngx.say(1) }"}
+	got := []string{http.Raw}
+	for _, d := range http.Block {
+		got = append(got, d.Raw)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %q but got %q", expected, got)
+	}
+	if http.Trivia != nil {
+		t.Fatal("expected no trivia without Lossless")
+	}
+
+	directives, err = New(&ParseOptions{SingleFile: true}).ParseString("user nginx;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if directives[0].Raw != "" {
+		t.Fatalf("expected no raw text without the option, got %q", directives[0].Raw)
+	}
+}
@@ -0,0 +1,102 @@
+package nginxparser
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const sourcePrefix = "source:"
+
+// comment renders the provenance as a source map comment.
+func (p *Provenance) comment() string {
+	fields := []string{sourcePrefix}
+	if p.Object != "" {
+		fields = append(fields, "object="+url.PathEscape(p.Object))
+	}
+	if p.FileName != "" {
+		fields = append(fields, "file="+url.PathEscape(p.FileName))
+	}
+	if p.Line > 0 {
+		fields = append(fields, "line="+strconv.Itoa(p.Line))
+	}
+	if p.Rule != "" {
+		fields = append(fields, "rule="+url.PathEscape(p.Rule))
+	}
+	return strings.Join(fields, " ")
+}
+
+// parseSource reads a source map comment, nil when text is not one.
+func parseSource(text string) *Provenance {
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(text), sourcePrefix))
+	if len(fields) == 0 {
+		return nil
+	}
+	source := &Provenance{}
+	for _, field := range fields {
+		i := strings.IndexByte(field, '=')
+		if i < 0 {
+			return nil
+		}
+		value, err := url.PathUnescape(field[i+1:])
+		if err != nil {
+			return nil
+		}
+		switch field[:i] {
+		case "object":
+			source.Object = value
+		case "file":
+			source.FileName = value
+		case "line":
+			if source.Line, err = strconv.Atoi(value); err != nil {
+				return nil
+			}
+		case "rule":
+			source.Rule = value
+		default:
+			return nil
+		}
+	}
+	return source
+}
+
+func (e *Emitter) source(d *Directive) *Provenance {
+	if e.options.SourceMap == nil || d.Directive == "#" {
+		return nil
+	}
+	return e.options.SourceMap(d)
+}
+
+// staleSource reports whether d is a source map comment the emitter
+// replaces with a fresh one.
+func (e *Emitter) staleSource(d *Directive) bool {
+	return e.options.SourceMap != nil && d.Directive == "#" && !d.Inline && parseSource(d.Comment) != nil
+}
+
+// SourceOf traces a position in a generated configuration, such as the
+// file and line of an nginx -t error, back to where the directive there
+// was generated from: the source map comment of the directive or of the
+// closest enclosing block with one. It returns nil when none has a source.
+func SourceOf(directives []*Directive, fileName string, line int) *Provenance {
+	var found *Provenance
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if found != nil || d.Directive == "#" {
+			return false
+		}
+		if d.FileName != fileName || d.Line != line {
+			return true
+		}
+		for i := len(parents); i >= 0; i-- {
+			current := d
+			if i < len(parents) {
+				current = parents[i]
+			}
+			if current.Annotations != nil && current.Annotations.Source != nil {
+				found = current.Annotations.Source
+				break
+			}
+		}
+		return false
+	})
+	return found
+}
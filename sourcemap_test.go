@@ -0,0 +1,69 @@
+package nginxparser
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSourceMap(t *testing.T) {
+	server := NewBlock("server", nil,
+		NewDirective("server_name", "web.example.com"),
+		NewBlock("location", []string{"/"},
+			NewDirective("proxy_pass", "http://web"),
+		),
+	)
+	server.Provenance = &Provenance{Object: "Ingress default/web", FileName: "web.yaml", Line: 7}
+	server.Block[1].Provenance = &Provenance{Object: "Ingress default/web", FileName: "web.yaml", Line: 12}
+	recorded := func(d *Directive) *Provenance {
+		return d.Provenance
+	}
+	var buf bytes.Buffer
+	if err := NewEmitter(&EmitOptions{SourceMap: recorded}).Emit(&buf, []*Directive{server}); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# source: object=Ingress%20default%2Fweb file=web.yaml line=7
+server {
+    server_name web.example.com;
+    # source: object=Ingress%20default%2Fweb file=web.yaml line=12
+    location / {
+        proxy_pass http://web;
+    }
+}
+`
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed := directives[1]
+	if !reflect.DeepEqual(parsed.Annotations.Source, server.Provenance) || parsed.Origin().String() != "from Ingress default/web (web.yaml:7)" {
+		t.Fatalf("unexpected source %+v", parsed.Annotations)
+	}
+	if source := SourceOf(directives, "", 6); source == nil || source.Line != 12 {
+		t.Fatalf("expected proxy_pass to trace back to line 12, got %+v", source)
+	}
+	if source := SourceOf(directives, "", 3); source == nil || source.Line != 7 {
+		t.Fatalf("expected server_name to trace back to the server, got %+v", source)
+	}
+	if source := SourceOf(directives, "", 100); source != nil {
+		t.Fatalf("expected no source, got %+v", source)
+	}
+
+	var again bytes.Buffer
+	annotated := func(d *Directive) *Provenance {
+		if d.Annotations == nil {
+			return nil
+		}
+		return d.Annotations.Source
+	}
+	if err := NewEmitter(&EmitOptions{SourceMap: annotated}).Emit(&again, directives); err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != expected {
+		t.Fatalf("expected the source comments to be replaced, got:\n%s", again.String())
+	}
+}
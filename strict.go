@@ -0,0 +1,135 @@
+package nginxparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// opaqueBlocks hold data rather than directives, their contents are not
+// checked in strict mode.
+var opaqueBlocks = map[string]bool{
+	"charset_map":   true,
+	"geo":           true,
+	"map":           true,
+	"match":         true,
+	"split_clients": true,
+	"types":         true,
+}
+
+// checkStrict validates every directive reachable from directives against
+// the catalog. The first violation is returned, with CatchErrors set every
+// file with a violation is marked failed in Files instead.
+func (p *parser) checkStrict(directives []*Directive) error {
+	catalog := p.options.Catalog
+	if catalog == nil {
+		catalog = directiveDocs
+	}
+	var first error
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if first != nil && !p.options.CatchErrors {
+			return false
+		}
+		if d.Directive == "#" {
+			return false
+		}
+		if err := p.strictError(catalog, d, parents); err != nil {
+			if first == nil {
+				first = err
+			}
+			if file, ok := p.files[d.FileName]; ok && file.Err == nil {
+				file.Status, file.Err, file.Error = FileFailed, err, err.Error()
+			}
+		}
+		return !opaqueBlocks[d.Directive]
+	})
+	if p.options.CatchErrors {
+		return nil
+	}
+	return first
+}
+
+func (p *parser) strictError(catalog []DirectiveDoc, d *Directive, parents []*Directive) error {
+	docs, known := contextDocs(catalog, d.Directive, parents)
+	if !known {
+		return p.directiveError(ErrUnknownDirective, d, `unknown directive "%s"`, d.Directive)
+	}
+	if len(docs) == 0 {
+		return p.directiveError(ErrInvalidDirective, d, `"%s" directive is not allowed here`, d.Directive)
+	}
+	doc := docs[0]
+	switch {
+	case isLuaBlock(d):
+		// The Lua code of the block is kept in its arguments.
+		if !doc.Block {
+			return p.directiveError(ErrInvalidDirective, d, `directive "%s" is not terminated by ";"`, d.Directive)
+		}
+	case doc.Block && !isBlock(d):
+		return p.directiveError(ErrInvalidDirective, d, `directive "%s" has no opening "{"`, d.Directive)
+	case !doc.Block && isBlock(d) && d.Directive != "include":
+		return p.directiveError(ErrInvalidDirective, d, `directive "%s" is not terminated by ";"`, d.Directive)
+	case len(d.Args) < doc.MinArgs, doc.MaxArgs >= 0 && len(d.Args) > doc.MaxArgs:
+		return p.directiveError(ErrInvalidDirective, d, `invalid number of arguments in "%s" directive`, d.Directive)
+	}
+	if message := unavailable(catalog, d, parents, p.options.Version); !p.options.Version.IsLatest() && message != "" {
+		return p.directiveError(ErrInvalidDirective, d, "%s", message)
+	}
+	return nil
+}
+
+// contextDocs returns the documentation of the directives of the name
+// allowed where parents place it, leaving out the modules of other
+// protocols: a server in a stream block is not the HTTP one. known
+// reports whether the name is documented at all.
+func contextDocs(catalog []DirectiveDoc, name string, parents []*Directive) (docs []DirectiveDoc, known bool) {
+	context := strictContext(parents)
+	var other string
+	switch {
+	case enclosing("http", parents) != nil:
+		other = "ngx_stream_"
+	case enclosing("stream", parents) != nil:
+		other = "ngx_http_"
+	}
+	for _, doc := range catalog {
+		if doc.Name != name {
+			continue
+		}
+		known = true
+		if other != "" && strings.HasPrefix(doc.Module, other) {
+			continue
+		}
+		if doc.AllowedIn(context) || strings.HasPrefix(context, "if in ") && doc.AllowedIn("if") {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, known
+}
+
+// strictContext names the context of a directive the way the
+// documentation does.
+func strictContext(parents []*Directive) string {
+	if len(parents) == 0 {
+		return "main"
+	}
+	name := parents[len(parents)-1].Directive
+	if name == "if" {
+		if len(parents) > 1 && parents[len(parents)-2].Directive == "location" {
+			return "if in location"
+		}
+		return "if in server"
+	}
+	return name
+}
+
+func (p *parser) directiveError(kind error, d *Directive, format string, args ...interface{}) error {
+	var includes []string
+	for _, include := range d.IncludeChain() {
+		includes = append(includes, fmt.Sprintf("%s:%d", include.FileName, include.Line))
+	}
+	return &ParseError{
+		Kind:     kind,
+		FileName: d.FileName,
+		Line:     d.Line,
+		Message:  fmt.Sprintf(format+" in file %s line %d", append(args, d.FileName, d.Line)...),
+		Includes: includes,
+	}
+}
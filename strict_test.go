@@ -0,0 +1,89 @@
+package nginxparser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStrict(t *testing.T) {
+	for _, filename := range []string{"testdata/simple/nginx.conf", "testdata/includes-regular/nginx.conf", "testdata/with-comments/nginx.conf"} {
+		if _, err := New(&ParseOptions{Strict: true, Root: "testdata/includes-regular"}).ParseFile(filename); err != nil {
+			t.Fatalf("%s: %v", filename, err)
+		}
+	}
+
+	directives, err := New(&ParseOptions{Strict: true}).ParseFile("testdata/spelling-mistake/nginx.conf")
+	if directives != nil {
+		t.Fatalf("expected no directives but got %v", directives)
+	}
+	var parseErr *ParseError
+	if !errors.Is(err, ErrUnknownDirective) || !errors.As(err, &parseErr) || parseErr.Line != 7 {
+		t.Fatalf("expected an unknown directive error but got %v", err)
+	}
+	if err.Error() != `unknown directive "proxy_passs" in file testdata/spelling-mistake/nginx.conf line 7` {
+		t.Fatalf("unexpected message %q", err.Error())
+	}
+
+	for config, expected := range map[string]string{
+		`server { listen 80; }`: `"server" directive is not allowed here`,
+		`worker_processes 1 2;`: `invalid number of arguments in "worker_processes" directive`,
+		`events;`:               `directive "events" has no opening "{"`,
+		`http { gzip on { } }`:  `directive "gzip" is not terminated by ";"`,
+		`http { server { location / { if ($a) { gzip_types x; } } } }`: `"gzip_types" directive is not allowed here`,
+		`http { server { location ~ ^/a$ /b { } } }`:                   `invalid number of arguments in "location" directive`,
+	} {
+		_, err := New(&ParseOptions{SingleFile: true, Strict: true}).ParseString(config)
+		if !errors.Is(err, ErrInvalidDirective) || !strings.HasPrefix(err.Error(), expected) {
+			t.Errorf("%s: expected %q but got %v", config, expected, err)
+		}
+	}
+
+	valid := `
+events {}
+http {
+    map $host $backend {
+        default anything goes;
+    }
+    upstream app {
+        server 127.0.0.1:8080 weight=5 max_fails=3 fail_timeout=10s;
+        keepalive 16;
+        keepalive_timeout 30s;
+    }
+    server {
+        listen 443 ssl default_server reuseport backlog=1024;
+        if ($host = a) {
+            return 301 https://b;
+        }
+        location / {
+            if ($arg_x) {
+                add_header X 1;
+                set $y 1;
+            }
+            proxy_pass http://app;
+        }
+    }
+}
+stream {
+    server {
+        listen 53 udp;
+        proxy_pass dns;
+    }
+}`
+	if _, err := New(&ParseOptions{SingleFile: true, Strict: true}).ParseString(valid); err != nil {
+		t.Fatal(err)
+	}
+
+	custom := append(DirectiveDocs(), doc("ngx_http_lua_module", "content_by_lua_block", "{ ... }", "", "location", "", ""))
+	if _, err := New(&ParseOptions{SingleFile: true, Strict: true, Catalog: custom}).ParseString(`http { server { location / { content_by_lua_block { ngx.say("hi") } } } }`); err != nil {
+		t.Fatal(err)
+	}
+
+	parser := New(&ParseOptions{Strict: true, CatchErrors: true})
+	if _, err := parser.ParseFile("testdata/spelling-mistake/nginx.conf"); err != nil {
+		t.Fatal(err)
+	}
+	if file := parser.Files()["testdata/spelling-mistake/nginx.conf"]; file.Status != FileFailed || !errors.Is(file.Err, ErrUnknownDirective) {
+		t.Fatalf("expected the file to be marked failed, got %+v", file)
+	}
+}
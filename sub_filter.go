@@ -0,0 +1,101 @@
+package nginxparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+type SubFilterRule struct {
+	Directive   *Directive `json:"-"`
+	Pattern     string     `json:"pattern"`
+	Replacement string     `json:"replacement"`
+}
+
+type SubFilter struct {
+	Location     *Directive       `json:"-"`
+	Rules        []*SubFilterRule `json:"rules"`
+	Once         bool             `json:"once"`
+	OnceSet      bool             `json:"once_set"`
+	Types        []string         `json:"types"`
+	LastModified bool             `json:"last_modified"`
+	Proxied      bool             `json:"proxied"`
+	// Decompressed is set when the upstream is asked for uncompressed
+	// responses.
+	Decompressed bool `json:"decompressed"`
+	// Precompressed is set when gzip_static serves .gz files as they are.
+	Precompressed bool `json:"precompressed"`
+}
+
+// SubFilters collects the effective sub_filter rules of every location
+// together with the settings that control them.
+func SubFilters(directives []*Directive) []*SubFilter {
+	result := make([]*SubFilter, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive != "location" {
+			return true
+		}
+		filter := &SubFilter{
+			Location: d,
+			Rules:    make([]*SubFilterRule, 0),
+			Once:     true,
+			Types:    []string{"text/html"},
+		}
+		for _, rule := range lookupInheritedAll("sub_filter", d.Block, parents) {
+			if len(rule.Args) == 2 {
+				filter.Rules = append(filter.Rules, &SubFilterRule{Directive: rule, Pattern: rule.Args[0], Replacement: rule.Args[1]})
+			}
+		}
+		if len(filter.Rules) == 0 {
+			return true
+		}
+		if once := lookupInherited("sub_filter_once", d.Block, parents); once != nil && len(once.Args) > 0 {
+			filter.Once, filter.OnceSet = once.Args[0] != "off", true
+		}
+		if types := lookupInherited("sub_filter_types", d.Block, parents); types != nil {
+			filter.Types = append(filter.Types, types.Args...)
+		}
+		if lastModified := lookupInherited("sub_filter_last_modified", d.Block, parents); lastModified != nil && len(lastModified.Args) > 0 {
+			filter.LastModified = lastModified.Args[0] == "on"
+		}
+		filter.Proxied = findFirst(d.Block, "proxy_pass") != nil
+		for _, header := range lookupInheritedAll("proxy_set_header", d.Block, parents) {
+			if len(header.Args) == 2 && strings.EqualFold(header.Args[0], "Accept-Encoding") && (header.Args[1] == "" || strings.EqualFold(header.Args[1], "identity")) {
+				filter.Decompressed = true
+			}
+		}
+		if static := lookupInherited("gzip_static", d.Block, parents); !filter.Proxied && static != nil && len(static.Args) > 0 && static.Args[0] != "off" {
+			filter.Precompressed = true
+		}
+		result = append(result, filter)
+		return true
+	})
+	return result
+}
+
+var nonHTMLPattern = regexp.MustCompile(`(?i)\.(js|css|json|xml|txt|svg)\b`)
+
+// checkSubFilters flags the settings that keep sub_filter from replacing
+// anything: compressed upstream responses and .gz files served by
+// gzip_static. gunzip does not help, it only decompresses for clients that
+// do not accept gzip. gzip on is not flagged, it compresses responses
+// after the substitution.
+func checkSubFilters(directives []*Directive) []*Issue {
+	issues := make([]*Issue, 0)
+	for _, filter := range SubFilters(directives) {
+		first := filter.Rules[0].Directive
+		if !filter.OnceSet {
+			issues = append(issues, newIssue("sub-filter", first, "sub_filter_once defaults to on, only the first match of each rule is replaced"))
+		}
+		_, pattern := locationPattern(filter.Location)
+		if len(filter.Types) == 1 && nonHTMLPattern.MatchString(pattern) {
+			issues = append(issues, newIssue("sub-filter", first, "location %s serves non-HTML content but sub_filter_types only covers text/html", pattern))
+		}
+		if filter.Proxied && !filter.Decompressed {
+			issues = append(issues, newIssue("sub-filter", first, "compressed upstream responses are passed through unmodified, set proxy_set_header Accept-Encoding \"\""))
+		}
+		if filter.Precompressed {
+			issues = append(issues, newIssue("sub-filter", first, "gzip_static serves precompressed files, which sub_filter cannot modify"))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,63 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestSubFilters(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        sub_filter 'http://internal' "https://example.com";
+        location / {
+            proxy_pass http://backend;
+            proxy_set_header Accept-Encoding "";
+            sub_filter_once off;
+        }
+        location ~ \.js$ {
+            proxy_pass http://backend;
+        }
+        location /plain {
+            sub_filter foo bar;
+            sub_filter baz qux;
+        }
+        location /gunzip {
+            proxy_pass http://backend;
+            gunzip on;
+            sub_filter_once off;
+        }
+        location /identity {
+            proxy_pass http://backend;
+            proxy_set_header Accept-Encoding identity;
+            proxy_buffering off;
+            sub_filter_once off;
+        }
+        location /static {
+            gzip_static on;
+            sub_filter_once off;
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filters := SubFilters(directives)
+	if len(filters) != 6 {
+		t.Fatalf("expected 6 filtered locations but got %d", len(filters))
+	}
+	if filters[0].Once || !filters[0].Decompressed || len(filters[2].Rules) != 2 {
+		t.Fatalf("unexpected filters %+v %+v", filters[0], filters[2])
+	}
+	if filters[3].Decompressed || !filters[4].Decompressed || filters[4].Precompressed || !filters[5].Precompressed {
+		t.Fatalf("unexpected filters %+v %+v %+v", filters[3], filters[4], filters[5])
+	}
+
+	issues := checkSubFilters(directives)
+	if len(issues) != 6 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	if issues[4].Message != "compressed upstream responses are passed through unmodified, set proxy_set_header Accept-Encoding \"\"" || issues[5].Message != "gzip_static serves precompressed files, which sub_filter cannot modify" {
+		t.Fatalf("unexpected issues %v", issues[4:])
+	}
+}
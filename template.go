@@ -0,0 +1,97 @@
+package nginxparser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var placeholderPattern = regexp.MustCompile(`%([A-Za-z_][A-Za-z0-9_.-]*)%`)
+
+type TemplateOptions struct {
+	// FileName names a file per site, with the same placeholders as the
+	// template. When set every server goes to its own file and the result
+	// holds the include directives pulling them in, ready for WriteFiles.
+	FileName string
+}
+
+// ExpandTemplate produces a copy of a block, usually a server, for each
+// site, replacing the %name% placeholders in its arguments and comments
+// with the values of the site. Placeholders parse as part of a word, so
+// the template is itself valid configuration. A placeholder the site has
+// no value for is an error. The copies record the position of the template
+// in their Provenance.
+func ExpandTemplate(template *Directive, sites []map[string]string, options *TemplateOptions) ([]*Directive, error) {
+	if options == nil {
+		options = &TemplateOptions{}
+	}
+	result := make([]*Directive, 0, len(sites))
+	for i, site := range sites {
+		expand := func(s string, d *Directive) (string, error) {
+			var missing string
+			s = placeholderPattern.ReplaceAllStringFunc(s, func(placeholder string) string {
+				name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+				value, ok := site[name]
+				if !ok && missing == "" {
+					missing = name
+				}
+				return value
+			})
+			if missing != "" {
+				if d == nil {
+					return "", fmt.Errorf("site %d: no value for %%%s%% in file name", i, missing)
+				}
+				return "", fmt.Errorf("site %d: no value for %%%s%% at %s:%d", i, missing, d.FileName, d.Line)
+			}
+			return s, nil
+		}
+
+		d := cloneDirectives([]*Directive{template})[0]
+		d.Parent = nil
+		MarkMoved(d)
+		var err error
+		Walk([]*Directive{d}, func(d *Directive, parents []*Directive) bool {
+			if err != nil {
+				return false
+			}
+			for j, arg := range d.Args {
+				if d.Args[j], err = expand(arg, d); err != nil {
+					return false
+				}
+				if d.Args[j] != arg {
+					d.Raw = ""
+				}
+			}
+			if d.Comment, err = expand(d.Comment, d); err != nil {
+				return false
+			}
+			for j, comment := range d.LeadingComments {
+				if d.LeadingComments[j], err = expand(comment, d); err != nil {
+					return false
+				}
+			}
+			d.TrailingComment, err = expand(d.TrailingComment, d)
+			return err == nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if options.FileName == "" {
+			result = append(result, d)
+			continue
+		}
+		name, err := expand(options.FileName, nil)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("site %d: empty file name", i)
+		}
+		d.FileName = name
+		include := Synthesize("template", "include", name)
+		include.Block = []*Directive{d}
+		result = append(result, include)
+	}
+	return result, nil
+}
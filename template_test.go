@@ -0,0 +1,78 @@
+package nginxparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	directives, err := New(&ParseOptions{}).ParseString(`server {
+    # site %name%
+    listen 443 ssl;
+    server_name %host% www.%host%;
+    root /srv/%name%;
+    location / {
+        proxy_pass http://%backend%;
+    }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := directives[0]
+	sites := []map[string]string{
+		{"name": "shop", "host": "shop.example.com", "backend": "127.0.0.1:8080"},
+		{"name": "blog", "host": "blog.example.com", "backend": "127.0.0.1:8081"},
+	}
+	servers, err := ExpandTemplate(template, sites, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 2 {
+		t.Fatalf("expected 2 servers, got %d", len(servers))
+	}
+	expected := `server {
+    # site blog
+    listen 443 ssl;
+    server_name blog.example.com www.blog.example.com;
+    root /srv/blog;
+    location / {
+        proxy_pass http://127.0.0.1:8081;
+    }
+}`
+	if s := servers[1].String(); s != expected {
+		t.Fatalf("unexpected server:\n%s", s)
+	}
+	if template.FirstChild("root").ArgAt(0) != "/srv/%name%" {
+		t.Fatal("expected the template to be left unchanged")
+	}
+	if location := servers[0].FirstChild("location"); location.Parent != servers[0] {
+		t.Fatal("expected the copies to have their own parents")
+	}
+
+	_, err = ExpandTemplate(template, []map[string]string{{"name": "shop"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "%host%") {
+		t.Fatalf("expected a missing value error, got %v", err)
+	}
+
+	includes, err := ExpandTemplate(template, sites, &TemplateOptions{FileName: "sites/%name%.conf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	write := func(name string, data []byte) error {
+		files[name] = string(data)
+		return nil
+	}
+	http := NewBlock("http", nil, includes...)
+	http.FileName = "nginx.conf"
+	if err := WriteFiles([]*Directive{http}, &WriteOptions{Write: write}); err != nil {
+		t.Fatal(err)
+	}
+	if files["nginx.conf"] != "http {\n    include sites/shop.conf;\n    include sites/blog.conf;\n}\n" {
+		t.Fatalf("unexpected main file %q", files["nginx.conf"])
+	}
+	if !strings.Contains(files["sites/shop.conf"], "server_name shop.example.com www.shop.example.com;") {
+		t.Fatalf("unexpected site file %q", files["sites/shop.conf"])
+	}
+}
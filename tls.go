@@ -0,0 +1,188 @@
+package nginxparser
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrTLSPreflight = errors.New("tls preflight failed")
+
+type Certificate struct {
+	Server      *Directive `json:"-"`
+	Certificate *Directive `json:"-"`
+	// Key is nil when no ssl_certificate_key pairs with the certificate.
+	Key             *Directive `json:"-"`
+	CertificateFile string     `json:"certificate"`
+	KeyFile         string     `json:"key,omitempty"`
+}
+
+// Certificates lists the certificate and key pairs in effect for every
+// http server. ssl_certificate may be given more than once, for RSA and
+// ECDSA certificates, each pairs with the ssl_certificate_key in the same
+// position.
+func Certificates(directives []*Directive) []*Certificate {
+	result := make([]*Certificate, 0)
+	for _, server := range servers(directives) {
+		parents := parentsOf(directives, server)
+		keys := lookupInheritedAll("ssl_certificate_key", server.Block, parents)
+		for i, d := range lookupInheritedAll("ssl_certificate", server.Block, parents) {
+			if len(d.Args) == 0 {
+				continue
+			}
+			certificate := &Certificate{Server: server, Certificate: d, CertificateFile: d.Args[0]}
+			if i < len(keys) && len(keys[i].Args) > 0 {
+				certificate.Key, certificate.KeyFile = keys[i], keys[i].Args[0]
+			}
+			result = append(result, certificate)
+		}
+	}
+	return result
+}
+
+type TLSPreflightOptions struct {
+	// Prefix is the directory relative paths are resolved against, the
+	// directory of the main configuration file for nginx.
+	Prefix string
+	// Roots verifies the certificate chains, nil uses the system roots.
+	Roots    *x509.CertPool
+	Now      func() time.Time
+	ReadFile func(name string) ([]byte, error)
+}
+
+// CheckTLS reads the certificates and keys of every server and reports
+// missing files, certificates outside their validity period, keys that do
+// not match and chains that do not verify, such as when intermediates
+// are missing from the certificate file. Paths containing variables and
+// data: values are skipped.
+func CheckTLS(directives []*Directive, options *TLSPreflightOptions) []*Issue {
+	if options == nil {
+		options = &TLSPreflightOptions{}
+	}
+	now := options.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	issues := make([]*Issue, 0)
+	checked := make(map[string]bool)
+	for _, certificate := range Certificates(directives) {
+		if skipTLSFile(certificate.CertificateFile) || (certificate.Key != nil && skipTLSFile(certificate.KeyFile)) {
+			continue
+		}
+		if checked[certificate.CertificateFile+"\x00"+certificate.KeyFile] {
+			continue
+		}
+		checked[certificate.CertificateFile+"\x00"+certificate.KeyFile] = true
+
+		d := certificate.Certificate
+		certPEM, err := options.read(certificate.CertificateFile)
+		if err != nil {
+			issues = append(issues, newIssue("tls-material", d, "cannot read certificate %s: %s", certificate.CertificateFile, err))
+			continue
+		}
+		chain, err := parseCertificates(certPEM)
+		if err != nil {
+			issues = append(issues, newIssue("tls-material", d, "invalid certificate in %s: %s", certificate.CertificateFile, err))
+		}
+		if len(chain) == 0 {
+			issues = append(issues, newIssue("tls-material", d, "no certificate found in %s", certificate.CertificateFile))
+			continue
+		}
+
+		leaf, at := chain[0], now()
+		switch {
+		case at.After(leaf.NotAfter):
+			issues = append(issues, newIssue("tls-material", d, "certificate %s expired on %s", certificate.CertificateFile, leaf.NotAfter.Format(time.RFC3339)))
+		case at.Before(leaf.NotBefore):
+			issues = append(issues, newIssue("tls-material", d, "certificate %s is not valid before %s", certificate.CertificateFile, leaf.NotBefore.Format(time.RFC3339)))
+		default:
+			intermediates := x509.NewCertPool()
+			for _, c := range chain[1:] {
+				intermediates.AddCert(c)
+			}
+			_, err := leaf.Verify(x509.VerifyOptions{
+				Roots:         options.Roots,
+				Intermediates: intermediates,
+				CurrentTime:   at,
+				KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+			})
+			var unknown x509.UnknownAuthorityError
+			switch {
+			case errors.As(err, &unknown):
+				issues = append(issues, newIssue("tls-material", d, "certificate %s does not chain to a trusted root, intermediates may be missing", certificate.CertificateFile))
+			case err != nil:
+				issues = append(issues, newIssue("tls-material", d, "certificate %s does not verify: %s", certificate.CertificateFile, err))
+			}
+		}
+
+		if certificate.Key == nil {
+			issues = append(issues, newIssue("tls-material", d, "certificate %s has no ssl_certificate_key", certificate.CertificateFile))
+			continue
+		}
+		keyPEM, err := options.read(certificate.KeyFile)
+		if err != nil {
+			issues = append(issues, newIssue("tls-material", certificate.Key, "cannot read key %s: %s", certificate.KeyFile, err))
+			continue
+		}
+		if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+			issues = append(issues, newIssue("tls-material", certificate.Key, "key %s does not match certificate %s: %s", certificate.KeyFile, certificate.CertificateFile, err))
+		}
+	}
+	return issues
+}
+
+// read reads name, resolving relative paths against the prefix.
+func (o *TLSPreflightOptions) read(name string) ([]byte, error) {
+	if !filepath.IsAbs(name) && o.Prefix != "" {
+		name = filepath.Join(o.Prefix, name)
+	}
+	if o.ReadFile != nil {
+		return o.ReadFile(name)
+	}
+	return os.ReadFile(name)
+}
+
+// parseCertificates decodes the certificates of a PEM file in order. On
+// error the certificates before the invalid one are returned.
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	chain := make([]*x509.Certificate, 0)
+	for rest := data; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			return chain, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		parsed, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return chain, err
+		}
+		chain = append(chain, parsed)
+	}
+}
+
+func skipTLSFile(name string) bool {
+	return strings.Contains(name, "$") || strings.HasPrefix(name, "data:") || strings.HasPrefix(name, "engine:")
+}
+
+// PreflightTLS fails with ErrTLSPreflight when CheckTLS finds any issue,
+// meant to run before reloading nginx.
+func PreflightTLS(directives []*Directive, options *TLSPreflightOptions) error {
+	issues := CheckTLS(directives, options)
+	if len(issues) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		messages = append(messages, issue.String())
+	}
+	return fmt.Errorf("%w: %s", ErrTLSPreflight, strings.Join(messages, "; "))
+}
@@ -0,0 +1,147 @@
+package nginxparser
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCertificate(t *testing.T, name string, parent *testCertificate, ca bool) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:              time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  ca,
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCertificate{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (c *testCertificate) keyPEM(t *testing.T) []byte {
+	der, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func TestCheckTLS(t *testing.T) {
+	root := newTestCertificate(t, "root", nil, true)
+	intermediate := newTestCertificate(t, "intermediate", root, true)
+	leaf := newTestCertificate(t, "example.com", intermediate, false)
+	other := newTestCertificate(t, "other.com", intermediate, false)
+
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"full.pem":  append(append([]byte{}, leaf.pem...), intermediate.pem...),
+		"leaf.pem":  leaf.pem,
+		"leaf.key":  leaf.keyPEM(t),
+		"other.key": other.keyPEM(t),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    ssl_certificate full.pem;
+    ssl_certificate_key leaf.key;
+    server {
+        listen 443 ssl;
+    }
+    server {
+        listen 443 ssl;
+        ssl_certificate leaf.pem;
+        ssl_certificate_key leaf.key;
+    }
+    server {
+        listen 443 ssl;
+        ssl_certificate full.pem;
+        ssl_certificate_key other.key;
+    }
+    server {
+        listen 443 ssl;
+        ssl_certificate missing.pem;
+        ssl_certificate_key leaf.key;
+        ssl_certificate $ssl_server_name.pem;
+        ssl_certificate_key $ssl_server_name.key;
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if certificates := Certificates(directives); len(certificates) != 5 || certificates[0].CertificateFile != "full.pem" || certificates[4].KeyFile != "$ssl_server_name.key" {
+		t.Fatalf("unexpected certificates %+v", certificates)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+	options := &TLSPreflightOptions{
+		Prefix: dir,
+		Roots:  roots,
+		Now: func() time.Time {
+			return time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+		},
+	}
+	issues := CheckTLS(directives, options)
+	expected := []string{"intermediates may be missing", "key other.key does not match", "cannot read certificate missing.pem"}
+	if len(issues) != len(expected) {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	for i, issue := range issues {
+		if !strings.Contains(issue.Message, expected[i]) {
+			t.Fatalf("expected %q in %s", expected[i], issue)
+		}
+	}
+
+	options.Now = func() time.Time {
+		return time.Date(2027, 6, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if issues := CheckTLS(directives[:0:0], options); len(issues) != 0 {
+		t.Fatalf("expected no issues without servers, got %v", issues)
+	}
+	issues = CheckTLS(directives, options)
+	if len(issues) == 0 || !strings.Contains(issues[0].Message, "expired on 2027-01-01") {
+		t.Fatalf("expected an expired certificate, got %v", issues)
+	}
+	if err := PreflightTLS(directives, options); !errors.Is(err, ErrTLSPreflight) {
+		t.Fatalf("expected ErrTLSPreflight, got %v", err)
+	}
+}
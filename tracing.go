@@ -0,0 +1,121 @@
+package nginxparser
+
+import (
+	"strings"
+)
+
+// headerDirectives maps each *_pass directive to the directive that sets
+// request headers for it and the form the header name takes there.
+var headerDirectives = map[string]struct {
+	directive string
+	cgi       bool
+}{
+	"proxy_pass":   {"proxy_set_header", false},
+	"grpc_pass":    {"grpc_set_header", false},
+	"fastcgi_pass": {"fastcgi_param", true},
+	"uwsgi_pass":   {"uwsgi_param", true},
+	"scgi_pass":    {"scgi_param", true},
+}
+
+func setsHeader(pass *Directive, parents []*Directive, header, variable string) bool {
+	spec, ok := headerDirectives[pass.Directive]
+	if !ok {
+		return false
+	}
+	name := header
+	if spec.cgi {
+		name = "HTTP_" + strings.ToUpper(strings.Replace(header, "-", "_", -1))
+	}
+	block := parents[len(parents)-1].Block
+	for _, d := range lookupInheritedAll(spec.directive, block, parents[:len(parents)-1]) {
+		if len(d.Args) >= 2 && strings.EqualFold(d.Args[0], name) && strings.Contains(d.Args[1], variable) {
+			return true
+		}
+	}
+	return false
+}
+
+func propagatesTraceContext(pass *Directive, parents []*Directive) bool {
+	block := parents[len(parents)-1].Block
+	outer := parents[:len(parents)-1]
+	trace := lookupInherited("otel_trace", block, outer)
+	context := lookupInherited("otel_trace_context", block, outer)
+	if trace != nil && len(trace.Args) > 0 && trace.Args[0] != "off" && context != nil && len(context.Args) > 0 && (context.Args[0] == "inject" || context.Args[0] == "propagate") {
+		return true
+	}
+	return setsHeader(pass, parents, "traceparent", "$")
+}
+
+type TracingGap struct {
+	Upstream    string       `json:"upstream"`
+	Passes      []*Directive `json:"-"`
+	NoRequestID []*Directive `json:"-"`
+	NoContext   []*Directive `json:"-"`
+}
+
+// TracingGaps groups the proxying directives by upstream and lists those
+// that do not forward X-Request-ID: $request_id or the W3C trace context.
+func TracingGaps(directives []*Directive) []*TracingGap {
+	result := make([]*TracingGap, 0)
+	index := make(map[string]*TracingGap)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if _, ok := headerDirectives[d.Directive]; !ok || len(d.Args) == 0 || len(parents) == 0 {
+			return true
+		}
+		_, host, port := splitPassTarget(d.Args[0])
+		upstream := host
+		if port != "" {
+			upstream += ":" + port
+		}
+		gap := index[upstream]
+		if gap == nil {
+			gap = &TracingGap{
+				Upstream:    upstream,
+				Passes:      make([]*Directive, 0),
+				NoRequestID: make([]*Directive, 0),
+				NoContext:   make([]*Directive, 0),
+			}
+			index[upstream] = gap
+			result = append(result, gap)
+		}
+		gap.Passes = append(gap.Passes, d)
+		if !setsHeader(d, parents, "X-Request-ID", "$request_id") {
+			gap.NoRequestID = append(gap.NoRequestID, d)
+		}
+		if !propagatesTraceContext(d, parents) {
+			gap.NoContext = append(gap.NoContext, d)
+		}
+		return true
+	})
+	return result
+}
+
+func checkRequestID(directives []*Directive) []*Issue {
+	issues := make([]*Issue, 0)
+	for _, gap := range TracingGaps(directives) {
+		for _, d := range gap.NoRequestID {
+			issues = append(issues, newIssue("tracing-request-id", d, "requests to %s do not carry X-Request-ID $request_id", gap.Upstream))
+		}
+	}
+	return issues
+}
+
+// checkTraceContext only applies once some location propagates the trace
+// context, the gaps are what breaks traces in that case.
+func checkTraceContext(directives []*Directive) []*Issue {
+	gaps := TracingGaps(directives)
+	traced := false
+	for _, gap := range gaps {
+		traced = traced || len(gap.NoContext) < len(gap.Passes)
+	}
+	issues := make([]*Issue, 0)
+	if !traced {
+		return issues
+	}
+	for _, gap := range gaps {
+		for _, d := range gap.NoContext {
+			issues = append(issues, newIssue("tracing-context", d, "requests to %s do not propagate the trace context", gap.Upstream))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,49 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestTracingGaps(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    proxy_set_header X-Request-ID $request_id;
+    server {
+        location / {
+            proxy_pass http://backend;
+        }
+        location /traced/ {
+            otel_trace on;
+            otel_trace_context propagate;
+            proxy_pass http://backend;
+        }
+        location /headers/ {
+            proxy_set_header Host $host;
+            proxy_pass http://backend;
+        }
+        location ~ \.php$ {
+            fastcgi_param HTTP_X_REQUEST_ID $request_id;
+            fastcgi_pass 127.0.0.1:9000;
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gaps := TracingGaps(directives)
+	if len(gaps) != 2 || gaps[0].Upstream != "backend" || gaps[1].Upstream != "127.0.0.1:9000" {
+		t.Fatalf("unexpected gaps %+v", gaps)
+	}
+	if len(gaps[0].Passes) != 3 || len(gaps[0].NoRequestID) != 1 || len(gaps[0].NoContext) != 2 || len(gaps[1].NoRequestID) != 0 {
+		t.Fatalf("unexpected gap %+v", gaps[0])
+	}
+
+	issues, err := Lint(directives, "tracing-request-id", "tracing-context")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 4 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+}
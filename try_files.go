@@ -0,0 +1,121 @@
+package nginxparser
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	FallbackURI   = "uri"
+	FallbackCode  = "code"
+	FallbackNamed = "named"
+)
+
+type TryFiles struct {
+	Directive    *Directive `json:"-"`
+	Server       *Directive `json:"-"`
+	Location     *Directive `json:"-"`
+	Files        []string   `json:"files"`
+	Fallback     string     `json:"fallback"`
+	FallbackKind string     `json:"fallback_kind"`
+	Target       *Directive `json:"-"`
+}
+
+// TryFilesRoutes models every try_files directive: the files checked in
+// order and the internal redirect or status code used when none exists.
+func TryFilesRoutes(directives []*Directive) []*TryFiles {
+	result := make([]*TryFiles, 0)
+	for _, server := range servers(directives) {
+		Walk(server.Block, func(d *Directive, parents []*Directive) bool {
+			if d.Directive != "try_files" || len(d.Args) < 2 {
+				return true
+			}
+			try := &TryFiles{
+				Directive: d,
+				Server:    server,
+				Location:  enclosing("location", parents),
+				Files:     d.Args[:len(d.Args)-1],
+				Fallback:  d.Args[len(d.Args)-1],
+			}
+			switch {
+			case strings.HasPrefix(try.Fallback, "="):
+				try.FallbackKind = FallbackCode
+			case strings.HasPrefix(try.Fallback, "@"):
+				try.FallbackKind = FallbackNamed
+				try.Target = NamedLocation(server, try.Fallback)
+			default:
+				try.FallbackKind = FallbackURI
+				uri := try.Fallback
+				if i := strings.IndexByte(uri, '?'); i >= 0 {
+					uri = uri[:i]
+				}
+				if len(Variables(uri)) == 0 {
+					try.Target = FindLocation(server, uri)
+				}
+			}
+			result = append(result, try)
+			return true
+		})
+	}
+	return result
+}
+
+func hasAccessControl(location *Directive, parents []*Directive) bool {
+	for _, name := range []string{"auth_basic", "auth_request", "auth_jwt"} {
+		if d := lookupInherited(name, location.Block, parents); d != nil && len(d.Args) > 0 && d.Args[0] != "off" {
+			return true
+		}
+	}
+	return len(lookupInheritedAll("deny", location.Block, parents)) > 0
+}
+
+func checkTryFiles(directives []*Directive) []*Issue {
+	parents := make(map[*Directive][]*Directive)
+	Walk(directives, func(d *Directive, p []*Directive) bool {
+		if d.Directive == "location" {
+			parents[d] = p
+		}
+		return true
+	})
+
+	issues := make([]*Issue, 0)
+	for _, try := range TryFilesRoutes(directives) {
+		switch try.FallbackKind {
+		case FallbackCode:
+			if code, err := strconv.Atoi(try.Fallback[1:]); err != nil || code < 100 || code > 599 {
+				issues = append(issues, newIssue("try-files", try.Directive, "invalid try_files status code %s", try.Fallback))
+			}
+			continue
+		case FallbackNamed:
+			if try.Target == nil {
+				issues = append(issues, newIssue("try-files", try.Directive, "try_files fallback %s is not defined", try.Fallback))
+				continue
+			}
+		case FallbackURI:
+			if try.Target == nil {
+				if len(Variables(try.Fallback)) == 0 {
+					issues = append(issues, newIssue("try-files", try.Directive, "try_files fallback %s does not match any location", try.Fallback))
+				}
+				continue
+			}
+			if try.Target == try.Location && !checksURI(try) {
+				issues = append(issues, newIssue("try-files", try.Directive, "try_files fallback %s is routed back to this location, requests loop until nginx aborts with 500", try.Fallback))
+			}
+		}
+		if try.Location != nil && try.Target != try.Location && hasAccessControl(try.Location, parents[try.Location]) && !hasAccessControl(try.Target, parents[try.Target]) {
+			issues = append(issues, newIssue("try-files", try.Directive, "try_files fallback %s is served by location on line %d without the access control of this location", try.Fallback, try.Target.Line))
+		}
+	}
+	return issues
+}
+
+// checksURI reports whether one of the files tried is the request URI
+// itself, so a fallback routed back to the location ends there.
+func checksURI(try *TryFiles) bool {
+	for _, file := range try.Files {
+		if strings.HasPrefix(file, "$uri") || file == try.Fallback {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestTryFiles(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        location / {
+            try_files $uri $uri/ /index.html;
+        }
+        location /app/ {
+            try_files /maintenance.html /app/;
+        }
+        location /admin/ {
+            auth_basic "admin";
+            try_files $uri /public/index.html;
+        }
+        location /public/ {
+        }
+        location /api/ {
+            try_files $uri @backend;
+        }
+        location /errors/ {
+            try_files $uri =4044;
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := TryFilesRoutes(directives)
+	if len(routes) != 5 {
+		t.Fatalf("expected 5 try_files but got %d", len(routes))
+	}
+	if routes[0].Target != routes[0].Location || routes[3].FallbackKind != FallbackNamed {
+		t.Fatalf("unexpected routes %+v %+v", routes[0], routes[3])
+	}
+
+	issues := checkTryFiles(directives)
+	lines := []int{8, 12, 17, 20}
+	if len(issues) != len(lines) {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	for i, issue := range issues {
+		if issue.Line != lines[i] {
+			t.Fatalf("unexpected issues %v", issues)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package nginxparser
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var durationUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+	"M":  30 * 24 * time.Hour,
+	"y":  365 * 24 * time.Hour,
+}
+
+// ParseDuration parses an nginx time value such as 30s, 1h30m or 500ms.
+// Values without a unit are seconds.
+func ParseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+	var total time.Duration
+	for i := 0; i < len(s); {
+		j := i
+		for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			j++
+		}
+		k := j
+		for k < len(s) && (s[k] < '0' || s[k] > '9') && s[k] != ' ' {
+			k++
+		}
+		unit, ok := durationUnits[s[j:k]]
+		n, err := strconv.Atoi(s[i:j])
+		if !ok || err != nil {
+			return 0, fmt.Errorf("invalid time %q", s)
+		}
+		total += time.Duration(n) * unit
+		for k < len(s) && s[k] == ' ' {
+			k++
+		}
+		i = k
+	}
+	return total, nil
+}
+
+var sizeUnits = map[byte]int64{
+	'k': 1 << 10,
+	'K': 1 << 10,
+	'm': 1 << 20,
+	'M': 1 << 20,
+	'g': 1 << 30,
+	'G': 1 << 30,
+}
+
+// ParseSize parses an nginx size value such as 512, 16k or 1m into bytes.
+func ParseSize(s string) (int64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	unit := int64(1)
+	if u, ok := sizeUnits[s[len(s)-1]]; ok {
+		unit, s = u, s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * unit, nil
+}
@@ -0,0 +1,44 @@
+package nginxparser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	fixtures := map[string]time.Duration{
+		"60":     time.Minute,
+		"30s":    30 * time.Second,
+		"1h30m":  90 * time.Minute,
+		"1h 30m": 90 * time.Minute,
+		"500ms":  500 * time.Millisecond,
+		"1d":     24 * time.Hour,
+	}
+	for s, expected := range fixtures {
+		actual, err := ParseDuration(s)
+		if err != nil || actual != expected {
+			t.Errorf("%s: expected %s but got %s (%v)", s, expected, actual, err)
+		}
+	}
+	if _, err := ParseDuration("1x"); err == nil {
+		t.Fatal("expected error but got nil")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	fixtures := map[string]int64{
+		"512": 512,
+		"16k": 16 << 10,
+		"1M":  1 << 20,
+		"2g":  2 << 30,
+	}
+	for s, expected := range fixtures {
+		actual, err := ParseSize(s)
+		if err != nil || actual != expected {
+			t.Errorf("%s: expected %d but got %d (%v)", s, expected, actual, err)
+		}
+	}
+	if _, err := ParseSize("1t"); err == nil {
+		t.Fatal("expected error but got nil")
+	}
+}
@@ -0,0 +1,104 @@
+package nginxparser
+
+import (
+	"strings"
+)
+
+func upstreams(directives []*Directive) []*Directive {
+	result := make([]*Directive, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive == "upstream" {
+			result = append(result, d)
+			return false
+		}
+		return d.Directive != "server"
+	})
+	return result
+}
+
+func upstreamName(d *Directive) string {
+	if len(d.Args) == 0 {
+		return ""
+	}
+	return d.Args[0]
+}
+
+var balancingMethods = map[string]bool{
+	"hash":       true,
+	"ip_hash":    true,
+	"sticky":     true,
+	"least_conn": true,
+	"least_time": true,
+	"random":     true,
+}
+
+type Affinity struct {
+	Upstream   *Directive   `json:"-"`
+	Name       string       `json:"name"`
+	Method     string       `json:"method"`
+	Key        string       `json:"key,omitempty"`
+	Consistent bool         `json:"consistent,omitempty"`
+	Sticky     bool         `json:"sticky"`
+	Methods    []*Directive `json:"-"`
+}
+
+// UpstreamAffinity reports the load balancing method of every upstream and
+// whether requests from one client keep reaching the same server.
+func UpstreamAffinity(directives []*Directive) []*Affinity {
+	result := make([]*Affinity, 0)
+	for _, upstream := range upstreams(directives) {
+		affinity := &Affinity{
+			Upstream: upstream,
+			Name:     upstreamName(upstream),
+			Method:   "round_robin",
+			Methods:  make([]*Directive, 0),
+		}
+		for _, d := range children(upstream.Block) {
+			if !balancingMethods[d.Directive] {
+				continue
+			}
+			affinity.Methods = append(affinity.Methods, d)
+			affinity.Method = d.Directive
+			switch d.Directive {
+			case "hash":
+				if len(d.Args) > 0 {
+					affinity.Key = d.Args[0]
+				}
+				affinity.Consistent = len(d.Args) > 1 && d.Args[1] == "consistent"
+				affinity.Sticky = true
+			case "ip_hash", "sticky":
+				affinity.Sticky = true
+			}
+		}
+		result = append(result, affinity)
+	}
+	return result
+}
+
+func checkUpstreamAffinity(directives []*Directive) []*Issue {
+	issues := make([]*Issue, 0)
+	for _, affinity := range UpstreamAffinity(directives) {
+		if len(affinity.Methods) > 1 {
+			names := make([]string, 0, len(affinity.Methods))
+			for _, d := range affinity.Methods {
+				names = append(names, d.Directive)
+			}
+			issues = append(issues, newIssue("upstream-affinity", affinity.Upstream, "upstream %s mixes balancing methods %s", affinity.Name, strings.Join(names, ", ")))
+		}
+		if affinity.Method != "hash" {
+			continue
+		}
+		hash := affinity.Methods[len(affinity.Methods)-1]
+		variables := Variables(affinity.Key)
+		optional := 0
+		for _, name := range variables {
+			if isOptionalVariable(name) {
+				optional++
+			}
+		}
+		if len(variables) > 0 && optional == len(variables) {
+			issues = append(issues, newIssue("upstream-affinity", hash, "hash key %s can be empty for some requests, those requests all land on one server", affinity.Key))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,45 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestUpstreamAffinity(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    upstream plain {
+        server 10.0.0.1;
+    }
+    upstream by_cookie {
+        hash $cookie_session consistent;
+        server 10.0.0.1;
+    }
+    upstream mixed {
+        ip_hash;
+        least_conn;
+        server 10.0.0.1;
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	affinities := UpstreamAffinity(directives)
+	if len(affinities) != 3 {
+		t.Fatalf("expected 3 upstreams but got %d", len(affinities))
+	}
+	if affinities[0].Method != "round_robin" || affinities[0].Sticky {
+		t.Fatalf("unexpected affinity %+v", affinities[0])
+	}
+	if affinities[1].Key != "$cookie_session" || !affinities[1].Consistent || !affinities[1].Sticky {
+		t.Fatalf("unexpected affinity %+v", affinities[1])
+	}
+
+	issues, err := Lint(directives, "upstream-affinity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 || issues[0].Line != 7 || issues[1].Line != 10 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+}
@@ -0,0 +1,74 @@
+package nginxparser
+
+import (
+	"strings"
+)
+
+// Variables returns the names of the variables referenced in s, without the
+// leading '$' and in order of appearance. Both $name and ${name} forms are
+// recognised; capture references such as $1 are returned as "1".
+func Variables(s string) []string {
+	result := make([]string, 0)
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || (i > 0 && s[i-1] == '\\') {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '{' {
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				break
+			}
+			result = append(result, s[i+2:i+2+end])
+			i += end + 2
+			continue
+		}
+		j := i + 1
+		if j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			result = append(result, s[j:j+1])
+			i = j
+			continue
+		}
+		for j < len(s) && isVariableByte(s[j]) {
+			j++
+		}
+		if j > i+1 {
+			result = append(result, s[i+1:j])
+		}
+		i = j - 1
+	}
+	return result
+}
+
+func isVariableByte(b byte) bool {
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+var optionalVariablePrefixes = []string{
+	"http_", "cookie_", "arg_", "sent_http_", "upstream_", "jwt_",
+}
+
+var optionalVariables = map[string]bool{
+	"args":            true,
+	"query_string":    true,
+	"is_args":         true,
+	"request_body":    true,
+	"remote_user":     true,
+	"content_type":    true,
+	"content_length":  true,
+	"ssl_server_name": true,
+	"ssl_client_s_dn": true,
+}
+
+// isOptionalVariable reports whether a built-in variable can be empty for
+// some requests, e.g. request headers, cookies and query arguments.
+func isOptionalVariable(name string) bool {
+	if optionalVariables[name] {
+		return true
+	}
+	for _, prefix := range optionalVariablePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,20 @@
+package nginxparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVariables(t *testing.T) {
+	fixtures := map[string][]string{
+		"$scheme://$host$request_uri": {"scheme", "host", "request_uri"},
+		"/abc/${uri}.html":            {"uri"},
+		"/img/$1-$2x":                 {"1", "2"},
+		`\$literal $`:                 {},
+	}
+	for s, expected := range fixtures {
+		if actual := Variables(s); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s: expected %v but got %v", s, expected, actual)
+		}
+	}
+}
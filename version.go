@@ -0,0 +1,117 @@
+package nginxparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is an nginx release such as 1.25.1. The zero Version stands for
+// the latest release.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+func ParseVersion(s string) (Version, error) {
+	var v Version
+	parts := strings.Split(strings.TrimPrefix(s, "nginx/"), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return v, fmt.Errorf("invalid nginx version %q", s)
+	}
+	fields := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid nginx version %q", s)
+		}
+		*fields[i] = n
+	}
+	return v, nil
+}
+
+func MustParseVersion(s string) Version {
+	v, err := ParseVersion(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (v Version) IsLatest() bool {
+	return v == Version{}
+}
+
+func (v Version) Compare(o Version) int {
+	switch {
+	case v.IsLatest() && o.IsLatest():
+		return 0
+	case v.IsLatest():
+		return 1
+	case o.IsLatest():
+		return -1
+	}
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			if d < 0 {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func (v Version) Before(o Version) bool {
+	return v.Compare(o) < 0
+}
+
+func (v Version) String() string {
+	if v.IsLatest() {
+		return "latest"
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// CheckVersion reports the documented directives that the given nginx
+// release does not have, added after it or removed before it, so a
+// configuration can be validated for the release it is deployed to.
+func CheckVersion(directives []*Directive, version Version) []*Issue {
+	issues := make([]*Issue, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive == "#" {
+			return false
+		}
+		if message := unavailable(directiveDocs, d, parents, version); message != "" {
+			issues = append(issues, newIssue("nginx-version", d, "%s", message))
+		}
+		return !opaqueBlocks[d.Directive]
+	})
+	return issues
+}
+
+// unavailable explains why the directive does not exist in the release,
+// "" when it does or is not documented.
+func unavailable(catalog []DirectiveDoc, d *Directive, parents []*Directive, version Version) string {
+	docs, _ := contextDocs(catalog, d.Directive, parents)
+	if len(docs) == 0 {
+		for _, doc := range catalog {
+			if doc.Name == d.Directive {
+				docs = append(docs, doc)
+			}
+		}
+	}
+	if len(docs) == 0 {
+		return ""
+	}
+	for _, doc := range docs {
+		if doc.Available(version) {
+			return ""
+		}
+	}
+	if docs[0].Since != (Version{}) && version.Before(docs[0].Since) {
+		return fmt.Sprintf("%s is not available before nginx %s", d.Directive, docs[0].Since)
+	}
+	return fmt.Sprintf("%s was removed in nginx %s", d.Directive, docs[0].Removed)
+}
@@ -0,0 +1,84 @@
+package nginxparser
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	v, err := ParseVersion("nginx/1.25.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != (Version{1, 25, 1}) || v.String() != "1.25.1" {
+		t.Fatalf("unexpected version %v", v)
+	}
+	if _, err := ParseVersion("1.x"); err == nil {
+		t.Fatal("expected error but got nil")
+	}
+	if !MustParseVersion("1.18").Before(v) || !v.Before(Version{}) || (Version{}).Before(v) {
+		t.Fatal("unexpected version ordering")
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        listen 443 ssl;
+        ssl on;
+        http3 on;
+        http2_push /style.css;
+        location /grpc {
+            grpc_pass grpc://127.0.0.1:50051;
+        }
+        location / {
+            proxy_pass http://app;
+            unknown_module_directive x;
+        }
+    }
+}
+stream {
+    server {
+        listen 53 udp;
+        proxy_pass dns;
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := func(version string) []string {
+		result := make([]string, 0)
+		for _, issue := range CheckVersion(directives, MustParseVersion(version)) {
+			result = append(result, fmt.Sprintf("%d: %s", issue.Line, issue.Message))
+		}
+		return result
+	}
+	for version, expected := range map[string][]string{
+		"1.8.0": {
+			"6: http3 is not available before nginx 1.25.0",
+			"7: http2_push is not available before nginx 1.13.9",
+			"9: grpc_pass is not available before nginx 1.13.10",
+			"17: stream is not available before nginx 1.9.0",
+			"18: server is not available before nginx 1.9.0",
+			"19: listen is not available before nginx 1.9.0",
+			"20: proxy_pass is not available before nginx 1.9.0",
+		},
+		"1.18.0": {"6: http3 is not available before nginx 1.25.0"},
+		"1.25.1": {"5: ssl was removed in nginx 1.25.1", "7: http2_push was removed in nginx 1.25.1"},
+	} {
+		if got := messages(version); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %q but got %q", version, expected, got)
+		}
+	}
+
+	_, err = New(&ParseOptions{SingleFile: true, Strict: true, Version: MustParseVersion("1.18")}).ParseString("http { server { http3 on; } }")
+	if !errors.Is(err, ErrInvalidDirective) || err.Error() != "http3 is not available before nginx 1.25.0 in file  line 1" {
+		t.Fatalf("expected http3 to fail on 1.18, got %v", err)
+	}
+	if _, err := New(&ParseOptions{SingleFile: true, Strict: true, Version: MustParseVersion("1.25.0")}).ParseString("http { server { http3 on; } }"); err != nil {
+		t.Fatal(err)
+	}
+}
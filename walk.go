@@ -0,0 +1,161 @@
+package nginxparser
+
+// Walk visits every directive in depth-first order. Returning false from fn
+// skips the directive's block. The contents of include directives are
+// visited with the include's parents, so callers see the nginx context a
+// directive is effective in rather than the file layout.
+func Walk(directives []*Directive, fn func(d *Directive, parents []*Directive) bool) {
+	walk(directives, nil, fn)
+}
+
+func walk(directives []*Directive, parents []*Directive, fn func(d *Directive, parents []*Directive) bool) {
+	for _, d := range directives {
+		if !fn(d, parents) {
+			continue
+		}
+		if d.Directive == "include" {
+			walk(d.Block, parents, fn)
+			continue
+		}
+		if len(d.Block) > 0 {
+			walk(d.Block, append(parents[:len(parents):len(parents)], d), fn)
+		}
+	}
+}
+
+func children(block []*Directive) []*Directive {
+	result := make([]*Directive, 0, len(block))
+	for _, d := range block {
+		switch d.Directive {
+		case "#":
+		case "include":
+			result = append(result, children(d.Block)...)
+		default:
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+func findAll(block []*Directive, name string) []*Directive {
+	result := make([]*Directive, 0)
+	for _, d := range children(block) {
+		if d.Directive == name {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+func findFirst(block []*Directive, name string) *Directive {
+	for _, d := range children(block) {
+		if d.Directive == name {
+			return d
+		}
+	}
+	return nil
+}
+
+func lookupInherited(name string, block []*Directive, parents []*Directive) *Directive {
+	if d := findFirst(block, name); d != nil {
+		return d
+	}
+	for i := len(parents) - 1; i >= 0; i-- {
+		if d := findFirst(parents[i].Block, name); d != nil {
+			return d
+		}
+	}
+	return nil
+}
+
+func enclosing(name string, parents []*Directive) *Directive {
+	for i := len(parents) - 1; i >= 0; i-- {
+		if parents[i].Directive == name {
+			return parents[i]
+		}
+	}
+	return nil
+}
+
+func servers(directives []*Directive) []*Directive {
+	result := make([]*Directive, 0)
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive == "server" && len(parents) > 0 && parents[len(parents)-1].Directive == "http" {
+			result = append(result, d)
+			return false
+		}
+		return true
+	})
+	return result
+}
+
+// lookupInheritedAll resolves array-like directives such as proxy_set_header
+// and add_header, which are inherited from an outer level only when the
+// current level defines none of them.
+func lookupInheritedAll(name string, block []*Directive, parents []*Directive) []*Directive {
+	if found := findAll(block, name); len(found) > 0 {
+		return found
+	}
+	for i := len(parents) - 1; i >= 0; i-- {
+		if found := findAll(parents[i].Block, name); len(found) > 0 {
+			return found
+		}
+	}
+	return make([]*Directive, 0)
+}
+
+// SetParents points the Parent of every directive at the block directive
+// around it. The parser does this, call it again after moving directives
+// around. When ParseOptions.ShareIncludes is set, a shared file points to
+// the block around the last include directive naming it.
+func SetParents(directives []*Directive) {
+	setParents(directives, nil)
+}
+
+func setParents(directives []*Directive, parent *Directive) {
+	for _, d := range directives {
+		d.Parent = parent
+		if d.Directive == "include" {
+			setParents(d.Block, parent)
+		} else {
+			setParents(d.Block, d)
+		}
+	}
+}
+
+// Ancestors returns the block directives around d, outermost first, as
+// Walk passes them.
+func (d *Directive) Ancestors() []*Directive {
+	result := make([]*Directive, 0)
+	for parent := d.Parent; parent != nil; parent = parent.Parent {
+		result = append(result, parent)
+	}
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
+
+// FirstChild returns the first directive named name in the block of d,
+// looking into included files, or nil.
+func (d *Directive) FirstChild(name string) *Directive {
+	return findFirst(d.Block, name)
+}
+
+// Children returns the directives named name in the block of d, looking
+// into included files. An empty name returns every directive except
+// comments.
+func (d *Directive) Children(name string) []*Directive {
+	if name == "" {
+		return children(d.Block)
+	}
+	return findAll(d.Block, name)
+}
+
+// ArgAt returns the i-th argument, or "" when there is none.
+func (d *Directive) ArgAt(i int) string {
+	if i < 0 || i >= len(d.Args) {
+		return ""
+	}
+	return d.Args[i]
+}
@@ -0,0 +1,84 @@
+package nginxparser
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParents(t *testing.T) {
+	root := filepath.Join("testdata", "includes-regular")
+	directives, err := New(&ParseOptions{Root: root}).ParseFile(filepath.Join(root, "nginx.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		ancestors := d.Ancestors()
+		if len(ancestors) != len(parents) {
+			t.Fatalf("expected %d ancestors for %s at %s:%d, got %d", len(parents), d.Directive, d.FileName, d.Line, len(ancestors))
+		}
+		for i := range parents {
+			if ancestors[i] != parents[i] {
+				t.Fatalf("unexpected ancestor %s of %s at %s:%d", ancestors[i].Directive, d.Directive, d.FileName, d.Line)
+			}
+		}
+		return true
+	})
+	http := directives[1]
+	if server := http.Block[0].Block[0]; server.Directive != "server" || server.Parent != http {
+		t.Fatalf("expected the included server to belong to http, got %+v", server.Parent)
+	}
+
+	include := http.Block[0]
+	Unshare(include)
+	if server := include.Block[0]; server.Parent != http || server.Block[0].Parent != server {
+		t.Fatal("expected the copy made by Unshare to keep its parents")
+	}
+
+	location := NewBlock("location", []string{"/"}, NewDirective("return", "204"))
+	server := NewBlock("server", nil, location)
+	if location.Block[0].Parent != location || location.Parent != server {
+		t.Fatal("expected builders to set parents")
+	}
+	moved := []*Directive{location}
+	SetParents(moved)
+	if location.Parent != nil || len(location.Block[0].Ancestors()) != 1 {
+		t.Fatal("expected SetParents to relink moved directives")
+	}
+}
+
+func TestDirectiveHelpers(t *testing.T) {
+	p := New(&ParseOptions{})
+	directives, err := p.ParseString(`server {
+    # the default port
+    listen 80;
+    listen 443 ssl;
+    location / { return 204; }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := directives[0]
+	if listen := server.FirstChild("listen"); listen == nil || listen.ArgAt(0) != "80" {
+		t.Fatalf("unexpected first listen %+v", listen)
+	}
+	if server.FirstChild("root") != nil {
+		t.Fatal("expected no root")
+	}
+	if listens := server.Children("listen"); len(listens) != 2 || listens[1].ArgAt(1) != "ssl" {
+		t.Fatalf("unexpected listens %+v", listens)
+	}
+	if all := server.Children(""); len(all) != 3 {
+		t.Fatalf("expected 3 children without comments, got %d", len(all))
+	}
+	listen := server.FirstChild("listen")
+	if listen.ArgAt(1) != "" || listen.ArgAt(-1) != "" {
+		t.Fatal("expected out of range arguments to be empty")
+	}
+	if s := listen.String(); s != "listen 80;" {
+		t.Fatalf("unexpected string %q", s)
+	}
+	if s := server.FirstChild("location").String(); s != "location / {\n    return 204;\n}" {
+		t.Fatalf("unexpected string %q", s)
+	}
+}
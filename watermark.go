@@ -0,0 +1,135 @@
+package nginxparser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const watermarkPrefix = "# watermark:"
+
+// Watermark identifies a file written by a tool, so generated files can be
+// told apart from hand written ones and hand edits to them detected.
+type Watermark struct {
+	Tool string    `json:"tool"`
+	Time time.Time `json:"time,omitempty"`
+	// Source is hashed into SourceHash when emitting, pass the input the
+	// file is generated from, such as a template.
+	Source     []byte `json:"-"`
+	SourceHash string `json:"source_hash,omitempty"`
+	// ContentHash is the hash of the file below the watermark as written.
+	ContentHash string `json:"content_hash"`
+	// Modified is set when reading a watermark back from a file whose
+	// content no longer matches ContentHash.
+	Modified bool `json:"modified,omitempty"`
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// header renders the watermark of content, ending with a blank line.
+func (w *Watermark) header(content []byte, nl string) string {
+	fields := []string{"tool=" + url.PathEscape(w.Tool)}
+	if !w.Time.IsZero() {
+		fields = append(fields, "time="+w.Time.UTC().Format(time.RFC3339))
+	}
+	sourceHash := w.SourceHash
+	if w.Source != nil {
+		sourceHash = sha256Hex(w.Source)
+	}
+	if sourceHash != "" {
+		fields = append(fields, "source="+sourceHash)
+	}
+	fields = append(fields, "content="+sha256Hex(content))
+	return fmt.Sprintf("# Generated by %s, DO NOT EDIT.%s%s %s%s%s", w.Tool, nl, watermarkPrefix, strings.Join(fields, " "), nl, nl)
+}
+
+// emitWatermarked writes the watermark of the configuration above it.
+func (e *Emitter) emitWatermarked(w io.Writer, directives []*Directive) error {
+	options := *e.options
+	options.Watermark = nil
+	var content bytes.Buffer
+	if err := NewEmitter(&options).Emit(&content, directives); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, e.options.Watermark.header(content.Bytes(), e.nl)); err != nil {
+		return err
+	}
+	_, err := w.Write(content.Bytes())
+	return err
+}
+
+// ReadWatermark reads the watermark at the top of a file, nil when there
+// is none, and checks whether the content below it was edited since.
+func ReadWatermark(src []byte) *Watermark {
+	_, w := splitWatermark(src)
+	return w
+}
+
+// splitWatermark returns the content of a watermarked file and its
+// watermark.
+func splitWatermark(src []byte) ([]byte, *Watermark) {
+	lines := make([][]byte, 0, 3)
+	rest := src
+	for len(lines) < 3 {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			return src, nil
+		}
+		lines = append(lines, bytes.TrimRight(rest[:i], "\r"))
+		rest = rest[i+1:]
+	}
+	if !bytes.HasPrefix(lines[0], []byte("# Generated by ")) || !bytes.HasPrefix(lines[1], []byte(watermarkPrefix)) || len(lines[2]) != 0 {
+		return src, nil
+	}
+	w := &Watermark{}
+	for _, field := range strings.Fields(string(lines[1][len(watermarkPrefix):])) {
+		i := strings.IndexByte(field, '=')
+		if i < 0 {
+			continue
+		}
+		value := field[i+1:]
+		switch field[:i] {
+		case "tool":
+			w.Tool, _ = url.PathUnescape(value)
+		case "time":
+			w.Time, _ = time.Parse(time.RFC3339, value)
+		case "source":
+			w.SourceHash = value
+		case "content":
+			w.ContentHash = value
+		}
+	}
+	if w.ContentHash == "" {
+		return src, nil
+	}
+	w.Modified = sha256Hex(rest) != w.ContentHash
+	return rest, w
+}
+
+// stripWatermark leaves the comments of a watermark out of the top-level
+// directives of a file.
+func stripWatermark(directives []*Directive) []*Directive {
+	i := 0
+	for i < len(directives) && i < 2 && directives[i].Directive == "#" && directives[i].Line == i+1 {
+		i++
+	}
+	if i != 2 {
+		return directives
+	}
+	directives = directives[2:]
+	if len(directives) > 0 {
+		directives[0].BlankLinesBefore = 0
+		if directives[0].Trivia != nil {
+			directives[0].Trivia.Before = strings.TrimLeft(directives[0].Trivia.Before, "\r\n")
+		}
+	}
+	return directives
+}
@@ -0,0 +1,51 @@
+package nginxparser
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWatermark(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString("server {\n    listen 80;\n}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	err = NewEmitter(&EmitOptions{Watermark: &Watermark{Tool: "nginx gen", Time: generated, Source: []byte("template")}}).Emit(&buf, directives)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("# Generated by nginx gen, DO NOT EDIT.\n# watermark: tool=nginx%20gen time=2024-05-01T12:00:00Z source=sha256:")) {
+		t.Fatalf("unexpected header:\n%s", buf.String())
+	}
+
+	w := ReadWatermark(buf.Bytes())
+	if w == nil || w.Tool != "nginx gen" || !w.Time.Equal(generated) || w.SourceHash != sha256Hex([]byte("template")) || w.Modified {
+		t.Fatalf("unexpected watermark %+v", w)
+	}
+
+	parser := New(&ParseOptions{SingleFile: true, Watermark: true})
+	parsed, err := parser.ParseString(buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != 1 || parsed[0].Directive != "server" {
+		t.Fatalf("expected the watermark comments to be stripped, got %+v", parsed)
+	}
+	if got := parser.Files()[""]; got == nil || got.Watermark == nil || got.Watermark.Modified {
+		t.Fatalf("expected an unmodified watermark, got %+v", got)
+	}
+	if out := parsed[0].String(); out != "server {\n    listen 80;\n}" {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	edited := bytes.Replace(buf.Bytes(), []byte("listen 80"), []byte("listen 8080"), 1)
+	if w := ReadWatermark(edited); w == nil || !w.Modified {
+		t.Fatalf("expected the hand edit to be detected, got %+v", w)
+	}
+	if w := ReadWatermark([]byte("# Generated by hand\nserver {}\n")); w != nil {
+		t.Fatalf("expected no watermark, got %+v", w)
+	}
+}
@@ -0,0 +1,81 @@
+package nginxparser
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+type WebSocketOptions struct {
+	// Paths are request URIs known to be served over WebSocket. The
+	// locations nginx selects for them are checked in addition to the
+	// ones detected from their configuration.
+	Paths []string
+}
+
+var webSocketPattern = regexp.MustCompile(`(?i)(^|/)(ws|wss|websockets?|socket\.io|cable)(/|$)`)
+
+const defaultProxyReadTimeout = 60 * time.Second
+
+// CheckWebSockets verifies that every proxied WebSocket location upgrades
+// the connection to the backend and keeps idle connections open long enough.
+func CheckWebSockets(directives []*Directive, options *WebSocketOptions) []*Issue {
+	if options == nil {
+		options = &WebSocketOptions{}
+	}
+	flagged := make(map[*Directive]bool)
+	for _, server := range servers(directives) {
+		for _, path := range options.Paths {
+			if location := FindLocation(server, path); location != nil {
+				flagged[location] = true
+			}
+		}
+	}
+
+	issues := make([]*Issue, 0)
+	report := func(d *Directive, format string, args ...interface{}) {
+		issues = append(issues, newIssue("websocket", d, format, args...))
+	}
+	Walk(directives, func(d *Directive, parents []*Directive) bool {
+		if d.Directive != "location" || findFirst(d.Block, "proxy_pass") == nil {
+			return true
+		}
+		headers := make(map[string]string)
+		for _, header := range lookupInheritedAll("proxy_set_header", d.Block, parents) {
+			if len(header.Args) == 2 {
+				headers[strings.ToLower(header.Args[0])] = strings.ToLower(header.Args[1])
+			}
+		}
+		_, pattern := locationPattern(d)
+		_, upgrade := headers["upgrade"]
+		if !flagged[d] && !upgrade && !webSocketPattern.MatchString(pattern) {
+			return true
+		}
+
+		version := lookupInherited("proxy_http_version", d.Block, parents)
+		if version == nil || len(version.Args) == 0 || version.Args[0] != "1.1" {
+			report(d, "WebSocket location %s must set proxy_http_version 1.1", pattern)
+		}
+		if headers["upgrade"] != "$http_upgrade" {
+			report(d, "WebSocket location %s must pass proxy_set_header Upgrade $http_upgrade", pattern)
+		}
+		if connection := headers["connection"]; connection != "upgrade" && connection != "$connection_upgrade" {
+			report(d, "WebSocket location %s must pass proxy_set_header Connection \"upgrade\"", pattern)
+		}
+		timeout := defaultProxyReadTimeout
+		if readTimeout := lookupInherited("proxy_read_timeout", d.Block, parents); readTimeout != nil && len(readTimeout.Args) > 0 {
+			if value, err := ParseDuration(readTimeout.Args[0]); err == nil {
+				timeout = value
+			}
+		}
+		if timeout <= defaultProxyReadTimeout {
+			report(d, "WebSocket location %s closes idle connections after %s, raise proxy_read_timeout", pattern, timeout)
+		}
+		return true
+	})
+	return issues
+}
+
+func checkWebSockets(directives []*Directive) []*Issue {
+	return CheckWebSockets(directives, nil)
+}
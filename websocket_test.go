@@ -0,0 +1,37 @@
+package nginxparser
+
+import (
+	"testing"
+)
+
+func TestCheckWebSockets(t *testing.T) {
+	directives, err := New(&ParseOptions{SingleFile: true}).ParseString(`
+http {
+    server {
+        proxy_http_version 1.1;
+        location /ws/ {
+            proxy_pass http://backend;
+            proxy_set_header Upgrade $http_upgrade;
+            proxy_set_header Connection "upgrade";
+            proxy_read_timeout 1h;
+        }
+        location /live {
+            proxy_pass http://backend;
+        }
+        location /api/ {
+            proxy_pass http://backend;
+        }
+    }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if issues := CheckWebSockets(directives, nil); len(issues) != 0 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	issues := CheckWebSockets(directives, &WebSocketOptions{Paths: []string{"/live"}})
+	if len(issues) != 3 || issues[0].Line != 11 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+}
@@ -0,0 +1,116 @@
+package nginxparser
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+)
+
+type WriteOptions struct {
+	Emit *EmitOptions
+	// Write stores the content of a file, it defaults to writing the file
+	// to disk.
+	Write func(name string, data []byte) error
+}
+
+// SplitByFile groups a parsed tree by the file each directive was read
+// from, following include directives into the files they pulled in. The
+// result maps file names to the top-level directives of each file, with
+// no directives for included files that were emptied. Directives without
+// a FileName belong to the file of the directive before them.
+func SplitByFile(directives []*Directive) map[string][]*Directive {
+	files := make(map[string][]*Directive)
+	root := ""
+	for _, d := range directives {
+		if d.FileName != "" {
+			root = d.FileName
+			break
+		}
+	}
+	files[root] = directives
+	splitIncludes(directives, files)
+	return files
+}
+
+func splitIncludes(directives []*Directive, files map[string][]*Directive) {
+	for _, d := range directives {
+		if d.Directive != "include" {
+			splitIncludes(d.Block, files)
+			continue
+		}
+		grouped := make(map[string][]*Directive)
+		order := make([]string, 0)
+		current := ""
+		for _, child := range d.Block {
+			if child.FileName != "" {
+				current = child.FileName
+			}
+			if _, ok := grouped[current]; !ok {
+				order = append(order, current)
+			}
+			grouped[current] = append(grouped[current], child)
+		}
+		for _, name := range order {
+			if _, ok := files[name]; ok {
+				continue
+			}
+			files[name] = grouped[name]
+			splitIncludes(grouped[name], files)
+		}
+		for _, name := range d.pulled {
+			if _, ok := files[name]; !ok {
+				files[name] = make([]*Directive, 0)
+			}
+		}
+	}
+}
+
+func writeFile(name string, data []byte) error {
+	if name == "" {
+		return fmt.Errorf("no file name to write to")
+	}
+	return os.WriteFile(name, data, 0644)
+}
+
+// WriteFiles writes every file of a parsed tree back, so changes made to
+// directives pulled in by include land in the file they came from.
+// Include directives are written as they are, files they pulled in that
+// lost all their directives are written empty. Without options.Write a
+// tree parsed from a reader, whose directives have no file name, is an
+// error.
+func WriteFiles(directives []*Directive, options *WriteOptions) error {
+	if options == nil {
+		options = &WriteOptions{}
+	}
+	write := options.Write
+	if write == nil {
+		write = writeFile
+	}
+
+	files := SplitByFile(directives)
+	if options.Write == nil {
+		if len(files[""]) > 0 {
+			d := files[""][0]
+			return fmt.Errorf("%s directive at line %d has no file name to write it to", d.Directive, d.Line)
+		}
+		delete(files, "")
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	emitter := NewEmitter(options.Emit)
+	for _, name := range names {
+		var buf bytes.Buffer
+		if err := emitter.Emit(&buf, files[name]); err != nil {
+			return err
+		}
+		if err := write(name, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
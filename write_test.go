@@ -0,0 +1,79 @@
+package nginxparser
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWriteFiles(t *testing.T) {
+	root := filepath.Join("testdata", "includes-regular")
+	directives, err := New(&ParseOptions{Root: root, Lossless: true}).ParseFile(filepath.Join(root, "nginx.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := directives[1].Block[0].Block[0]
+	server.Block[0].Args[0] = "127.0.0.1:8081"
+	location := server.Block[2].Block[0]
+	location.Block[0].Args[1] = "bar"
+
+	written := make(map[string]string)
+	err = WriteFiles(directives, &WriteOptions{
+		Write: func(name string, data []byte) error {
+			written[filepath.ToSlash(name)] = string(data)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"testdata/includes-regular/nginx.conf":         "events {}\nhttp {\n    include conf.d/server.conf;\n}\n",
+		"testdata/includes-regular/conf.d/server.conf": "server {\n    listen 127.0.0.1:8081;\n    server_name default_server;\n    include     foo.conf;\n    include     bar.conf;\n}\n",
+		"testdata/includes-regular/foo.conf":           "location /foo {\n    return 200 'bar';\n}\n",
+	}
+	if len(written) != len(expected) {
+		t.Fatalf("unexpected files %v", written)
+	}
+	for name, content := range expected {
+		if written[name] != content {
+			t.Fatalf("expected %s:\n%q\nbut got:\n%q", name, content, written[name])
+		}
+	}
+}
+
+func TestWriteFilesEmptied(t *testing.T) {
+	fsys := fstest.MapFS{
+		"nginx.conf": {Data: []byte("http {\n    include inc.conf;\n}\n")},
+		"inc.conf":   {Data: []byte("gzip on;\n")},
+	}
+	directives, err := New(&ParseOptions{FS: fsys, Root: "."}).ParseFile("nginx.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	include := directives[0].Block[0]
+	include.Block = removeDirectives(include.Block, map[*Directive]bool{include.Block[0]: true})
+
+	written := make(map[string]string)
+	err = WriteFiles(directives, &WriteOptions{
+		Write: func(name string, data []byte) error {
+			written[name] = string(data)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, ok := written["inc.conf"]; !ok || content != "" || len(written) != 2 {
+		t.Fatalf("expected the emptied file to be written, got %q", written)
+	}
+
+	directives, err = New(nil).ParseString("gzip on;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFiles(directives, nil); err == nil {
+		t.Fatal("expected directives without a file name to fail")
+	}
+}